@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aperturerobotics/cli"
+	"github.com/aperturerobotics/common/protogen"
+)
+
+var breakingCmd = &cli.Command{
+	Name:  "breaking",
+	Usage: "Report wire and source incompatible proto changes against a git ref",
+	Flags: append(configFlags(),
+		&cli.StringFlag{
+			Name:     "against",
+			Usage:    "Git ref to compare the proto files against (e.g. main, v1.2.0)",
+			Required: true,
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the findings as a JSON array",
+		},
+	),
+	Action: runBreaking,
+}
+
+func runBreaking(c *cli.Context) error {
+	cfg, err := loadProjectConfig(c)
+	if err != nil {
+		return err
+	}
+
+	gen, err := protogen.NewGenerator(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create generator: %w", err)
+	}
+	defer gen.Close(c.Context)
+
+	changes, err := gen.Breaking(c.Context, c.String("against"))
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("json") {
+		if changes == nil {
+			changes = []protogen.BreakingChange{}
+		}
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, string(data))
+	} else {
+		for _, change := range changes {
+			fmt.Fprintln(os.Stdout, change.String())
+		}
+	}
+	if len(changes) != 0 {
+		return fmt.Errorf("%d breaking changes against %s", len(changes), c.String("against"))
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aperturerobotics/cli"
+	"github.com/aperturerobotics/common/protogen"
+)
+
+// configFlags returns the flags that map onto protogen.Config fields.
+func configFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "targets",
+			Aliases: []string{"t"},
+			Usage:   "Proto file glob patterns, prefix with ! to deselect (can be specified multiple times)",
+			Value:   cli.NewStringSlice("./*.proto"),
+		},
+		&cli.StringSliceFlag{
+			Name:    "exclude",
+			Aliases: []string{"e"},
+			Usage:   "Proto file glob patterns to exclude, prefix with ! to keep (can be specified multiple times)",
+		},
+		&cli.BoolFlag{
+			Name:  "include-untracked",
+			Usage: "Discover proto files that are not tracked by git (set to false for tracked files only)",
+			Value: true,
+		},
+		&cli.BoolFlag{
+			Name:    "force",
+			Aliases: []string{"f"},
+			Usage:   "Regenerate all files regardless of cache",
+		},
+		&cli.StringFlag{
+			Name:  "cache-file",
+			Usage: "Path to the cache file",
+			Value: protogen.DefaultCacheFile,
+		},
+		&cli.BoolFlag{
+			Name:    "verbose",
+			Aliases: []string{"v"},
+			Usage:   "Enable verbose output",
+		},
+		&cli.StringFlag{
+			Name:  "features",
+			Usage: "Go-lite features to enable",
+			Value: protogen.DefaultGoLiteFeatures,
+		},
+		&cli.StringSliceFlag{
+			Name:  "go-import-remap",
+			Usage: "Rewrite a Go import path in generated Go files, as old=new (can be specified multiple times)",
+		},
+		&cli.StringFlag{
+			Name:  "tools-dir",
+			Usage: "Tools directory path",
+			Value: ".tools",
+		},
+		&cli.StringSliceFlag{
+			Name:    "language",
+			Aliases: []string{"l", "languages"},
+			Usage:   "Output language to generate: go, ts, cpp, rust, csharp, python (can be specified multiple times)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "rpc",
+			Usage: "RPC stub libraries to generate: starpc, starpc-python, none, false (can be specified multiple times)",
+		},
+		&cli.StringFlag{
+			Name:    "project-dir",
+			Aliases: []string{"C"},
+			Usage:   "Project directory",
+		},
+		&cli.IntFlag{
+			Name:    "jobs",
+			Aliases: []string{"j"},
+			Usage:   "Number of concurrent protoc invocations, sharded by package directory (0 uses all CPUs)",
+			Value:   1,
+		},
+		&cli.StringFlag{
+			Name:  "wasm-cache-dir",
+			Usage: "Directory for the persistent WASM compilation cache (default: user cache dir)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "descriptor-set",
+			Usage: "Write a FileDescriptorSet of all proto files to this path, as JSON if it ends in .json (can be specified multiple times)",
+		},
+		&cli.StringFlag{
+			Name:  "config",
+			Usage: "Path to the aptre.yaml or aptre.json config file (default: found in the project directory)",
+		},
+	}
+}
+
+// loadProjectConfig builds the generator config for the command.
+// Precedence, highest first: flags, aptre.yaml / aptre.json, package.json, defaults.
+func loadProjectConfig(c *cli.Context) (*protogen.Config, error) {
+	return loadProjectConfigIn(c, c.String("project-dir"))
+}
+
+// loadProjectConfigIn builds the generator config for the command with the
+// given project directory.
+func loadProjectConfigIn(c *cli.Context, projectDir string) (*protogen.Config, error) {
+	cfg := protogen.NewConfig()
+	cfg.ProjectDir = projectDir
+	if _, err := cfg.ApplyConfigFile(c.String("config")); err != nil {
+		return nil, fmt.Errorf("failed to load config file: %w", err)
+	}
+
+	if c.IsSet("targets") {
+		cfg.Targets = c.StringSlice("targets")
+	}
+	if c.IsSet("exclude") {
+		cfg.Exclude = c.StringSlice("exclude")
+	}
+	if c.IsSet("include-untracked") {
+		cfg.IncludeUntracked = c.Bool("include-untracked")
+	}
+	if c.IsSet("force") {
+		cfg.Force = c.Bool("force")
+	}
+	if c.IsSet("cache-file") {
+		cfg.CacheFile = c.String("cache-file")
+	}
+	if c.IsSet("verbose") {
+		cfg.Verbose = c.Bool("verbose")
+	}
+	if c.IsSet("features") {
+		cfg.GoLiteFeatures = c.String("features")
+	}
+	if c.IsSet("go-import-remap") {
+		remaps, err := parseGoImportRemaps(c.StringSlice("go-import-remap"))
+		if err != nil {
+			return nil, err
+		}
+		cfg.GoImportRemaps = remaps
+	}
+	if c.IsSet("tools-dir") {
+		cfg.ToolsDir = c.String("tools-dir")
+	}
+	if c.IsSet("language") {
+		cfg.Languages = c.StringSlice("language")
+	}
+	if c.IsSet("rpc") {
+		cfg.RPCLibraries = c.StringSlice("rpc")
+	}
+	if c.IsSet("jobs") {
+		cfg.Jobs = c.Int("jobs")
+	}
+	if c.IsSet("wasm-cache-dir") {
+		cfg.WasmCacheDir = c.String("wasm-cache-dir")
+	}
+	if c.IsSet("descriptor-set") {
+		cfg.DescriptorSets = c.StringSlice("descriptor-set")
+	}
+
+	// Extra args are passed through
+	if c.Args().Len() != 0 {
+		cfg.ExtraArgs = c.Args().Slice()
+	}
+	return cfg, nil
+}
+
+// parseGoImportRemaps parses old=new import path pairs.
+func parseGoImportRemaps(values []string) (map[string]string, error) {
+	remaps := make(map[string]string, len(values))
+	for _, v := range values {
+		from, to, ok := strings.Cut(v, "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid go import remap %q: expected old=new", v)
+		}
+		remaps[from] = to
+	}
+	return remaps, nil
+}
+
+var configCmd = &cli.Command{
+	Name:  "config",
+	Usage: "Inspect the aptre project configuration",
+	Subcommands: []*cli.Command{
+		{
+			Name:   "show",
+			Usage:  "Print the fully resolved configuration as JSON",
+			Flags:  configFlags(),
+			Action: runConfigShow,
+		},
+		{
+			Name:   "schema",
+			Usage:  "Print the JSON schema for aptre.yaml and aptre.json",
+			Action: runConfigSchema,
+		},
+	},
+}
+
+func runConfigShow(c *cli.Context) error {
+	cfg, err := loadProjectConfig(c)
+	if err != nil {
+		return err
+	}
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(resolved, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(data))
+	return err
+}
+
+func runConfigSchema(c *cli.Context) error {
+	_, err := os.Stdout.Write(protogen.ConfigFileSchema)
+	return err
+}
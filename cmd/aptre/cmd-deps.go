@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -114,7 +115,9 @@ func planGenerateDependencies(cfg *protogen.Config) (generateDependencyPlan, err
 	}, nil
 }
 
-func ensureGenerateDeps(cfg *protogen.Config, verbose bool) error {
+// ensureGenerateDeps ensures the tools needed to generate the configured
+// languages. Progress and tool build output are written to stdout.
+func ensureGenerateDeps(cfg *protogen.Config, verbose bool, stdout io.Writer) error {
 	plan, err := planGenerateDependencies(cfg)
 	if err != nil {
 		return err
@@ -129,11 +132,11 @@ func ensureGenerateDeps(cfg *protogen.Config, verbose bool) error {
 			return err
 		}
 		toolsPath := toolsDir
-		if err := ensureToolsDir(projectDir, toolsPath, verbose); err != nil {
+		if err := ensureToolsDir(projectDir, toolsPath, verbose, stdout); err != nil {
 			return err
 		}
 		for _, tool := range plan.nativeTools {
-			if err := ensureTool(projectDir, toolsPath, tool, false, verbose); err != nil {
+			if err := ensureTool(projectDir, toolsPath, tool, false, verbose, stdout); err != nil {
 				return err
 			}
 		}
@@ -143,7 +146,7 @@ func ensureGenerateDeps(cfg *protogen.Config, verbose bool) error {
 		if err != nil {
 			return err
 		}
-		if err := ensureNodeModules(projectDir, verbose); err != nil {
+		if err := ensureNodeModules(projectDir, verbose, stdout); err != nil {
 			return err
 		}
 	}
@@ -214,21 +217,21 @@ func ensureAllDeps(projectDir, toolsDir string, verbose, force bool) error {
 
 	// Ensure tools directory exists
 	toolsPath := filepath.Join(absProjectDir, toolsDir)
-	if err := ensureToolsDir(absProjectDir, toolsPath, verbose); err != nil {
+	if err := ensureToolsDir(absProjectDir, toolsPath, verbose, os.Stdout); err != nil {
 		return err
 	}
 
 	// Build required tools
 	requiredTools := []string{"protoc-gen-go-lite", "protoc-gen-go-starpc", "protoc-gen-starpc-cpp", "protoc-gen-starpc-rust", "gofumpt"}
 	for _, toolName := range requiredTools {
-		if err := ensureTool(absProjectDir, toolsPath, toolName, force, verbose); err != nil {
+		if err := ensureTool(absProjectDir, toolsPath, toolName, force, verbose, os.Stdout); err != nil {
 			return fmt.Errorf("failed to ensure %s: %w", toolName, err)
 		}
 	}
 
 	// Ensure node_modules if package.json exists
 	if _, err := os.Stat(filepath.Join(absProjectDir, "package.json")); err == nil {
-		if err := ensureNodeModules(absProjectDir, verbose); err != nil {
+		if err := ensureNodeModules(absProjectDir, verbose, os.Stdout); err != nil {
 			return fmt.Errorf("failed to ensure node_modules: %w", err)
 		}
 	}
@@ -280,14 +283,14 @@ func invalidateToolBinaries(toolsPath string) error {
 	return nil
 }
 
-func ensureToolsDir(projectDir, toolsPath string, verbose bool) error {
+func ensureToolsDir(projectDir, toolsPath string, verbose bool, stdout io.Writer) error {
 	if err := os.MkdirAll(toolsPath, 0o755); err != nil {
 		return err
 	}
 	identity := resolveCommonPackage(projectDir)
 	_, err := reconcileToolsStamp(toolsStampPath(toolsPath), identity, func() error {
 		if verbose {
-			fmt.Println("Synchronizing embedded tool metadata...")
+			fmt.Fprintln(stdout, "Synchronizing embedded tool metadata...")
 		}
 		relToolsPath, err := filepath.Rel(projectDir, toolsPath)
 		if err != nil {
@@ -295,7 +298,7 @@ func ensureToolsDir(projectDir, toolsPath string, verbose bool) error {
 		}
 		cmd := exec.Command("go", "run", "-mod=mod", "-v", identity, relToolsPath)
 		cmd.Dir = projectDir
-		cmd.Stdout = os.Stdout
+		cmd.Stdout = stdout
 		cmd.Stderr = os.Stderr
 		return cmd.Run()
 	}, func() error {
@@ -338,7 +341,7 @@ func resolveCommonPackage(projectDir string) string {
 	return commonModule
 }
 
-func ensureTool(projectDir, toolsPath, toolName string, force, verbose bool) error {
+func ensureTool(projectDir, toolsPath, toolName string, force, verbose bool, stdout io.Writer) error {
 	binPath := filepath.Join(toolsPath, "bin", toolName)
 
 	// Check if already exists
@@ -354,7 +357,7 @@ func ensureTool(projectDir, toolsPath, toolName string, force, verbose bool) err
 	}
 
 	if verbose {
-		fmt.Printf("Building %s...\n", toolName)
+		fmt.Fprintf(stdout, "Building %s...\n", toolName)
 	}
 
 	plan := selectedToolPlan(projectDir, toolName)
@@ -367,24 +370,24 @@ func ensureTool(projectDir, toolsPath, toolName string, force, verbose bool) err
 		cmd = exec.Command("go", "build", "-mod=readonly", "-v", "-o", binPath, spec.ImportPath)
 		cmd.Dir = toolsPath
 	}
-	cmd.Stdout = os.Stdout
+	cmd.Stdout = stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
-func ensureNodeModules(projectDir string, verbose bool) error {
+func ensureNodeModules(projectDir string, verbose bool, stdout io.Writer) error {
 	nodeModulesPath := filepath.Join(projectDir, "node_modules")
 	if _, err := os.Stat(nodeModulesPath); err == nil {
 		return nil // Already exists
 	}
 
 	if verbose {
-		fmt.Println("Installing node_modules...")
+		fmt.Fprintln(stdout, "Installing node_modules...")
 	}
 
 	cmd := exec.Command("bun", "install")
 	cmd.Dir = projectDir
-	cmd.Stdout = os.Stdout
+	cmd.Stdout = stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
@@ -407,11 +410,11 @@ func EnsureToolBuilt(projectDir, toolsDir, toolName string, verbose bool) (strin
 	toolsPath := filepath.Join(absProjectDir, toolsDir)
 
 	// Ensure tools directory exists first
-	if err := ensureToolsDir(absProjectDir, toolsPath, verbose); err != nil {
+	if err := ensureToolsDir(absProjectDir, toolsPath, verbose, os.Stdout); err != nil {
 		return "", fmt.Errorf("failed to ensure tools directory: %w", err)
 	}
 
-	if err := ensureTool(absProjectDir, toolsPath, toolName, false, verbose); err != nil {
+	if err := ensureTool(absProjectDir, toolsPath, toolName, false, verbose, os.Stdout); err != nil {
 		return "", err
 	}
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 	"slices"
@@ -59,7 +60,7 @@ func TestEnsureGenerateDepsPythonOnlyCreatesNoToolOrNodeDirectories(t *testing.T
 	cfg.ToolsDir = ".tools"
 	cfg.Languages = []string{"python"}
 
-	if err := ensureGenerateDeps(cfg, false); err != nil {
+	if err := ensureGenerateDeps(cfg, false, io.Discard); err != nil {
 		t.Fatalf("ensure Python-only dependencies: %v", err)
 	}
 	for _, name := range []string{".tools", "node_modules"} {
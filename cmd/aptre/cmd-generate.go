@@ -1,7 +1,15 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/aperturerobotics/cli"
 	"github.com/aperturerobotics/common/protogen"
@@ -11,89 +19,113 @@ var generateCmd = &cli.Command{
 	Name:    "generate",
 	Aliases: []string{"gen", "genproto"},
 	Usage:   "Generate protobuf code",
-	Flags: []cli.Flag{
-		&cli.StringSliceFlag{
-			Name:    "targets",
-			Aliases: []string{"t"},
-			Usage:   "Proto file patterns (can be specified multiple times)",
-			Value:   cli.NewStringSlice("./*.proto"),
-		},
-		&cli.StringSliceFlag{
-			Name:    "exclude",
-			Aliases: []string{"e"},
-			Usage:   "Proto file patterns to exclude (can be specified multiple times)",
-		},
+	Flags: append(configFlags(),
 		&cli.BoolFlag{
-			Name:    "force",
-			Aliases: []string{"f"},
-			Usage:   "Regenerate all files regardless of cache",
-		},
-		&cli.StringFlag{
-			Name:  "cache-file",
-			Usage: "Path to the cache file",
-			Value: protogen.DefaultCacheFile,
+			Name:  "deps",
+			Usage: "Ensure dependencies before generating",
+			Value: true,
 		},
 		&cli.BoolFlag{
-			Name:    "verbose",
-			Aliases: []string{"v"},
-			Usage:   "Enable verbose output",
+			Name:    "watch",
+			Aliases: []string{"w"},
+			Usage:   "Keep running and regenerate affected packages when inputs change",
 		},
-		&cli.StringFlag{
-			Name:  "features",
-			Usage: "Go-lite features to enable",
-			Value: protogen.DefaultGoLiteFeatures,
+		&cli.DurationFlag{
+			Name:  "watch-interval",
+			Usage: "Polling interval for --watch",
+			Value: protogen.DefaultWatchInterval,
 		},
-		&cli.StringFlag{
-			Name:  "tools-dir",
-			Usage: "Tools directory path",
-			Value: ".tools",
+		&cli.BoolFlag{
+			Name:  "check",
+			Usage: "Generate into a scratch directory and fail with a diff if committed generated files are stale",
 		},
-		&cli.StringSliceFlag{
-			Name:    "language",
-			Aliases: []string{"l", "languages"},
-			Usage:   "Output language to generate: go, ts, cpp, rust, csharp, python (can be specified multiple times)",
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Print the packages, protoc commands and output files that would be generated without generating",
 		},
-		&cli.StringSliceFlag{
-			Name:  "rpc",
-			Usage: "RPC stub libraries to generate: starpc, starpc-python, none, false (can be specified multiple times)",
+		&cli.BoolFlag{
+			Name:  "explain",
+			Usage: "Print why each package is regenerated",
 		},
 		&cli.StringFlag{
-			Name:    "project-dir",
-			Aliases: []string{"C"},
-			Usage:   "Project directory",
+			Name:  "output",
+			Usage: "Progress output format: text or json (one event per line on stdout)",
+			Value: "text",
 		},
 		&cli.BoolFlag{
-			Name:  "deps",
-			Usage: "Ensure dependencies before generating",
-			Value: true,
+			Name:  "workspace",
+			Usage: "Generate every module listed in go.work, each with its own config and manifest",
 		},
-	},
+	),
 	Action: runGenerate,
 }
 
 func runGenerate(c *cli.Context) error {
-	cfg := protogen.NewConfig()
-	cfg.Targets = c.StringSlice("targets")
-	cfg.Exclude = c.StringSlice("exclude")
-	cfg.Force = c.Bool("force")
-	cfg.CacheFile = c.String("cache-file")
-	cfg.Verbose = c.Bool("verbose")
-	cfg.GoLiteFeatures = c.String("features")
-	cfg.ToolsDir = c.String("tools-dir")
-	cfg.ProjectDir = c.String("project-dir")
-	if c.IsSet("language") {
-		cfg.Languages = c.StringSlice("language")
+	cfg, err := loadProjectConfig(c)
+	if err != nil {
+		return err
 	}
-	if c.IsSet("rpc") {
-		cfg.RPCLibraries = c.StringSlice("rpc")
+
+	// progress receives the non-event output of the generator and tools.
+	stdout, progress := io.Writer(os.Stdout), io.Writer(os.Stdout)
+	var events func(*protogen.Event)
+	switch output := c.String("output"); output {
+	case "text":
+	case "json":
+		if c.Bool("check") {
+			return errors.New("--output json cannot be combined with --check")
+		}
+		// Keep stdout for events, everything else goes to stderr.
+		events = protogen.NewJSONEventWriter(stdout)
+		progress = os.Stderr
+	default:
+		return fmt.Errorf("unknown output format %q: must be text or json", output)
 	}
 
-	// Extra args are passed through
-	cfg.ExtraArgs = c.Args().Slice()
+	if c.Bool("workspace") {
+		if c.Bool("check") || c.Bool("watch") {
+			return errors.New("--workspace cannot be combined with --check or --watch")
+		}
+		projectDir, err := cfg.GetProjectDir()
+		if err != nil {
+			return err
+		}
+		goWorkPath, err := protogen.FindWorkspaceFile(projectDir)
+		if err != nil {
+			return err
+		}
+		if goWorkPath == "" {
+			return errors.New("--workspace requires a go.work file")
+		}
+		ws, err := protogen.LoadWorkspace(goWorkPath)
+		if err != nil {
+			return fmt.Errorf("failed to load go.work: %w", err)
+		}
+		for _, m := range ws.Modules {
+			moduleCfg, err := loadProjectConfigIn(c, m.Dir)
+			if err != nil {
+				return fmt.Errorf("%s: %w", m.Path, err)
+			}
+			if events == nil {
+				fmt.Fprintf(stdout, "==> %s\n", m.Path)
+			}
+			if err := generateProject(c, moduleCfg, events, stdout, progress); err != nil {
+				return fmt.Errorf("%s: %w", m.Path, err)
+			}
+		}
+		return nil
+	}
+
+	return generateProject(c, cfg, events, stdout, progress)
+}
 
+// generateProject runs the generate command for a single project. Events
+// are written to the events writer if set, plans and diffs to stdout, and
+// progress output to progress.
+func generateProject(c *cli.Context, cfg *protogen.Config, events func(*protogen.Event), stdout, progress io.Writer) error {
 	// Ensure dependencies if requested
-	if c.Bool("deps") {
-		if err := ensureGenerateDeps(cfg, cfg.Verbose); err != nil {
+	if c.Bool("deps") && !c.Bool("dry-run") {
+		if err := ensureGenerateDeps(cfg, cfg.Verbose, progress); err != nil {
 			return fmt.Errorf("failed to ensure dependencies: %w", err)
 		}
 	}
@@ -102,10 +134,86 @@ func runGenerate(c *cli.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to create generator: %w", err)
 	}
+	defer gen.Close(c.Context)
+
+	gen.Stdout = progress
+	gen.Stderr = os.Stderr
+	gen.Events = events
+	gen.Explain = c.Bool("explain")
+
+	if c.Bool("dry-run") {
+		if c.Bool("check") || c.Bool("watch") {
+			return errors.New("--dry-run cannot be combined with --check or --watch")
+		}
+		plan, err := gen.Plan()
+		if err != nil {
+			return err
+		}
+		if events != nil {
+			return json.NewEncoder(stdout).Encode(plan)
+		}
+		printPlan(stdout, plan)
+		return nil
+	}
+
+	if c.Bool("check") {
+		if c.Bool("watch") {
+			return errors.New("--check cannot be combined with --watch")
+		}
+		stale, err := gen.Check(c.Context)
+		if err != nil {
+			return err
+		}
+		for _, f := range stale {
+			fmt.Fprint(stdout, f.Diff)
+		}
+		if len(stale) != 0 {
+			return fmt.Errorf("%d generated files are out of date, run aptre generate", len(stale))
+		}
+		return nil
+	}
+
+	if c.Bool("watch") {
+		ctx, cancel := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+		return gen.Watch(ctx, c.Duration("watch-interval"))
+	}
 
 	return gen.Generate(c.Context)
 }
 
+// printPlan prints a generation plan in human-readable form.
+func printPlan(w io.Writer, plan *protogen.Plan) {
+	for _, pkg := range plan.Packages {
+		if pkg.Profile != "" {
+			fmt.Fprintf(w, "Would generate %s (%s, profile %s)\n", pkg.Dir, pkg.Reason, pkg.Profile)
+		} else {
+			fmt.Fprintf(w, "Would generate %s (%s)\n", pkg.Dir, pkg.Reason)
+		}
+		for _, line := range pkg.Details {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+		for _, out := range pkg.Outputs {
+			fmt.Fprintf(w, "  %s\n", out.ProtoFile)
+			for _, f := range out.Files {
+				fmt.Fprintf(w, "    %s\n", f)
+			}
+		}
+	}
+	for _, dir := range plan.Skipped {
+		fmt.Fprintf(w, "Skipping %s (up to date)\n", dir)
+	}
+	for _, f := range plan.DescriptorSets {
+		fmt.Fprintf(w, "Would write descriptor set %s\n", f)
+	}
+	for _, args := range plan.Commands {
+		fmt.Fprintf(w, "Would run: %s\n", strings.Join(args, " "))
+	}
+	if len(plan.Packages) == 0 && len(plan.DescriptorSets) == 0 {
+		fmt.Fprintf(w, "Nothing to generate (%d proto files up to date)\n", len(plan.ProtoFiles))
+	}
+}
+
 var cleanCmd = &cli.Command{
 	Name:  "clean",
 	Usage: "Remove generated files and cache",
@@ -120,19 +228,134 @@ var cleanCmd = &cli.Command{
 			Aliases: []string{"C"},
 			Usage:   "Project directory",
 		},
+		&cli.StringFlag{
+			Name:  "config",
+			Usage: "Path to the aptre.yaml or aptre.json config file (default: found in the project directory)",
+		},
+		&cli.BoolFlag{
+			Name:  "wasm-cache",
+			Usage: "Also purge the persistent WASM compilation cache",
+		},
+		&cli.StringSliceFlag{
+			Name:    "targets",
+			Aliases: []string{"t"},
+			Usage:   "Only remove the outputs of proto files matching these patterns (can be specified multiple times)",
+		},
+		&cli.StringSliceFlag{
+			Name:    "exclude",
+			Aliases: []string{"e"},
+			Usage:   "Keep the outputs of proto files matching these patterns (can be specified multiple times)",
+		},
+		&cli.StringSliceFlag{
+			Name:    "language",
+			Aliases: []string{"l", "languages"},
+			Usage:   "Only remove the outputs of these languages: go, ts, cpp, rust, csharp, python (can be specified multiple times)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "rpc",
+			Usage: "Only remove the RPC stubs of these libraries: starpc, starpc-python (can be specified multiple times)",
+		},
+		&cli.BoolFlag{
+			Name:  "orphans",
+			Usage: "Only remove generated files whose proto files were deleted, and report unclaimed generated files",
+		},
+		&cli.BoolFlag{
+			Name:    "yes",
+			Aliases: []string{"y"},
+			Usage:   "Remove unclaimed generated files found by --orphans without asking",
+		},
+		&cli.StringFlag{
+			Name:  "wasm-cache-dir",
+			Usage: "Directory for the persistent WASM compilation cache (default: user cache dir)",
+		},
 	},
 	Action: runClean,
 }
 
 func runClean(c *cli.Context) error {
-	cfg := protogen.NewConfig()
-	cfg.CacheFile = c.String("cache-file")
-	cfg.ProjectDir = c.String("project-dir")
+	cfg, err := loadProjectConfig(c)
+	if err != nil {
+		return err
+	}
 
 	gen, err := protogen.NewGenerator(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create generator: %w", err)
 	}
 
+	if err := cleanProject(c, gen); err != nil {
+		return err
+	}
+	if c.Bool("wasm-cache") {
+		if err := cfg.PurgeWasmCache(); err != nil {
+			return fmt.Errorf("failed to purge WASM compilation cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// cleanProject removes the generated files selected by the clean flags.
+func cleanProject(c *cli.Context, gen *protogen.Generator) error {
+	if c.Bool("orphans") {
+		return runCleanOrphans(gen, c.Bool("yes"))
+	}
+	if c.IsSet("targets") || c.IsSet("exclude") || c.IsSet("language") || c.IsSet("rpc") {
+		filter := &protogen.CleanFilter{
+			Targets: c.StringSlice("targets"),
+			Exclude: c.StringSlice("exclude"),
+		}
+		var err error
+		if c.IsSet("language") {
+			if filter.Languages, err = protogen.NewLanguages(c.StringSlice("language")); err != nil {
+				return err
+			}
+		}
+		if c.IsSet("rpc") {
+			if filter.RPCLibraries, err = protogen.NewRPCLibraries(c.StringSlice("rpc")); err != nil {
+				return err
+			}
+		}
+		removed, err := gen.CleanSelected(filter)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d generated files\n", len(removed))
+		return nil
+	}
 	return gen.Clean()
 }
+
+// runCleanOrphans removes the outputs of deleted proto files and offers to
+// remove the generated files that the cache does not claim.
+func runCleanOrphans(gen *protogen.Generator, yes bool) error {
+	removed, err := gen.CleanOrphans()
+	for _, f := range removed {
+		fmt.Printf("Removed orphaned %s\n", f)
+	}
+	if err != nil {
+		return err
+	}
+
+	unclaimed, err := gen.FindUnclaimedGeneratedFiles()
+	if err != nil {
+		return err
+	}
+	if len(unclaimed) == 0 {
+		return nil
+	}
+	for _, f := range unclaimed {
+		fmt.Printf("Unclaimed generated file %s\n", f)
+	}
+	if !yes {
+		fmt.Printf("Remove %d unclaimed generated files? [y/N] ", len(unclaimed))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			return nil
+		}
+	}
+	if err := gen.RemoveProjectFiles(unclaimed); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %d unclaimed generated files\n", len(unclaimed))
+	return nil
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 
 	"github.com/aperturerobotics/cli"
+	"github.com/aperturerobotics/common/protogen"
 )
 
 var lintCmd = &cli.Command{
@@ -28,7 +31,24 @@ var lintCmd = &cli.Command{
 			Usage:   "Enable verbose output",
 		},
 	},
-	Action: runLint,
+	Subcommands: []*cli.Command{lintProtoCmd},
+	Action:      runLint,
+}
+
+var lintProtoCmd = &cli.Command{
+	Name:  "proto",
+	Usage: "Check .proto files against the proto lint rules",
+	Flags: append(configFlags(),
+		&cli.BoolFlag{
+			Name:  "fix",
+			Usage: "Fix the findings that do not change the generated API in place",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the findings as a JSON array",
+		},
+	),
+	Action: runLintProto,
 }
 
 func runLint(c *cli.Context) error {
@@ -131,7 +151,20 @@ var formatCmd = &cli.Command{
 			Usage:   "Enable verbose output",
 		},
 	},
-	Action: runFormat,
+	Subcommands: []*cli.Command{formatProtoCmd},
+	Action:      runFormat,
+}
+
+var formatProtoCmd = &cli.Command{
+	Name:  "proto",
+	Usage: "Format .proto files in the canonical style",
+	Flags: append(configFlags(),
+		&cli.BoolFlag{
+			Name:  "check",
+			Usage: "Print a diff for unformatted files and fail instead of writing them",
+		},
+	),
+	Action: runFormatProto,
 }
 
 func runFormat(c *cli.Context) error {
@@ -201,7 +234,7 @@ func runGoimports(c *cli.Context) error {
 	}
 
 	goimportsPath := filepath.Join(projectDir, toolsDir, "bin", "goimports")
-	if err := ensureTool(projectDir, filepath.Join(projectDir, toolsDir), "goimports", false, verbose); err != nil {
+	if err := ensureTool(projectDir, filepath.Join(projectDir, toolsDir), "goimports", false, verbose, os.Stdout); err != nil {
 		return err
 	}
 
@@ -216,3 +249,83 @@ func runGoimports(c *cli.Context) error {
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
+
+func runLintProto(c *cli.Context) error {
+	cfg, err := loadProjectConfig(c)
+	if err != nil {
+		return err
+	}
+
+	gen, err := protogen.NewGenerator(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create generator: %w", err)
+	}
+	defer gen.Close(c.Context)
+
+	diags, err := gen.LintProto(c.Context)
+	if err != nil {
+		return err
+	}
+	if c.Bool("fix") {
+		fixed, err := gen.FixLintProto(diags)
+		if err != nil {
+			return err
+		}
+		if fixed != 0 {
+			fmt.Fprintf(os.Stderr, "Fixed %d findings\n", fixed)
+			diags, err = gen.LintProto(c.Context)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.Bool("json") {
+		if diags == nil {
+			diags = []protogen.LintDiagnostic{}
+		}
+		data, err := json.MarshalIndent(diags, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, string(data))
+	} else {
+		for _, d := range diags {
+			fmt.Fprintln(os.Stdout, d.String())
+		}
+	}
+	if len(diags) != 0 {
+		return fmt.Errorf("%d proto lint findings", len(diags))
+	}
+	return nil
+}
+
+func runFormatProto(c *cli.Context) error {
+	cfg, err := loadProjectConfig(c)
+	if err != nil {
+		return err
+	}
+
+	gen, err := protogen.NewGenerator(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create generator: %w", err)
+	}
+	defer gen.Close(c.Context)
+
+	check := c.Bool("check")
+	changed, err := gen.FormatProto(c.Context, check)
+	if err != nil {
+		return err
+	}
+	for _, f := range changed {
+		if check {
+			fmt.Fprint(os.Stdout, f.Diff)
+		} else {
+			fmt.Fprintln(os.Stdout, f.Path)
+		}
+	}
+	if check && len(changed) != 0 {
+		return fmt.Errorf("%d proto files are not formatted", len(changed))
+	}
+	return nil
+}
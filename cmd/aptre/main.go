@@ -19,6 +19,8 @@ func main() {
 		Commands: []*cli.Command{
 			generateCmd,
 			cleanCmd,
+			configCmd,
+			breakingCmd,
 			depsCmd,
 			lintCmd,
 			fixCmd,
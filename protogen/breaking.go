@@ -0,0 +1,568 @@
+package protogen
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// BreakingCategory classifies how a breaking change affects users of a schema.
+type BreakingCategory string
+
+const (
+	// BreakingWire changes break the binary encoding or RPC routing, so old
+	// and new peers can no longer exchange messages.
+	BreakingWire BreakingCategory = "wire"
+	// BreakingSource changes keep the binary encoding but break generated
+	// code or the JSON encoding.
+	BreakingSource BreakingCategory = "source"
+)
+
+// Kinds of breaking changes reported by CompareDescriptors.
+const (
+	BreakingFileRemoved             = "file-removed"
+	BreakingPackageChanged          = "package-changed"
+	BreakingMessageRemoved          = "message-removed"
+	BreakingFieldRemoved            = "field-removed"
+	BreakingFieldRenamed            = "field-renamed"
+	BreakingFieldNumberChanged      = "field-number-changed"
+	BreakingFieldTypeChanged        = "field-type-changed"
+	BreakingFieldCardinalityChanged = "field-cardinality-changed"
+	BreakingReservedRemoved         = "reserved-removed"
+	BreakingReservedReused          = "reserved-reused"
+	BreakingEnumRemoved             = "enum-removed"
+	BreakingEnumValueRemoved        = "enum-value-removed"
+	BreakingEnumValueRenamed        = "enum-value-renamed"
+	BreakingEnumValueNumberChanged  = "enum-value-number-changed"
+	BreakingServiceRemoved          = "service-removed"
+	BreakingMethodRemoved           = "method-removed"
+	BreakingMethodTypeChanged       = "method-type-changed"
+	BreakingMethodStreamingChanged  = "method-streaming-changed"
+)
+
+// BreakingChange describes an incompatible change between two versions of
+// the proto schema.
+type BreakingChange struct {
+	// File is the project-relative proto file containing the change.
+	File string `json:"file"`
+	// Line is the 1-based line in File. Removed elements point at their
+	// parent in the new version of the file.
+	Line int `json:"line"`
+	// Category is the kind of compatibility that is broken.
+	Category BreakingCategory `json:"category"`
+	// Kind identifies the rule that reported the change.
+	Kind string `json:"kind"`
+	// Message describes the change.
+	Message string `json:"message"`
+}
+
+// String formats the change as "file:line: [category] message".
+func (c *BreakingChange) String() string {
+	return fmt.Sprintf("%s:%d: [%s] %s", c.File, c.Line, c.Category, c.Message)
+}
+
+// Breaking compares the proto files discovered in the project with the proto
+// files at the given git ref and returns the wire and source incompatible
+// changes. Both versions are compiled with the embedded protoc.
+func (g *Generator) Breaking(ctx context.Context, againstRef string) ([]BreakingChange, error) {
+	protoFiles, err := DiscoverProtoFiles(g.ProjectDir, g.Config.Targets, g.Config.Exclude, g.Config.IncludeUntracked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover proto files: %w", err)
+	}
+
+	scratchDir, err := os.MkdirTemp("", "aptre-breaking-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scratchDir)
+
+	if err := extractGitProtoTree(ctx, g.ProjectDir, againstRef, filepath.Join(scratchDir, "project"), g.Config.ToolsDir); err != nil {
+		return nil, err
+	}
+	base, err := g.newSnapshotGenerator(scratchDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare scratch directory: %w", err)
+	}
+	baseFiles, err := DiscoverProtoFiles(base.ProjectDir, g.Config.Targets, g.Config.Exclude, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover proto files at %s: %w", againstRef, err)
+	}
+
+	previous, _, err := base.buildDescriptorSet(ctx, baseFiles, filepath.Join(scratchDir, "previous.binpb"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile proto files at %s: %w", againstRef, err)
+	}
+	current, _, err := g.buildDescriptorSet(ctx, protoFiles, filepath.Join(scratchDir, "current.binpb"))
+	if err != nil {
+		return nil, err
+	}
+	return CompareDescriptors(previous, current, g.ModulePath), nil
+}
+
+// buildDescriptorSet compiles the proto files with the embedded protoc into a
+// FileDescriptorSet with source info at outPath. Returns the parsed files and
+// the warnings printed by protoc.
+func (g *Generator) buildDescriptorSet(ctx context.Context, protoFiles []string, outPath string) ([]*FileDescriptor, string, error) {
+	data, warnings, err := g.compileDescriptorSet(ctx, protoFiles, outPath, true)
+	if err != nil || data == nil {
+		return nil, warnings, err
+	}
+	files, err := ParseFileDescriptorSet(data)
+	return files, warnings, err
+}
+
+// compileDescriptorSet compiles the proto files with the embedded protoc into
+// a serialized FileDescriptorSet at outPath, optionally with source info.
+// Returns the descriptor set and the warnings printed by protoc.
+func (g *Generator) compileDescriptorSet(ctx context.Context, protoFiles []string, outPath string, sourceInfo bool) ([]byte, string, error) {
+	if len(protoFiles) == 0 {
+		return nil, "", nil
+	}
+	defer g.cleanupProjectSymlinks()
+	if err := g.setupProjectSymlinks(); err != nil {
+		return nil, "", fmt.Errorf("failed to setup project symlinks: %w", err)
+	}
+
+	var flags []string
+	if sourceInfo {
+		flags = append(flags, "--include_source_info")
+	}
+	warnings, err := g.execDescriptorSet(ctx, protoFiles, outPath, flags...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, warnings, nil
+}
+
+// execDescriptorSet runs protoc to write a FileDescriptorSet of the proto
+// files to outPath with the given extra protoc flags. The project symlinks
+// must be set up. Returns the warnings printed by protoc.
+func (g *Generator) execDescriptorSet(ctx context.Context, protoFiles []string, outPath string, flags ...string) (string, error) {
+	args := []string{"protoc"}
+	args = append(args, g.protocIncludeArgs()...)
+	args = append(args, flags...)
+	args = append(args, "--descriptor_set_out="+outPath)
+	for _, f := range protoFiles {
+		args = append(args, filepath.Join(g.VendorDir, g.ModulePath, f))
+	}
+	return g.execProtoc(ctx, g.Plugins, args, protoFiles, g.Stdout, filepath.Dir(outPath))
+}
+
+// extractGitProtoTree writes the .proto files of the git ref below dir to
+// dstDir, skipping vendored, node and tools directories.
+func extractGitProtoTree(ctx context.Context, dir, ref, dstDir, toolsDir string) error {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", "archive", "--format=tar", ref)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git archive %s: %w: %s", ref, err, strings.TrimSpace(stderr.String()))
+	}
+
+	if err := os.MkdirAll(dstDir, 0o755); err != nil {
+		return err
+	}
+
+	skipDirs := []string{"vendor", "node_modules"}
+	toolsRel := filepath.ToSlash(filepath.Clean(toolsDir)) + "/"
+	tr := tar.NewReader(&stdout)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("git archive %s: %w", ref, err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !strings.HasSuffix(hdr.Name, ".proto") || !filepath.IsLocal(hdr.Name) {
+			continue
+		}
+		parts := strings.Split(hdr.Name, "/")
+		if strings.HasPrefix(hdr.Name, toolsRel) || slices.ContainsFunc(parts[:len(parts)-1], func(part string) bool {
+			return slices.Contains(skipDirs, part)
+		}) {
+			continue
+		}
+
+		dst := filepath.Join(dstDir, filepath.FromSlash(hdr.Name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, data, 0o644); err != nil {
+			return err
+		}
+	}
+}
+
+// CompareDescriptors returns the breaking changes from the previous to the
+// current version of the proto files. Files are matched by name and elements
+// by name relative to the file's package. File names are reported relative
+// to the Go module path.
+func CompareDescriptors(previous, current []*FileDescriptor, modulePath string) []BreakingChange {
+	c := &breakingComparer{modulePath: modulePath}
+	currentFiles := make(map[string]*FileDescriptor, len(current))
+	for _, f := range current {
+		currentFiles[f.Name] = f
+	}
+	for _, prev := range previous {
+		cur, ok := currentFiles[prev.Name]
+		if !ok {
+			c.add(prev, nil, BreakingSource, BreakingFileRemoved, "file %s was removed", c.fileName(prev))
+			for _, svc := range prev.Services {
+				c.add(prev, svc.path, BreakingWire, BreakingServiceRemoved, "service %q was removed", prev.qualify(svc.Name))
+			}
+			continue
+		}
+		c.compareFiles(prev, cur)
+	}
+	slices.SortStableFunc(c.changes, func(a, b BreakingChange) int {
+		if a.File != b.File {
+			return strings.Compare(a.File, b.File)
+		}
+		return a.Line - b.Line
+	})
+	return c.changes
+}
+
+// breakingComparer accumulates the breaking changes between two descriptor
+// sets.
+type breakingComparer struct {
+	modulePath string
+	changes    []BreakingChange
+}
+
+// fileName returns the project-relative name of a file descriptor.
+func (c *breakingComparer) fileName(f *FileDescriptor) string {
+	return strings.TrimPrefix(f.Name, c.modulePath+"/")
+}
+
+// add records a breaking change located at the descriptor path in file.
+func (c *breakingComparer) add(file *FileDescriptor, path []int32, category BreakingCategory, kind, format string, args ...any) {
+	c.changes = append(c.changes, BreakingChange{
+		File:     c.fileName(file),
+		Line:     file.lineOf(path),
+		Category: category,
+		Kind:     kind,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// compareFiles compares two versions of the same proto file.
+func (c *breakingComparer) compareFiles(prev, cur *FileDescriptor) {
+	if prev.Package != cur.Package {
+		c.add(cur, []int32{2}, BreakingWire, BreakingPackageChanged, "package changed from %q to %q", prev.Package, cur.Package)
+	}
+	cmp := &fileComparer{breakingComparer: c, prev: prev, cur: cur}
+	cmp.compareMessages("", nil, prev.Messages, cur.Messages)
+	cmp.compareEnums("", nil, prev.Enums, cur.Enums)
+	cmp.compareServices()
+}
+
+// fileComparer compares the elements of two versions of a proto file.
+type fileComparer struct {
+	*breakingComparer
+	prev, cur *FileDescriptor
+}
+
+// compareMessages compares the messages declared in scope. parentPath is the
+// path of the enclosing message in the current file.
+func (c *fileComparer) compareMessages(scope string, parentPath []int32, prev, cur []*MessageDescriptor) {
+	for _, pm := range prev {
+		name := scope + pm.Name
+		cm := findByName(cur, pm.Name, func(m *MessageDescriptor) string { return m.Name })
+		if cm == nil {
+			if !pm.MapEntry {
+				c.add(c.cur, parentPath, BreakingSource, BreakingMessageRemoved, "message %q was removed", c.prev.qualify(name))
+			}
+			continue
+		}
+		c.compareFields(name, pm, cm)
+		c.compareReserved(name, cm.path, pm.ReservedRanges, cm.ReservedRanges, pm.ReservedNames, cm.ReservedNames, fieldNumbersAndNames(cm.Fields))
+		c.compareMessages(name+".", cm.path, pm.Nested, cm.Nested)
+		c.compareEnums(name+".", cm.path, pm.Enums, cm.Enums)
+	}
+}
+
+// compareFields compares the fields of two versions of a message.
+func (c *fileComparer) compareFields(msgName string, prev, cur *MessageDescriptor) {
+	qualified := c.prev.qualify(msgName)
+	for _, pf := range prev.Fields {
+		cf := findByName(cur.Fields, pf.Name, func(f *FieldDescriptor) string { return f.Name })
+		if cf == nil {
+			if byNumber := findFieldByNumber(cur.Fields, pf.Number); byNumber != nil {
+				c.add(c.cur, byNumber.path, BreakingSource, BreakingFieldRenamed, "field %d on %q was renamed from %q to %q", pf.Number, qualified, pf.Name, byNumber.Name)
+				cf = byNumber
+			} else {
+				category := BreakingWire
+				if isReserved(cur.ReservedRanges, pf.Number) {
+					category = BreakingSource
+				}
+				c.add(c.cur, cur.path, category, BreakingFieldRemoved, "field %q (%d) was removed from %q", pf.Name, pf.Number, qualified)
+				continue
+			}
+		} else if cf.Number != pf.Number {
+			c.add(c.cur, cf.path, BreakingWire, BreakingFieldNumberChanged, "field %q on %q changed number from %d to %d", pf.Name, qualified, pf.Number, cf.Number)
+		}
+
+		if pf.Type != cf.Type || c.prev.relativeType(pf.TypeName) != c.cur.relativeType(cf.TypeName) {
+			c.add(c.cur, cf.path, BreakingWire, BreakingFieldTypeChanged, "field %q on %q changed type from %s to %s", cf.Name, qualified, fieldTypeName(pf), fieldTypeName(cf))
+		}
+		switch {
+		case pf.Label != cf.Label:
+			c.add(c.cur, cf.path, BreakingWire, BreakingFieldCardinalityChanged, "field %q on %q changed from %s to %s", cf.Name, qualified, fieldCardinality(pf), fieldCardinality(cf))
+		case pf.Proto3Optional != cf.Proto3Optional || (pf.OneofIndex < 0) != (cf.OneofIndex < 0):
+			c.add(c.cur, cf.path, BreakingSource, BreakingFieldCardinalityChanged, "field %q on %q changed from %s to %s", cf.Name, qualified, fieldCardinality(pf), fieldCardinality(cf))
+		}
+	}
+}
+
+// compareReserved reports reserved numbers and names of prev that are no
+// longer reserved, and reserved entries now used by an element of cur.
+func (c *fileComparer) compareReserved(name string, path []int32, prevRanges, curRanges []ReservedRange, prevNames, curNames []string, used map[int32]string) {
+	qualified := c.prev.qualify(name)
+	for _, r := range prevRanges {
+		reused := false
+		for number, elem := range used {
+			if r.Contains(number) {
+				reused = true
+				c.add(c.cur, path, BreakingWire, BreakingReservedReused, "%q reuses reserved number %d for %q", qualified, number, elem)
+			}
+		}
+		if !reused && !rangeCovered(r, curRanges) {
+			c.add(c.cur, path, BreakingWire, BreakingReservedRemoved, "%q no longer reserves %s", qualified, formatReservedRange(r))
+		}
+	}
+	for _, reserved := range prevNames {
+		if slices.Contains(curNames, reserved) {
+			continue
+		}
+		kind := BreakingReservedRemoved
+		for _, elem := range used {
+			if elem == reserved {
+				kind = BreakingReservedReused
+			}
+		}
+		c.add(c.cur, path, BreakingSource, kind, "%q no longer reserves name %q", qualified, reserved)
+	}
+}
+
+// compareEnums compares the enums declared in scope.
+func (c *fileComparer) compareEnums(scope string, parentPath []int32, prev, cur []*EnumDescriptor) {
+	for _, pe := range prev {
+		name := scope + pe.Name
+		qualified := c.prev.qualify(name)
+		ce := findByName(cur, pe.Name, func(e *EnumDescriptor) string { return e.Name })
+		if ce == nil {
+			c.add(c.cur, parentPath, BreakingSource, BreakingEnumRemoved, "enum %q was removed", qualified)
+			continue
+		}
+		used := make(map[int32]string, len(ce.Values))
+		for _, pv := range pe.Values {
+			if cv := findByName(ce.Values, pv.Name, func(v *EnumValueDescriptor) string { return v.Name }); cv != nil {
+				if cv.Number != pv.Number {
+					c.add(c.cur, cv.path, BreakingWire, BreakingEnumValueNumberChanged, "enum value %q on %q changed number from %d to %d", pv.Name, qualified, pv.Number, cv.Number)
+				}
+				continue
+			}
+			if renamed := findEnumValueByNumber(ce.Values, pv.Number); renamed != nil {
+				c.add(c.cur, renamed.path, BreakingSource, BreakingEnumValueRenamed, "enum value %d on %q was renamed from %q to %q", pv.Number, qualified, pv.Name, renamed.Name)
+			} else {
+				category := BreakingWire
+				if isReserved(ce.ReservedRanges, pv.Number) {
+					category = BreakingSource
+				}
+				c.add(c.cur, ce.path, category, BreakingEnumValueRemoved, "enum value %q (%d) was removed from %q", pv.Name, pv.Number, qualified)
+			}
+		}
+		for _, cv := range ce.Values {
+			used[cv.Number] = cv.Name
+		}
+		c.compareReserved(name, ce.path, pe.ReservedRanges, ce.ReservedRanges, pe.ReservedNames, ce.ReservedNames, used)
+	}
+}
+
+// compareServices compares the RPC services of the file.
+func (c *fileComparer) compareServices() {
+	for _, ps := range c.prev.Services {
+		qualified := c.prev.qualify(ps.Name)
+		cs := findByName(c.cur.Services, ps.Name, func(s *ServiceDescriptor) string { return s.Name })
+		if cs == nil {
+			c.add(c.cur, nil, BreakingWire, BreakingServiceRemoved, "service %q was removed", qualified)
+			continue
+		}
+		for _, pm := range ps.Methods {
+			cm := findByName(cs.Methods, pm.Name, func(m *MethodDescriptor) string { return m.Name })
+			if cm == nil {
+				c.add(c.cur, cs.path, BreakingWire, BreakingMethodRemoved, "method %q was removed from %q", pm.Name, qualified)
+				continue
+			}
+			if c.prev.relativeType(pm.InputType) != c.cur.relativeType(cm.InputType) ||
+				c.prev.relativeType(pm.OutputType) != c.cur.relativeType(cm.OutputType) {
+				c.add(c.cur, cm.path, BreakingWire, BreakingMethodTypeChanged, "method %q on %q changed signature from (%s) returns (%s) to (%s) returns (%s)",
+					pm.Name, qualified, strings.TrimPrefix(pm.InputType, "."), strings.TrimPrefix(pm.OutputType, "."),
+					strings.TrimPrefix(cm.InputType, "."), strings.TrimPrefix(cm.OutputType, "."))
+			}
+			if pm.ClientStreaming != cm.ClientStreaming || pm.ServerStreaming != cm.ServerStreaming {
+				c.add(c.cur, cm.path, BreakingWire, BreakingMethodStreamingChanged, "method %q on %q changed streaming", pm.Name, qualified)
+			}
+		}
+	}
+}
+
+// qualify returns the fully qualified name of an element declared in the file.
+func (f *FileDescriptor) qualify(name string) string {
+	if f.Package == "" {
+		return name
+	}
+	return f.Package + "." + name
+}
+
+// relativeType strips the file's package from a fully qualified type name so
+// types can be compared across a package rename.
+func (f *FileDescriptor) relativeType(typeName string) string {
+	if f.Package == "" {
+		return strings.TrimPrefix(typeName, ".")
+	}
+	return strings.TrimPrefix(typeName, "."+f.Package+".")
+}
+
+// lineOf returns the line of the element at path, falling back to its closest
+// parent with source info and then to the package statement.
+func (f *FileDescriptor) lineOf(path []int32) int {
+	for len(path) >= 2 {
+		if line := f.Line(path); line != 0 {
+			return line
+		}
+		path = path[:len(path)-2]
+	}
+	if line := f.Line([]int32{2}); line != 0 {
+		return line
+	}
+	return 1
+}
+
+// findByName returns the element with the given name, or nil.
+func findByName[T any](elems []*T, name string, getName func(*T) string) *T {
+	for _, elem := range elems {
+		if getName(elem) == name {
+			return elem
+		}
+	}
+	return nil
+}
+
+// findFieldByNumber returns the field with the given number, or nil.
+func findFieldByNumber(fields []*FieldDescriptor, number int32) *FieldDescriptor {
+	for _, f := range fields {
+		if f.Number == number {
+			return f
+		}
+	}
+	return nil
+}
+
+// findEnumValueByNumber returns the first enum value with the given number,
+// or nil.
+func findEnumValueByNumber(values []*EnumValueDescriptor, number int32) *EnumValueDescriptor {
+	for _, v := range values {
+		if v.Number == number {
+			return v
+		}
+	}
+	return nil
+}
+
+// fieldNumbersAndNames maps the field numbers of a message to field names.
+func fieldNumbersAndNames(fields []*FieldDescriptor) map[int32]string {
+	used := make(map[int32]string, len(fields))
+	for _, f := range fields {
+		used[f.Number] = f.Name
+	}
+	return used
+}
+
+// isReserved returns true if the number is inside any of the ranges.
+func isReserved(ranges []ReservedRange, number int32) bool {
+	return slices.ContainsFunc(ranges, func(r ReservedRange) bool {
+		return r.Contains(number)
+	})
+}
+
+// rangeCovered returns true if every number in r is inside one of the ranges.
+func rangeCovered(r ReservedRange, ranges []ReservedRange) bool {
+	sorted := slices.Clone(ranges)
+	slices.SortFunc(sorted, func(a, b ReservedRange) int {
+		return int(a.Start) - int(b.Start)
+	})
+	next := r.Start
+	for _, other := range sorted {
+		if next >= r.End {
+			break
+		}
+		if other.Start <= next && other.End > next {
+			next = other.End
+		}
+	}
+	return next >= r.End
+}
+
+// formatReservedRange formats a reserved range as in a reserved statement.
+func formatReservedRange(r ReservedRange) string {
+	if r.End-r.Start == 1 {
+		return fmt.Sprintf("number %d", r.Start)
+	}
+	return fmt.Sprintf("numbers %d to %d", r.Start, r.End-1)
+}
+
+// fieldTypeNames are the names of FieldDescriptorProto.Type values.
+var fieldTypeNames = []string{
+	1: "double", 2: "float", 3: "int64", 4: "uint64", 5: "int32",
+	6: "fixed64", 7: "fixed32", 8: "bool", 9: "string", 10: "group",
+	11: "message", 12: "bytes", 13: "uint32", 14: "enum", 15: "sfixed32",
+	16: "sfixed64", 17: "sint32", 18: "sint64",
+}
+
+// fieldTypeName returns the proto type name of a field.
+func fieldTypeName(f *FieldDescriptor) string {
+	if f.TypeName != "" {
+		return strings.TrimPrefix(f.TypeName, ".")
+	}
+	if int(f.Type) < len(fieldTypeNames) && fieldTypeNames[f.Type] != "" {
+		return fieldTypeNames[f.Type]
+	}
+	return fmt.Sprintf("type %d", f.Type)
+}
+
+// fieldCardinality describes the label and presence of a field.
+func fieldCardinality(f *FieldDescriptor) string {
+	switch {
+	case f.Label == fieldLabelRepeated:
+		return "repeated"
+	case f.Label == fieldLabelRequired:
+		return "required"
+	case f.Proto3Optional:
+		return "optional"
+	case f.OneofIndex >= 0:
+		return "oneof member"
+	default:
+		return "singular"
+	}
+}
@@ -0,0 +1,175 @@
+package protogen
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// commitAll commits the working tree of the git repository at dir.
+func commitAll(t *testing.T, dir string) {
+	t.Helper()
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "baseline"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+}
+
+func TestBreakingReportsIncompatibleChanges(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"api/api.proto": `syntax = "proto3";
+package api;
+
+message Req {
+  string id = 1;
+  int32 count = 2;
+  string note = 3;
+  repeated string tags = 4;
+  reserved 10;
+}
+
+message Resp {}
+
+service Svc {
+  rpc Get(Req) returns (Resp);
+  rpc List(Req) returns (Resp);
+}
+`,
+		"old/old.proto": "syntax = \"proto3\";\npackage old;\nmessage Old {}\n",
+	})
+	commitAll(t, g.ProjectDir)
+
+	current := `syntax = "proto3";
+package api;
+
+message Req {
+  string id = 5;
+  int64 count = 2;
+  string tags = 4;
+  string reuse = 10;
+}
+
+message Resp {}
+
+service Svc {
+  rpc Get(Req) returns (Resp);
+}
+`
+	if err := os.WriteFile(filepath.Join(g.ProjectDir, "api", "api.proto"), []byte(current), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("git", "rm", "-rq", "old")
+	cmd.Dir = g.ProjectDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git rm: %v\n%s", err, out)
+	}
+
+	changes, err := g.Breaking(context.Background(), "HEAD")
+	if err != nil {
+		t.Fatalf("breaking: %v", err)
+	}
+
+	type key struct {
+		file, kind string
+		line       int
+		category   BreakingCategory
+	}
+	got := make(map[key]bool)
+	for _, c := range changes {
+		got[key{c.File, c.Kind, c.Line, c.Category}] = true
+	}
+	for _, want := range []key{
+		{"api/api.proto", BreakingFieldNumberChanged, 5, BreakingWire},
+		{"api/api.proto", BreakingFieldTypeChanged, 6, BreakingWire},
+		{"api/api.proto", BreakingFieldRemoved, 4, BreakingWire},
+		{"api/api.proto", BreakingFieldCardinalityChanged, 7, BreakingWire},
+		{"api/api.proto", BreakingReservedReused, 4, BreakingWire},
+		{"api/api.proto", BreakingMethodRemoved, 13, BreakingWire},
+		{"old/old.proto", BreakingFileRemoved, 2, BreakingSource},
+	} {
+		if !got[want] {
+			t.Errorf("missing %+v in %v", want, changes)
+		}
+	}
+	if len(changes) != 7 {
+		t.Errorf("expected 7 changes, got %d: %v", len(changes), changes)
+	}
+}
+
+func TestBreakingNoChanges(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"a/a.proto": "syntax = \"proto3\";\npackage a;\nmessage A { string id = 1; }\n",
+	})
+	commitAll(t, g.ProjectDir)
+
+	// Adding fields and messages is compatible.
+	if err := os.WriteFile(filepath.Join(g.ProjectDir, "a", "a.proto"), []byte("syntax = \"proto3\";\npackage a;\nmessage A { string id = 1; string name = 2; }\nmessage B {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := g.Breaking(context.Background(), "HEAD")
+	if err != nil {
+		t.Fatalf("breaking: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("unexpected changes: %v", changes)
+	}
+
+	if _, err := g.Breaking(context.Background(), "does-not-exist"); err == nil {
+		t.Fatal("expected error for unknown ref")
+	}
+}
+
+func TestCompareDescriptorsPackageRename(t *testing.T) {
+	prev := []*FileDescriptor{{
+		Name:    "example.com/project/a/a.proto",
+		Package: "a.v1",
+		Messages: []*MessageDescriptor{{
+			Name:   "A",
+			Fields: []*FieldDescriptor{{Name: "b", Number: 1, Type: 11, TypeName: ".a.v1.B", OneofIndex: -1}},
+		}, {Name: "B"}},
+		Enums: []*EnumDescriptor{{
+			Name:           "Kind",
+			Values:         []*EnumValueDescriptor{{Name: "KIND_NONE"}, {Name: "KIND_A", Number: 1}},
+			ReservedRanges: []ReservedRange{{Start: 5, End: 10}},
+		}},
+	}}
+	cur := []*FileDescriptor{{
+		Name:    "example.com/project/a/a.proto",
+		Package: "a.v2",
+		Messages: []*MessageDescriptor{{
+			Name:   "A",
+			Fields: []*FieldDescriptor{{Name: "b", Number: 1, Type: 11, TypeName: ".a.v2.B", OneofIndex: -1}},
+		}, {Name: "B"}},
+		Enums: []*EnumDescriptor{{
+			Name:           "Kind",
+			Values:         []*EnumValueDescriptor{{Name: "KIND_NONE"}, {Name: "KIND_RENAMED", Number: 1}},
+			ReservedRanges: []ReservedRange{{Start: 5, End: 8}},
+		}},
+	}}
+
+	changes := CompareDescriptors(prev, cur, "example.com/project")
+	kinds := make([]string, len(changes))
+	for i, c := range changes {
+		kinds[i] = c.Kind
+		if c.File != "a/a.proto" || c.Line != 1 {
+			t.Errorf("unexpected location %s:%d", c.File, c.Line)
+		}
+	}
+	want := []string{BreakingPackageChanged, BreakingEnumValueRenamed, BreakingReservedRemoved}
+	if len(kinds) != len(want) {
+		t.Fatalf("got kinds %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("got kinds %v, want %v", kinds, want)
+		}
+	}
+}
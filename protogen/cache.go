@@ -11,7 +11,7 @@ import (
 )
 
 // CacheVersion is the current cache format version.
-const CacheVersion = 2
+const CacheVersion = 3
 
 // Cache represents the protoc manifest cache.
 type Cache struct {
@@ -23,6 +23,9 @@ type Cache struct {
 	ToolVersions string `json:"toolVersions,omitempty"`
 	// Packages maps package identifiers to package info.
 	Packages map[string]*PackageInfo `json:"packages"`
+	// DescriptorSet records the descriptor set files written for all proto
+	// files. Nil if none are configured.
+	DescriptorSet *PackageInfo `json:"descriptorSet,omitempty"`
 }
 
 // PackageInfo contains cached information about a proto package.
@@ -33,6 +36,20 @@ type PackageInfo struct {
 	GeneratedFiles []string `json:"generatedFiles"`
 	// ProtoFiles is the list of source proto file paths.
 	ProtoFiles []string `json:"protoFiles"`
+	// ProtocFlagsHash is the hash of the protoc flags used for this package.
+	// Packages in different profiles are generated with different flags.
+	// Falls back to the top-level hash when empty.
+	ProtocFlagsHash string `json:"protocFlagsHash,omitempty"`
+	// ProtocFlags are the protoc flags used for this package, relativized like
+	// the hash. Used to explain which flag changed.
+	ProtocFlags []string `json:"protocFlags,omitempty"`
+	// FileHashes maps each proto file to its content hash. Used to explain
+	// which file changed.
+	FileHashes map[string]string `json:"fileHashes,omitempty"`
+	// Imports maps the import path of every file in the transitive import
+	// closure of the package, excluding its own files, to its content hash.
+	// Imports that could not be found map to an empty string.
+	Imports map[string]string `json:"imports,omitempty"`
 }
 
 // NewCache creates a new empty cache.
@@ -86,11 +103,67 @@ func (c *Cache) SetProtocFlags(flags []string, rootDir string) {
 	c.ProtocFlagsHash = HashProtocFlags(flags, rootDir)
 }
 
+// SetPackageProtocFlags sets the protoc flags and their hash for a cached
+// package.
+func (c *Cache) SetPackageProtocFlags(packageKey string, flags []string, rootDir string) {
+	if info := c.Packages[packageKey]; info != nil {
+		info.ProtocFlagsHash = HashProtocFlags(flags, rootDir)
+		info.ProtocFlags = relativizeProtocFlags(flags, rootDir)
+	}
+}
+
+// SetPackageImports sets the import closure for a cached package.
+func (c *Cache) SetPackageImports(packageKey string, imports map[string]string) {
+	if info := c.Packages[packageKey]; info != nil {
+		info.Imports = imports
+	}
+}
+
+// ChangedImports returns the sorted import paths in the import closure of a
+// cached package whose content changed. hash returns the current content hash
+// for an import path.
+func (c *Cache) ChangedImports(packageKey string, hash func(importPath string) string) []string {
+	info := c.Packages[packageKey]
+	if info == nil {
+		return nil
+	}
+	var changed []string
+	for importPath, cached := range info.Imports {
+		if hash(importPath) != cached {
+			changed = append(changed, importPath)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
 // SetToolVersions sets the tool versions string.
 func (c *Cache) SetToolVersions(versions string) {
 	c.ToolVersions = versions
 }
 
+// StaleReason is the reason a cached package needs regeneration.
+type StaleReason string
+
+const (
+	// StaleReasonNone means the package is up to date.
+	StaleReasonNone StaleReason = ""
+	// StaleReasonForce means regeneration was forced.
+	StaleReasonForce StaleReason = "force"
+	// StaleReasonToolVersions means the selected tool versions changed.
+	StaleReasonToolVersions StaleReason = "tool-versions"
+	// StaleReasonNewPackage means the package is not in the cache.
+	StaleReasonNewPackage StaleReason = "new-package"
+	// StaleReasonFlags means the protoc flags changed.
+	StaleReasonFlags StaleReason = "flags"
+	// StaleReasonProtoFiles means the proto file list changed.
+	StaleReasonProtoFiles StaleReason = "proto-files"
+	// StaleReasonContent means the content hash of the proto files changed.
+	StaleReasonContent StaleReason = "content"
+	// StaleReasonImports means a file in the import closure changed.
+	StaleReasonImports StaleReason = "imports"
+)
+
 // NeedsRegeneration checks if a cached package needs regeneration.
 // Returns true if:
 // - The package is not in the cache
@@ -98,36 +171,46 @@ func (c *Cache) SetToolVersions(versions string) {
 // - The protoc flags or selected tool versions have changed
 // - Force is true
 func (c *Cache) NeedsRegeneration(packageKey string, protoFiles []string, projectDir string, flagsHash string, toolVersions string, force bool) (bool, error) {
+	return c.staleReason(packageKey, protoFiles, projectDir, flagsHash, toolVersions, force) != StaleReasonNone, nil
+}
+
+// staleReason returns the first condition that requires regenerating a cached
+// package, or StaleReasonNone if it is up to date. The import closure is not
+// checked.
+func (c *Cache) staleReason(packageKey string, protoFiles []string, projectDir string, flagsHash string, toolVersions string, force bool) StaleReason {
 	if force {
-		return true, nil
+		return StaleReasonForce
+	}
+
+	info, ok := c.Packages[packageKey]
+	if !ok {
+		return StaleReasonNewPackage
 	}
 
 	if c.ToolVersions != toolVersions {
-		return true, nil
+		return StaleReasonToolVersions
 	}
 
 	// Check if flags changed
-	if c.ProtocFlagsHash != flagsHash {
-		return true, nil
+	cachedFlagsHash := info.ProtocFlagsHash
+	if cachedFlagsHash == "" {
+		cachedFlagsHash = c.ProtocFlagsHash
 	}
-
-	info, ok := c.Packages[packageKey]
-	if !ok {
-		return true, nil
+	if cachedFlagsHash != flagsHash {
+		return StaleReasonFlags
 	}
 
 	// Check if proto files list changed
 	if !stringsEqual(info.ProtoFiles, protoFiles) {
-		return true, nil
+		return StaleReasonProtoFiles
 	}
 
 	// Check content hash
 	currentHash, err := hashProtoFiles(protoFiles, projectDir)
-	if err != nil {
-		return true, nil
+	if err != nil || info.Hash != currentHash {
+		return StaleReasonContent
 	}
-
-	return info.Hash != currentHash, nil
+	return StaleReasonNone
 }
 
 // UpdatePackage updates the cache for a package after generation.
@@ -136,13 +219,79 @@ func (c *Cache) UpdatePackage(packageKey string, protoFiles []string, generatedF
 	if err != nil {
 		return err
 	}
+	fileHashes, err := hashEachProtoFile(protoFiles, projectDir)
+	if err != nil {
+		return err
+	}
 
 	c.Packages[packageKey] = &PackageInfo{
 		Hash:           hash,
 		GeneratedFiles: generatedFiles,
 		ProtoFiles:     protoFiles,
+		FileHashes:     fileHashes,
+	}
+
+	return nil
+}
+
+// NeedsDescriptorSet checks if the descriptor set files need to be written.
+// Returns true if outputs are configured and:
+// - They were not written before or the configured outputs changed
+// - The proto file list or content hash has changed
+// - A file in the import closure changed, hash returns its current hash
+// - An output file is missing
+// - The selected tool versions have changed
+// - Force is true
+// Also returns true if previously written outputs are no longer configured.
+func (c *Cache) NeedsDescriptorSet(protoFiles []string, outputs []string, projectDir string, toolVersions string, force bool, hash func(importPath string) string) bool {
+	info := c.DescriptorSet
+	if info == nil {
+		return len(outputs) != 0
+	}
+	if !stringsEqual(info.GeneratedFiles, outputs) {
+		return true
+	}
+	if len(outputs) == 0 {
+		return false
+	}
+	if force || c.ToolVersions != toolVersions || !stringsEqual(info.ProtoFiles, protoFiles) {
+		return true
+	}
+	currentHash, err := hashProtoFiles(protoFiles, projectDir)
+	if err != nil || info.Hash != currentHash {
+		return true
+	}
+	for importPath, cached := range info.Imports {
+		if hash(importPath) != cached {
+			return true
+		}
+	}
+	for _, out := range outputs {
+		if _, err := os.Stat(filepath.Join(projectDir, out)); err != nil {
+			return true
+		}
 	}
+	return false
+}
 
+// UpdateDescriptorSet records the descriptor set files written for the proto
+// files along with the hashes of their import closure. An empty list of
+// outputs clears the entry.
+func (c *Cache) UpdateDescriptorSet(protoFiles []string, outputs []string, projectDir string, imports map[string]string) error {
+	if len(outputs) == 0 {
+		c.DescriptorSet = nil
+		return nil
+	}
+	hash, err := hashProtoFiles(protoFiles, projectDir)
+	if err != nil {
+		return err
+	}
+	c.DescriptorSet = &PackageInfo{
+		Hash:           hash,
+		GeneratedFiles: outputs,
+		ProtoFiles:     protoFiles,
+		Imports:        imports,
+	}
 	return nil
 }
 
@@ -175,6 +324,20 @@ func hashProtoFiles(protoFiles []string, projectDir string) (string, error) {
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// hashEachProtoFile computes the content hash of each proto file.
+func hashEachProtoFile(protoFiles []string, projectDir string) (map[string]string, error) {
+	hashes := make(map[string]string, len(protoFiles))
+	for _, f := range protoFiles {
+		data, err := os.ReadFile(filepath.Join(projectDir, f))
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		hashes[f] = hex.EncodeToString(sum[:])
+	}
+	return hashes, nil
+}
+
 // HashProtocFlags computes a checkout-portable hash of the protoc flags.
 // Absolute path prefixes under rootDir are rewritten to rootDir-relative form
 // so two checkouts of identical content at different absolute paths hash the
@@ -182,14 +345,23 @@ func hashProtoFiles(protoFiles []string, projectDir string) (string, error) {
 // still change the hash. rootDir must be the absolute Go module root.
 func HashProtocFlags(flags []string, rootDir string) string {
 	h := sha256.New()
-	for _, f := range flags {
-		h.Write([]byte(relativizeProtocFlag(f, rootDir)))
+	for _, f := range relativizeProtocFlags(flags, rootDir) {
+		h.Write([]byte(f))
 		// NUL separates flags so distinct slices cannot collide.
 		h.Write([]byte{0})
 	}
 	return hex.EncodeToString(h.Sum(nil))
 }
 
+// relativizeProtocFlags relativizes each protoc flag to rootDir.
+func relativizeProtocFlags(flags []string, rootDir string) []string {
+	out := make([]string, len(flags))
+	for i, f := range flags {
+		out[i] = relativizeProtocFlag(f, rootDir)
+	}
+	return out
+}
+
 // relativizeProtocFlag rewrites the rootDir absolute prefix inside a single
 // protoc flag to rootDir-relative form. The flag may be a bare path or a
 // "--name=<value>" pair; only the value's path portion is rewritten, and
@@ -0,0 +1,265 @@
+package protogen
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// StaleFile describes a generated file that differs from a fresh generation.
+type StaleFile struct {
+	// Path is the project-relative path of the generated file.
+	Path string
+	// Diff is the unified diff from the committed file to the fresh output.
+	Diff string
+}
+
+// Check runs the full generation pipeline (protoc, post-processing and
+// formatting) into a scratch directory and compares the result byte-for-byte
+// against the generated files in the project. It never writes to the project.
+// Returns the generated files that are stale, missing or no longer produced.
+func (g *Generator) Check(ctx context.Context) ([]StaleFile, error) {
+	protoFiles, err := DiscoverProtoFiles(g.ProjectDir, g.Config.Targets, g.Config.Exclude, g.Config.IncludeUntracked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover proto files: %w", err)
+	}
+	if len(protoFiles) == 0 {
+		return nil, nil
+	}
+
+	scratchDir, err := os.MkdirTemp("", "aptre-check-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scratchDir)
+
+	scratch, err := g.newScratchGenerator(scratchDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare scratch directory: %w", err)
+	}
+	defer scratch.cleanupProjectSymlinks()
+	if err := scratch.setupProjectSymlinks(); err != nil {
+		return nil, fmt.Errorf("failed to setup scratch symlinks: %w", err)
+	}
+	if err := scratch.generateFiles(ctx, protoFiles); err != nil {
+		return nil, err
+	}
+
+	// Collect the fresh outputs and everything the project currently claims.
+	fresh := make(map[string]struct{})
+	committed := make(map[string]struct{})
+	for _, f := range protoFiles {
+		plugins := g.pluginsForFile(f)
+		gf, err := plugins.FindGeneratedFiles(f, scratch.ProjectDir, scratch.VendorDir, g.ModulePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range gf {
+			fresh[p] = struct{}{}
+		}
+		gf, err = plugins.FindGeneratedFiles(f, g.ProjectDir, g.VendorDir, g.ModulePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range gf {
+			committed[p] = struct{}{}
+		}
+		if info := g.Cache.Packages[GetPackageKey(g.ModulePath, f)]; info != nil {
+			for _, p := range info.GeneratedFiles {
+				committed[p] = struct{}{}
+			}
+		}
+	}
+	for _, p := range g.Config.DescriptorSets {
+		fresh[filepath.Clean(p)] = struct{}{}
+	}
+	if info := g.Cache.DescriptorSet; info != nil {
+		for _, p := range info.GeneratedFiles {
+			committed[filepath.Clean(p)] = struct{}{}
+		}
+	}
+
+	paths := make([]string, 0, len(fresh)+len(committed))
+	for p := range fresh {
+		paths = append(paths, p)
+	}
+	for p := range committed {
+		if _, ok := fresh[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	slices.Sort(paths)
+
+	var stale []StaleFile
+	for _, p := range paths {
+		aName, bName := "a/"+filepath.ToSlash(p), "b/"+filepath.ToSlash(p)
+		var want []byte
+		if _, ok := fresh[p]; ok {
+			want, err = os.ReadFile(filepath.Join(scratch.ProjectDir, p))
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			bName = "/dev/null"
+		}
+		have, err := os.ReadFile(filepath.Join(g.ProjectDir, p))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+			if bName == "/dev/null" {
+				continue
+			}
+			aName = "/dev/null"
+		}
+		if diff := UnifiedDiff(aName, bName, have, want); diff != "" {
+			stale = append(stale, StaleFile{Path: p, Diff: diff})
+		}
+	}
+	return stale, nil
+}
+
+// newScratchGenerator returns a copy of the generator that reads a snapshot of
+// the project's proto files from scratchDir and writes all outputs, the cache
+// and the protoc symlinks there. Vendored dependencies are linked read-only
+// from the real vendor directory.
+func (g *Generator) newScratchGenerator(scratchDir string) (*Generator, error) {
+	projectDir := filepath.Join(scratchDir, "project")
+	if err := copyProtoTree(g.ProjectDir, projectDir, g.Config.ToolsDir); err != nil {
+		return nil, err
+	}
+	return g.newSnapshotGenerator(scratchDir)
+}
+
+// newSnapshotGenerator returns a copy of the generator that reads the proto
+// files already placed in scratchDir/project and writes all outputs, the
+// cache and the protoc symlinks under scratchDir.
+func (g *Generator) newSnapshotGenerator(scratchDir string) (*Generator, error) {
+	projectDir := filepath.Join(scratchDir, "project")
+	vendorDir := filepath.Join(scratchDir, "vendor")
+
+	if err := linkVendorTree(g.VendorDir, vendorDir, []string{g.ModulePath, pythonModulePath(g.ModulePath)}); err != nil {
+		return nil, err
+	}
+	// Formatting uses the project's tools directory.
+	toolsDir := filepath.Join(g.ProjectDir, g.Config.ToolsDir)
+	if _, err := os.Stat(toolsDir); err == nil && !filepath.IsAbs(g.Config.ToolsDir) {
+		scratchTools := filepath.Join(projectDir, g.Config.ToolsDir)
+		if err := os.MkdirAll(filepath.Dir(scratchTools), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.Symlink(toolsDir, scratchTools); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := *g.Config
+	cfg.ProjectDir = projectDir
+	cfg.Force = true
+
+	scratch := *g
+	scratch.Config = &cfg
+	// Custom plugin output directories inside the project move to scratch.
+	scratch.Plugins = g.Plugins.rebaseOutDirs(g.ProjectDir, projectDir)
+	scratch.ProfilePlugins = make(map[string]*Plugins, len(g.ProfilePlugins))
+	for name, plugins := range g.ProfilePlugins {
+		scratch.ProfilePlugins[name] = plugins.rebaseOutDirs(g.ProjectDir, projectDir)
+	}
+	scratch.Cache = NewCache()
+	scratch.ProjectDir = projectDir
+	scratch.ModuleDir = projectDir
+	scratch.VendorDir = vendorDir
+	scratch.OutDir = vendorDir
+	scratch.Events = nil
+	scratch.Explain = false
+	return &scratch, nil
+}
+
+// copyProtoTree copies every .proto file under srcDir to dstDir, skipping
+// version control, vendored, node and tools directories.
+func copyProtoTree(srcDir, dstDir, toolsDir string) error {
+	skipDirs := map[string]struct{}{
+		".git":         {},
+		"vendor":       {},
+		"node_modules": {},
+	}
+	toolsRel := filepath.Clean(toolsDir)
+	return filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if _, skip := skipDirs[d.Name()]; skip || rel == toolsRel {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".proto") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dstDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(dst, data, 0o644)
+	})
+}
+
+// linkVendorTree mirrors srcVendor into dstVendor using symlinks. Directories
+// along each of the given module paths are created as real directories so the
+// module path itself can be mapped to a different project without writing
+// into srcVendor.
+func linkVendorTree(srcVendor, dstVendor string, modulePaths []string) error {
+	realDirs := map[string]struct{}{".": {}}
+	reserved := make(map[string]struct{}, len(modulePaths))
+	for _, modulePath := range modulePaths {
+		modulePath = filepath.Clean(modulePath)
+		reserved[modulePath] = struct{}{}
+		for dir := filepath.Dir(modulePath); dir != "."; dir = filepath.Dir(dir) {
+			realDirs[dir] = struct{}{}
+		}
+	}
+
+	dirs := make([]string, 0, len(realDirs))
+	for dir := range realDirs {
+		dirs = append(dirs, dir)
+	}
+	slices.Sort(dirs)
+	for _, dir := range dirs {
+		if err := os.MkdirAll(filepath.Join(dstVendor, dir), 0o755); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(filepath.Join(srcVendor, dir))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		for _, entry := range entries {
+			rel := filepath.Join(dir, entry.Name())
+			if _, ok := realDirs[rel]; ok {
+				continue
+			}
+			if _, ok := reserved[rel]; ok {
+				continue
+			}
+			if err := os.Symlink(filepath.Join(srcVendor, rel), filepath.Join(dstVendor, rel)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package protogen
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newCppTestGenerator returns a generator for a temporary module that only
+// emits protoc's built-in C++ outputs, so no plugin binaries are needed.
+func newCppTestGenerator(t *testing.T, files map[string]string) *Generator {
+	t.Helper()
+	dir, _ := writeProtoTree(t, files)
+	vendorDir := filepath.Join(dir, "vendor")
+	if err := os.MkdirAll(vendorDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"add", "-A"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	cfg := NewConfig()
+	cfg.ProjectDir = dir
+	cfg.Targets = []string{"*.proto"}
+	// Keep compiled modules out of the user cache directory.
+	cfg.WasmCacheDir = t.TempDir()
+	return &Generator{
+		Config:     cfg,
+		Plugins:    &Plugins{Languages: Languages{LanguageCpp: {}}, RPCLibraries: RPCLibraries{}},
+		Cache:      NewCache(),
+		ProjectDir: dir,
+		ModuleDir:  dir,
+		ModulePath: "example.com/project",
+		VendorDir:  vendorDir,
+		OutDir:     vendorDir,
+		Stdout:     io.Discard,
+		Stderr:     io.Discard,
+	}
+}
+
+func TestCheckDetectsStaleGeneratedFiles(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"foo/foo.proto": "syntax = \"proto3\";\npackage foo;\nmessage Foo { string id = 1; }\n",
+	})
+	ctx := context.Background()
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	stale, err := g.Check(ctx)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(stale) != 0 {
+		t.Fatalf("fresh output reported stale: %v", stale)
+	}
+
+	header := filepath.Join(g.ProjectDir, "foo", "foo.pb.h")
+	data, err := os.ReadFile(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := append([]byte("// hand edit\n"), data...)
+	if err := os.WriteFile(header, edited, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(g.ProjectDir, "foo", "foo.pb.cc")); err != nil {
+		t.Fatal(err)
+	}
+
+	stale, err = g.Check(ctx)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(stale) != 2 {
+		t.Fatalf("expected 2 stale files, got %v", stale)
+	}
+	if stale[0].Path != filepath.Join("foo", "foo.pb.cc") || !strings.Contains(stale[0].Diff, "--- /dev/null") {
+		t.Fatalf("missing file not reported as new: %+v", stale[0])
+	}
+	if stale[1].Path != filepath.Join("foo", "foo.pb.h") || !strings.Contains(stale[1].Diff, "-// hand edit") {
+		t.Fatalf("edited file not reported: %+v", stale[1])
+	}
+
+	// The check must not touch the working tree.
+	after, err := os.ReadFile(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(edited) {
+		t.Fatal("check rewrote a generated file in the project")
+	}
+	if _, err := os.Stat(filepath.Join(g.ProjectDir, "foo", "foo.pb.cc")); !os.IsNotExist(err) {
+		t.Fatal("check restored a generated file in the project")
+	}
+	if _, err := os.Lstat(filepath.Join(g.VendorDir, g.ModulePath)); !os.IsNotExist(err) {
+		t.Fatal("check left a project symlink in the vendor directory")
+	}
+}
+
+func TestLinkVendorTreeKeepsModulePathWritable(t *testing.T) {
+	src := t.TempDir()
+	for _, dir := range []string{
+		"github.com/aperturerobotics/protobuf/src",
+		"github.com/aperturerobotics/util",
+		"github.com/other/lib",
+	} {
+		if err := os.MkdirAll(filepath.Join(src, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dst := filepath.Join(t.TempDir(), "vendor")
+	if err := linkVendorTree(src, dst, []string{"github.com/aperturerobotics/common"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{"github.com", "github.com/aperturerobotics"} {
+		info, err := os.Lstat(filepath.Join(dst, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 || !info.IsDir() {
+			t.Fatalf("%s must be a real directory", dir)
+		}
+	}
+	for _, dir := range []string{"github.com/aperturerobotics/protobuf", "github.com/other"} {
+		info, err := os.Lstat(filepath.Join(dst, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			t.Fatalf("%s must link to the source vendor directory", dir)
+		}
+	}
+}
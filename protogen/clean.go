@@ -0,0 +1,127 @@
+package protogen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CleanFilter selects the generated files removed by CleanSelected.
+type CleanFilter struct {
+	// Targets are the proto file patterns whose outputs are removed, with the
+	// same semantics as Config.Targets. Empty selects every proto file.
+	Targets []string
+	// Exclude are the proto file patterns whose outputs are kept.
+	Exclude []string
+	// Languages selects the outputs of these languages. Nil selects all.
+	Languages Languages
+	// RPCLibraries selects the RPC stubs of these libraries. Nil selects
+	// messages and RPC stubs alike.
+	RPCLibraries RPCLibraries
+}
+
+// matches returns true if the filter selects a generated file. Files of
+// unknown kind, such as custom plugin outputs, are only selected without
+// language and RPC filters.
+func (f *CleanFilter) matches(file string) bool {
+	if f.Languages == nil && f.RPCLibraries == nil {
+		return true
+	}
+	for _, kind := range generatedFileKinds {
+		if !strings.HasSuffix(file, kind.suffix) {
+			continue
+		}
+		if f.Languages != nil && !f.Languages.Has(kind.lang) {
+			return false
+		}
+		return f.RPCLibraries == nil || (kind.rpc != "" && f.RPCLibraries.Has(kind.rpc))
+	}
+	return false
+}
+
+// CleanSelected removes the generated files selected by the filter and
+// updates the cache to match instead of deleting it. Packages that lost files
+// are regenerated by the next Generate. Returns the removed project-relative
+// files, sorted.
+func (g *Generator) CleanSelected(filter *CleanFilter) ([]string, error) {
+	var selected map[string]struct{}
+	if len(filter.Targets) != 0 || len(filter.Exclude) != 0 {
+		targets := filter.Targets
+		if len(targets) == 0 {
+			targets = g.Config.Targets
+		}
+		protoFiles, err := DiscoverProtoFiles(g.ProjectDir, targets, filter.Exclude, g.Config.IncludeUntracked)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover proto files: %w", err)
+		}
+		selected = make(map[string]struct{}, len(protoFiles))
+		for _, f := range protoFiles {
+			selected[f] = struct{}{}
+		}
+	}
+	isSelected := func(protoFile string) bool {
+		if selected == nil {
+			return true
+		}
+		_, ok := selected[protoFile]
+		return ok
+	}
+
+	var removed []string
+	for key, info := range g.Cache.Packages {
+		allSelected := !slices.ContainsFunc(info.ProtoFiles, func(f string) bool { return !isSelected(f) })
+		var kept []string
+		for _, f := range info.GeneratedFiles {
+			owner := generatedFileOwner(f, info.ProtoFiles)
+			if (owner == "" && !allSelected) || (owner != "" && !isSelected(owner)) || !filter.matches(f) {
+				kept = append(kept, f)
+				continue
+			}
+			if err := os.Remove(filepath.Join(g.ProjectDir, f)); err != nil && !os.IsNotExist(err) {
+				return removed, err
+			}
+			removed = append(removed, f)
+		}
+		if len(kept) == len(info.GeneratedFiles) {
+			continue
+		}
+		if len(kept) == 0 {
+			delete(g.Cache.Packages, key)
+			continue
+		}
+		info.GeneratedFiles = kept
+		// Clearing the hash regenerates the package on the next run.
+		info.Hash = ""
+	}
+	slices.Sort(removed)
+
+	cacheFile, err := g.Config.GetCacheFilePath()
+	if err != nil {
+		return removed, err
+	}
+	return removed, g.Cache.Save(cacheFile)
+}
+
+// generatedFileOwner returns the proto file a generated file was generated
+// from, or an empty string if it cannot be determined. The longest matching
+// proto base name wins so a_b.pb.go belongs to a_b.proto rather than a.proto.
+func generatedFileOwner(file string, protoFiles []string) string {
+	var owner string
+	for _, p := range protoFiles {
+		baseName := strings.TrimSuffix(filepath.Base(p), ".proto")
+		var ok bool
+		if filepath.Dir(file) == filepath.Dir(p) {
+			name := filepath.Base(file)
+			ok = strings.HasPrefix(name, baseName+".") || strings.HasPrefix(name, baseName+"_")
+		}
+		if !ok && file == csharpFileName(baseName)+".cs" {
+			ok = true
+		}
+		if ok && len(p) > len(owner) {
+			owner = p
+		}
+	}
+	return owner
+}
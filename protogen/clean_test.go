@@ -0,0 +1,101 @@
+package protogen
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCleanSelected(t *testing.T) {
+	outputs := map[string][]string{
+		"api/api.proto":     {"api/api.pb.go", "api/api_srpc.pb.go", "api/api.pb.rs", "api/api.pb.cc", "api/api.pb.h"},
+		"api/api_ext.proto": {"api/api_ext.pb.go", "api/api_ext.pb.rs"},
+		"other/o.proto":     {"other/o.pb.go", "other/o.pb.rs"},
+	}
+	files := map[string]string{}
+	for proto, gen := range outputs {
+		files[proto] = "syntax = \"proto3\";\n"
+		for _, f := range gen {
+			files[f] = "// @generated\n"
+		}
+	}
+	g := newCppTestGenerator(t, files)
+	for _, protoFiles := range [][]string{{"api/api.proto", "api/api_ext.proto"}, {"other/o.proto"}} {
+		var gen []string
+		for _, f := range protoFiles {
+			gen = append(gen, outputs[f]...)
+		}
+		slices.Sort(gen)
+		if err := g.Cache.UpdatePackage(GetPackageKey(g.ModulePath, protoFiles[0]), protoFiles, gen, g.ProjectDir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	apiKey, otherKey := GetPackageKey(g.ModulePath, "api/api.proto"), GetPackageKey(g.ModulePath, "other/o.proto")
+
+	// Only the Rust outputs.
+	removed, err := g.CleanSelected(&CleanFilter{Languages: Languages{LanguageRust: {}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"api/api.pb.rs", "api/api_ext.pb.rs", "other/o.pb.rs"}; !slices.Equal(removed, want) {
+		t.Fatalf("unexpected removed files: %v", removed)
+	}
+	if _, err := os.Stat(filepath.Join(g.ProjectDir, "api", "api.pb.rs")); !os.IsNotExist(err) {
+		t.Fatal("rust output was not removed")
+	}
+	info := g.Cache.Packages[apiKey]
+	if info == nil || info.Hash != "" || slices.Contains(info.GeneratedFiles, "api/api.pb.rs") || !slices.Contains(info.GeneratedFiles, "api/api.pb.go") {
+		t.Fatalf("cache not updated: %+v", info)
+	}
+
+	// Only the RPC stubs of one proto file.
+	removed, err = g.CleanSelected(&CleanFilter{Targets: []string{"api/api.proto"}, RPCLibraries: RPCLibraries{RPCLibraryStarpc: {}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(removed, []string{"api/api_srpc.pb.go"}) {
+		t.Fatalf("unexpected removed files: %v", removed)
+	}
+
+	// Everything under a directory drops the package from the cache.
+	removed, err = g.CleanSelected(&CleanFilter{Targets: []string{"other/*.proto"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(removed, []string{"other/o.pb.go"}) {
+		t.Fatalf("unexpected removed files: %v", removed)
+	}
+	if _, ok := g.Cache.Packages[otherKey]; ok {
+		t.Fatal("cleaned package was not removed from the cache")
+	}
+
+	// The manifest on disk matches.
+	cacheFile, err := g.Config.GetCacheFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := LoadCache(cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"api/api.pb.cc", "api/api.pb.go", "api/api.pb.h", "api/api_ext.pb.go"}; !slices.Equal(cache.Packages[apiKey].GeneratedFiles, want) {
+		t.Fatalf("unexpected cached files: %v", cache.Packages[apiKey].GeneratedFiles)
+	}
+}
+
+func TestGeneratedFileOwner(t *testing.T) {
+	protoFiles := []string{"api/a.proto", "api/a_b.proto", "api/my_service.proto"}
+	for file, want := range map[string]string{
+		"api/a.pb.go":       "api/a.proto",
+		"api/a_srpc.pb.go":  "api/a.proto",
+		"api/a_b.pb.ts":     "api/a_b.proto",
+		"MyService.cs":      "api/my_service.proto",
+		"gen/a.validate.go": "",
+		"other/a.pb.go":     "",
+	} {
+		if got := generatedFileOwner(file, protoFiles); got != want {
+			t.Errorf("generatedFileOwner(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
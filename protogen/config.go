@@ -6,6 +6,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
 )
 
 // DefaultCacheFile is the default cache file name.
@@ -20,11 +21,17 @@ type Config struct {
 	// If empty, uses the current working directory.
 	ProjectDir string
 	// Targets is the list of proto file glob patterns to process.
+	// Patterns starting with "!" deselect files matched by earlier patterns.
 	// Default: ["./*.proto"]
 	Targets []string
 	// Exclude is a list of proto file glob patterns to exclude.
-	// Files matching any of these patterns will be skipped.
+	// Files matching any of these patterns will be skipped, unless a later
+	// pattern starting with "!" matches them again.
 	Exclude []string
+	// IncludeUntracked discovers proto files that are not tracked by git.
+	// If false, discovery in a git checkout only returns tracked files.
+	// Default: true
+	IncludeUntracked bool
 	// Force regenerates all files regardless of cache.
 	Force bool
 	// CacheFile is the path to the cache file.
@@ -35,21 +42,53 @@ type Config struct {
 	// GoLiteFeatures is the go-lite features to enable.
 	// Default: "marshal+unmarshal+size+equal+json+clone+text"
 	GoLiteFeatures string
+	// GoImportRemaps maps Go import paths to their replacements in generated
+	// Go files, such as forks of common proto libraries. A remap applies to
+	// the import path and every package below it. Entries override the
+	// built-in protobuf-go-lite remaps of the protobuf-go well-known types.
+	GoImportRemaps map[string]string
 	// ToolsDir is the tools directory containing plugin binaries.
 	// Default: ".tools"
 	ToolsDir string
 	// ExtraArgs contains any additional protoc arguments.
 	ExtraArgs []string
 	// Languages is the opt-in protobuf output language filter.
-	// Empty preserves the Go, TypeScript, C++, and Rust defaults.
+	// Nil defers to package.json; an empty non-nil slice selects the
+	// Go, TypeScript, C++, and Rust defaults.
 	Languages []string
 	// RPCLibraries is the opt-in RPC stub generator filter.
-	// Empty enables the default RPC library set.
+	// Nil defers to package.json; an empty non-nil slice selects the
+	// default RPC library set.
 	RPCLibraries []string
 	// TsImportBoundaries are module-relative path prefixes where generated
 	// TypeScript protobuf imports should switch to @go/... when crossing
 	// between boundaries.
 	TsImportBoundaries []string
+	// Jobs is the number of concurrent protoc invocations.
+	// Values above 1 shard the stale files by package directory.
+	// Zero or negative uses the number of CPUs.
+	// Default: 1
+	Jobs int
+	// WasmCacheDir is the root of the persistent WASM compilation cache.
+	// Compiled modules are stored in a subdirectory keyed by the embedded
+	// protoc and prost versions.
+	// Default: "aptre/wazero" under the user cache directory.
+	WasmCacheDir string
+	// Profiles are named setting overrides for the package directories
+	// matching their globs. The first matching profile wins; directories
+	// matching no profile use the top-level settings.
+	Profiles []*Profile
+	// CustomPlugins are third-party protoc plugins run in the same protoc
+	// pass as the built-in generators.
+	CustomPlugins []*CustomPlugin
+	// Lint configures the proto lint rules.
+	// If nil, all rules are enabled.
+	Lint *LintConfig
+	// DescriptorSets are paths relative to the project directory where
+	// generation writes a FileDescriptorSet of all discovered proto files,
+	// including imports and source info. Paths ending in .json are written in
+	// the protobuf JSON format, all others in the binary format.
+	DescriptorSets []string
 }
 
 type packageJSONConfig struct {
@@ -65,13 +104,23 @@ type packageJSONAptreConfig struct {
 // NewConfig returns a new Config with default values.
 func NewConfig() *Config {
 	return &Config{
-		Targets:        []string{"./*.proto"},
-		CacheFile:      DefaultCacheFile,
-		GoLiteFeatures: DefaultGoLiteFeatures,
-		ToolsDir:       ".tools",
+		Targets:          []string{"./*.proto"},
+		IncludeUntracked: true,
+		CacheFile:        DefaultCacheFile,
+		GoLiteFeatures:   DefaultGoLiteFeatures,
+		ToolsDir:         ".tools",
+		Jobs:             1,
 	}
 }
 
+// GetJobs returns the number of concurrent protoc invocations.
+func (c *Config) GetJobs() int {
+	if c.Jobs <= 0 {
+		return runtime.NumCPU()
+	}
+	return c.Jobs
+}
+
 // GetProjectDir returns the project directory, defaulting to cwd.
 func (c *Config) GetProjectDir() (string, error) {
 	if c.ProjectDir != "" {
@@ -193,7 +242,7 @@ func (c *Config) GetTsImportBoundaries() ([]string, error) {
 // GetLanguages returns configured output languages.
 // Explicit config takes precedence; otherwise reads package.json aptre config.
 func (c *Config) GetLanguages() (Languages, error) {
-	if len(c.Languages) != 0 {
+	if c.Languages != nil {
 		return NewLanguages(c.Languages)
 	}
 
@@ -224,7 +273,7 @@ func (c *Config) GetLanguages() (Languages, error) {
 // GetRPCLibraries returns configured RPC generators.
 // Explicit config takes precedence; otherwise reads package.json aptre config.
 func (c *Config) GetRPCLibraries() (RPCLibraries, error) {
-	if len(c.RPCLibraries) != 0 {
+	if c.RPCLibraries != nil {
 		return NewRPCLibraries(c.RPCLibraries)
 	}
 
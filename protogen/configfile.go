@@ -0,0 +1,259 @@
+package protogen
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ConfigFileNames are the project configuration file names in lookup order.
+var ConfigFileNames = []string{"aptre.yaml", "aptre.yml", "aptre.json"}
+
+// ConfigFileSchema is the JSON schema for aptre.yaml and aptre.json.
+//
+//go:embed aptre.schema.json
+var ConfigFileSchema []byte
+
+// ConfigFile is the project configuration file (aptre.yaml or aptre.json).
+//
+// Every field is optional. Precedence, highest first: command line flags, the
+// configuration file, the "aptre" key in package.json, then the defaults.
+type ConfigFile struct {
+	// Schema is the optional JSON schema reference for editor validation.
+	Schema string `json:"$schema,omitempty" yaml:"$schema,omitempty"`
+	// Targets is the list of proto file glob patterns to process.
+	Targets []string `json:"targets,omitempty" yaml:"targets,omitempty"`
+	// Exclude is a list of proto file glob patterns to exclude.
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+	// IncludeUntracked discovers proto files that are not tracked by git.
+	IncludeUntracked *bool `json:"includeUntracked,omitempty" yaml:"includeUntracked,omitempty"`
+	// Force regenerates all files regardless of cache.
+	Force *bool `json:"force,omitempty" yaml:"force,omitempty"`
+	// CacheFile is the path to the cache file.
+	CacheFile string `json:"cacheFile,omitempty" yaml:"cacheFile,omitempty"`
+	// Verbose enables verbose output.
+	Verbose *bool `json:"verbose,omitempty" yaml:"verbose,omitempty"`
+	// GoLiteFeatures is the go-lite features to enable.
+	GoLiteFeatures string `json:"features,omitempty" yaml:"features,omitempty"`
+	// GoImportRemaps maps Go import paths to their replacements in generated Go files.
+	GoImportRemaps map[string]string `json:"goImportRemaps,omitempty" yaml:"goImportRemaps,omitempty"`
+	// ToolsDir is the tools directory containing plugin binaries.
+	ToolsDir string `json:"toolsDir,omitempty" yaml:"toolsDir,omitempty"`
+	// ExtraArgs contains any additional protoc arguments.
+	ExtraArgs []string `json:"extraArgs,omitempty" yaml:"extraArgs,omitempty"`
+	// Languages is the protobuf output language filter.
+	Languages []string `json:"languages,omitempty" yaml:"languages,omitempty"`
+	// RPCLibraries is the RPC stub generator filter.
+	RPCLibraries []string `json:"rpc,omitempty" yaml:"rpc,omitempty"`
+	// TsImportBoundaries are module-relative TypeScript import boundaries.
+	TsImportBoundaries []string `json:"tsImportBoundaries,omitempty" yaml:"tsImportBoundaries,omitempty"`
+	// Jobs is the number of concurrent protoc invocations.
+	Jobs *int `json:"jobs,omitempty" yaml:"jobs,omitempty"`
+	// WasmCacheDir is the root of the persistent WASM compilation cache.
+	WasmCacheDir string `json:"wasmCacheDir,omitempty" yaml:"wasmCacheDir,omitempty"`
+	// Profiles are named setting overrides for matching package directories.
+	Profiles []*Profile `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+	// Plugins are third-party protoc plugins run alongside the built-in ones.
+	Plugins []*CustomPlugin `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+	// Lint configures the proto lint rules.
+	Lint *LintConfig `json:"lint,omitempty" yaml:"lint,omitempty"`
+	// DescriptorSets are the descriptor set files written during generation.
+	DescriptorSets []string `json:"descriptorSets,omitempty" yaml:"descriptorSets,omitempty"`
+}
+
+// FindConfigFile returns the path of the configuration file in dir.
+// Returns an empty string if there is none.
+func FindConfigFile(dir string) (string, error) {
+	for _, name := range ConfigFileNames {
+		path := filepath.Join(dir, name)
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// LoadConfigFile parses a configuration file. Files ending in .json are parsed
+// as JSON, everything else as YAML. Unknown keys are rejected.
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file ConfigFile
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&file)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&file)
+		if errors.Is(err, io.EOF) {
+			// An empty YAML document is a valid empty configuration.
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// Apply copies the fields set in the file into cfg. Lists and maps present in
+// the file replace the configured ones even if empty, so an explicit
+// "exclude: []" clears the excludes set before.
+func (f *ConfigFile) Apply(cfg *Config) {
+	if f.Targets != nil {
+		cfg.Targets = f.Targets
+	}
+	if f.Exclude != nil {
+		cfg.Exclude = f.Exclude
+	}
+	if f.IncludeUntracked != nil {
+		cfg.IncludeUntracked = *f.IncludeUntracked
+	}
+	if f.Force != nil {
+		cfg.Force = *f.Force
+	}
+	if f.CacheFile != "" {
+		cfg.CacheFile = f.CacheFile
+	}
+	if f.Verbose != nil {
+		cfg.Verbose = *f.Verbose
+	}
+	if f.GoLiteFeatures != "" {
+		cfg.GoLiteFeatures = f.GoLiteFeatures
+	}
+	if f.GoImportRemaps != nil {
+		cfg.GoImportRemaps = f.GoImportRemaps
+	}
+	if f.ToolsDir != "" {
+		cfg.ToolsDir = f.ToolsDir
+	}
+	if f.ExtraArgs != nil {
+		cfg.ExtraArgs = f.ExtraArgs
+	}
+	if f.Languages != nil {
+		cfg.Languages = f.Languages
+	}
+	if f.RPCLibraries != nil {
+		cfg.RPCLibraries = f.RPCLibraries
+	}
+	if f.TsImportBoundaries != nil {
+		cfg.TsImportBoundaries = f.TsImportBoundaries
+	}
+	if f.Jobs != nil {
+		cfg.Jobs = *f.Jobs
+	}
+	if f.WasmCacheDir != "" {
+		cfg.WasmCacheDir = f.WasmCacheDir
+	}
+	if f.Profiles != nil {
+		cfg.Profiles = f.Profiles
+	}
+	if f.Plugins != nil {
+		cfg.CustomPlugins = f.Plugins
+	}
+	if f.Lint != nil {
+		cfg.Lint = f.Lint
+	}
+	if f.DescriptorSets != nil {
+		cfg.DescriptorSets = f.DescriptorSets
+	}
+}
+
+// ApplyConfigFile loads the configuration file from the project directory, or
+// from path if set, and applies it to the config. Returns the path of the file
+// that was applied, or an empty string if there was none.
+func (c *Config) ApplyConfigFile(path string) (string, error) {
+	if path == "" {
+		projectDir, err := c.GetProjectDir()
+		if err != nil {
+			return "", err
+		}
+		path, err = FindConfigFile(projectDir)
+		if err != nil || path == "" {
+			return "", err
+		}
+	}
+
+	file, err := LoadConfigFile(path)
+	if err != nil {
+		return "", err
+	}
+	file.Apply(c)
+	return path, nil
+}
+
+// Resolve returns the fully resolved configuration, including the values that
+// are read lazily from package.json.
+func (c *Config) Resolve() (*ConfigFile, error) {
+	langs, err := c.GetLanguages()
+	if err != nil {
+		return nil, err
+	}
+	rpcs, err := c.GetRPCLibraries()
+	if err != nil {
+		return nil, err
+	}
+	tsImportBoundaries, err := c.GetTsImportBoundaries()
+	if err != nil {
+		return nil, err
+	}
+
+	langNames := make([]string, 0, len(langs))
+	for lang := range langs {
+		langNames = append(langNames, string(lang))
+	}
+	slices.Sort(langNames)
+	rpcNames := make([]string, 0, len(rpcs))
+	for rpc := range rpcs {
+		rpcNames = append(rpcNames, string(rpc))
+	}
+	slices.Sort(rpcNames)
+	if len(rpcNames) == 0 {
+		rpcNames = []string{"none"}
+	}
+
+	wasmCacheDir, err := c.GetWasmCacheRoot()
+	if err != nil {
+		return nil, err
+	}
+	includeUntracked, force, verbose, jobs := c.IncludeUntracked, c.Force, c.Verbose, c.GetJobs()
+	return &ConfigFile{
+		Targets:            c.Targets,
+		Exclude:            c.Exclude,
+		IncludeUntracked:   &includeUntracked,
+		Force:              &force,
+		CacheFile:          c.CacheFile,
+		Verbose:            &verbose,
+		GoLiteFeatures:     c.GoLiteFeatures,
+		GoImportRemaps:     c.GetGoImportRemaps(),
+		ToolsDir:           c.ToolsDir,
+		ExtraArgs:          c.ExtraArgs,
+		Languages:          langNames,
+		RPCLibraries:       rpcNames,
+		TsImportBoundaries: tsImportBoundaries,
+		Jobs:               &jobs,
+		WasmCacheDir:       wasmCacheDir,
+		Profiles:           c.Profiles,
+		Plugins:            c.CustomPlugins,
+		Lint:               c.Lint,
+		DescriptorSets:     c.DescriptorSets,
+	}, nil
+}
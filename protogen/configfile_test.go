@@ -0,0 +1,211 @@
+package protogen
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestApplyConfigFileYAMLOverridesPackageJSON(t *testing.T) {
+	projectDir := t.TempDir()
+	packageJSON := []byte(`{"aptre": {"languages": ["ts"], "rpc": ["none"]}}`)
+	if err := os.WriteFile(filepath.Join(projectDir, "package.json"), packageJSON, 0o644); err != nil {
+		t.Fatalf("write package.json: %v", err)
+	}
+	aptreYAML := []byte("targets:\n  - ./api/*.proto\nexclude: [\"./api/internal/*.proto\"]\nlanguages: [go]\njobs: 4\nforce: true\n")
+	if err := os.WriteFile(filepath.Join(projectDir, "aptre.yaml"), aptreYAML, 0o644); err != nil {
+		t.Fatalf("write aptre.yaml: %v", err)
+	}
+
+	cfg := NewConfig()
+	cfg.ProjectDir = projectDir
+	path, err := cfg.ApplyConfigFile("")
+	if err != nil {
+		t.Fatalf("apply config file: %v", err)
+	}
+	if path != filepath.Join(projectDir, "aptre.yaml") {
+		t.Fatalf("expected aptre.yaml to be applied, got %q", path)
+	}
+	if !slices.Equal(cfg.Targets, []string{"./api/*.proto"}) {
+		t.Fatalf("unexpected targets %v", cfg.Targets)
+	}
+	if !slices.Equal(cfg.Exclude, []string{"./api/internal/*.proto"}) {
+		t.Fatalf("unexpected exclude %v", cfg.Exclude)
+	}
+	if cfg.Jobs != 4 || !cfg.Force {
+		t.Fatalf("expected jobs 4 and force, got jobs %d force %v", cfg.Jobs, cfg.Force)
+	}
+	// Unset fields keep their defaults.
+	if cfg.GoLiteFeatures != DefaultGoLiteFeatures {
+		t.Fatalf("expected default features, got %q", cfg.GoLiteFeatures)
+	}
+
+	// The file's languages win over package.json, package.json still supplies rpc.
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if !slices.Equal(resolved.Languages, []string{"go"}) {
+		t.Fatalf("expected languages [go], got %v", resolved.Languages)
+	}
+	if !slices.Equal(resolved.RPCLibraries, []string{"none"}) {
+		t.Fatalf("expected rpc [none], got %v", resolved.RPCLibraries)
+	}
+}
+
+func TestApplyConfigFileEmptyListsOverridePackageJSON(t *testing.T) {
+	projectDir := t.TempDir()
+	packageJSON := []byte(`{"aptre": {"languages": ["ts"], "rpc": ["none"]}}`)
+	if err := os.WriteFile(filepath.Join(projectDir, "package.json"), packageJSON, 0o644); err != nil {
+		t.Fatalf("write package.json: %v", err)
+	}
+	aptreYAML := []byte("languages: []\nrpc: []\n")
+	if err := os.WriteFile(filepath.Join(projectDir, "aptre.yaml"), aptreYAML, 0o644); err != nil {
+		t.Fatalf("write aptre.yaml: %v", err)
+	}
+
+	cfg := NewConfig()
+	cfg.ProjectDir = projectDir
+	if _, err := cfg.ApplyConfigFile(""); err != nil {
+		t.Fatalf("apply config file: %v", err)
+	}
+
+	// Explicitly empty lists select the defaults instead of package.json.
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if !slices.Equal(resolved.Languages, []string{"cpp", "go", "rust", "ts"}) {
+		t.Fatalf("expected default languages, got %v", resolved.Languages)
+	}
+	if !slices.Equal(resolved.RPCLibraries, []string{"starpc"}) {
+		t.Fatalf("expected rpc [starpc], got %v", resolved.RPCLibraries)
+	}
+}
+
+func TestApplyConfigFileJSON(t *testing.T) {
+	projectDir := t.TempDir()
+	aptreJSON := []byte(`{"$schema": "./aptre.schema.json", "features": "marshal+unmarshal", "rpc": ["starpc", "starpc-python"]}`)
+	if err := os.WriteFile(filepath.Join(projectDir, "aptre.json"), aptreJSON, 0o644); err != nil {
+		t.Fatalf("write aptre.json: %v", err)
+	}
+
+	cfg := NewConfig()
+	cfg.ProjectDir = projectDir
+	if _, err := cfg.ApplyConfigFile(""); err != nil {
+		t.Fatalf("apply config file: %v", err)
+	}
+	if cfg.GoLiteFeatures != "marshal+unmarshal" {
+		t.Fatalf("unexpected features %q", cfg.GoLiteFeatures)
+	}
+	if !slices.Equal(cfg.RPCLibraries, []string{"starpc", "starpc-python"}) {
+		t.Fatalf("unexpected rpc %v", cfg.RPCLibraries)
+	}
+}
+
+func TestApplyConfigFileEmptyLists(t *testing.T) {
+	for name, data := range map[string]string{
+		"aptre.yaml": "targets: []\nexclude: []\n",
+		"aptre.json": `{"targets": [], "exclude": []}`,
+	} {
+		projectDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(projectDir, name), []byte(data), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+
+		cfg := NewConfig()
+		cfg.ProjectDir = projectDir
+		cfg.Exclude = []string{"./internal/*.proto"}
+		if _, err := cfg.ApplyConfigFile(""); err != nil {
+			t.Fatalf("%s: apply config file: %v", name, err)
+		}
+		if cfg.Targets == nil || len(cfg.Targets) != 0 {
+			t.Fatalf("%s: expected empty targets, got %v", name, cfg.Targets)
+		}
+		if cfg.Exclude == nil || len(cfg.Exclude) != 0 {
+			t.Fatalf("%s: expected empty exclude, got %v", name, cfg.Exclude)
+		}
+	}
+}
+
+func TestApplyConfigFileMissing(t *testing.T) {
+	cfg := NewConfig()
+	cfg.ProjectDir = t.TempDir()
+	path, err := cfg.ApplyConfigFile("")
+	if err != nil {
+		t.Fatalf("apply config file: %v", err)
+	}
+	if path != "" {
+		t.Fatalf("expected no config file, got %q", path)
+	}
+	if !slices.Equal(cfg.Targets, []string{"./*.proto"}) {
+		t.Fatalf("expected default targets, got %v", cfg.Targets)
+	}
+}
+
+func TestLoadConfigFileRejectsUnknownFields(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"aptre.yaml": "target: ./*.proto\n",
+		"aptre.json": `{"target": "./*.proto"}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		_, err := LoadConfigFile(path)
+		if err == nil || !strings.Contains(err.Error(), "target") {
+			t.Fatalf("expected unknown field error for %s, got %v", name, err)
+		}
+	}
+}
+
+func TestConfigFileSchemaCoversAllFields(t *testing.T) {
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(ConfigFileSchema, &schema); err != nil {
+		t.Fatalf("parse schema: %v", err)
+	}
+
+	data, err := json.Marshal(&ConfigFile{
+		Schema:             "x",
+		Targets:            []string{"x"},
+		Exclude:            []string{"x"},
+		IncludeUntracked:   new(bool),
+		Force:              new(bool),
+		CacheFile:          "x",
+		Verbose:            new(bool),
+		GoLiteFeatures:     "x",
+		GoImportRemaps:     map[string]string{"x": "x"},
+		ToolsDir:           "x",
+		ExtraArgs:          []string{"x"},
+		Languages:          []string{"x"},
+		RPCLibraries:       []string{"x"},
+		TsImportBoundaries: []string{"x"},
+		Jobs:               new(int),
+		WasmCacheDir:       "x",
+		Profiles:           []*Profile{{Name: "x"}},
+		Plugins:            []*CustomPlugin{{Name: "x"}},
+		Lint:               &LintConfig{},
+		DescriptorSets:     []string{"x"},
+	})
+	if err != nil {
+		t.Fatalf("marshal config file: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("unmarshal config file: %v", err)
+	}
+	for field := range fields {
+		if _, ok := schema.Properties[field]; !ok {
+			t.Fatalf("schema is missing property %q", field)
+		}
+	}
+	if len(fields) != len(schema.Properties) {
+		t.Fatalf("schema has %d properties, config file has %d fields", len(schema.Properties), len(fields))
+	}
+}
@@ -0,0 +1,252 @@
+package protogen
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// builtinPluginNames are the protoc plugin names reserved by aptre.
+var builtinPluginNames = []string{
+	"cpp", "csharp", "python", "pyi",
+	"go-lite", "go-starpc", "es-lite", "es-starpc",
+	"starpc-cpp", "starpc-rust", "starpc-python", "prost",
+}
+
+// CustomPlugin declares a third-party protoc plugin run in the same protoc
+// pass as the built-in generators.
+type CustomPlugin struct {
+	// Name is the protoc plugin name used for the --{name}_out and
+	// --{name}_opt flags (e.g. "validate").
+	Name string `json:"name" yaml:"name"`
+	// Binary is the plugin executable name or path. Paths are resolved
+	// relative to the project directory. Names are looked up in the tools
+	// bin directory, node_modules/.bin and PATH. Values ending in .wasm are
+	// WASI modules run inside protoc's runtime, resolved relative to the
+	// project directory or as a vendored module path.
+	// Default: "protoc-gen-{name}"
+	Binary string `json:"binary,omitempty" yaml:"binary,omitempty"`
+	// Out is the output directory relative to the project directory. It must
+	// be inside the project directory, which is the only directory protoc can
+	// write to. Empty uses the default output directory, so outputs land next to the
+	// proto files like the built-in generators.
+	Out string `json:"out,omitempty" yaml:"out,omitempty"`
+	// Options are passed to the plugin as --{name}_opt=key=value.
+	Options map[string]string `json:"options,omitempty" yaml:"options,omitempty"`
+	// Languages restricts the plugin to runs where at least one of these
+	// output languages is enabled. Empty always runs the plugin.
+	Languages []string `json:"languages,omitempty" yaml:"languages,omitempty"`
+	// Match restricts the plugin to package directories matching these globs.
+	// Empty runs the plugin for every package.
+	Match []string `json:"match,omitempty" yaml:"match,omitempty"`
+	// Outputs are the generated files to track for each proto file, as
+	// project-relative globs. {dir} expands to the proto file's directory
+	// and {name} to its base name without the .proto extension.
+	// Example: "{dir}/{name}.pb.validate.go"
+	Outputs []string `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+}
+
+// GetBinary returns the plugin executable name or path.
+func (p *CustomPlugin) GetBinary() string {
+	if p.Binary != "" {
+		return p.Binary
+	}
+	return "protoc-gen-" + p.Name
+}
+
+// ValidateCustomPlugins checks that every custom plugin has a unique name
+// that does not shadow a built-in generator and an output directory inside
+// the project directory.
+func (c *Config) ValidateCustomPlugins() error {
+	seen := make(map[string]struct{}, len(c.CustomPlugins))
+	for i, p := range c.CustomPlugins {
+		if p == nil || p.Name == "" {
+			return fmt.Errorf("plugin %d: name is required", i)
+		}
+		if strings.ContainsAny(p.Name, "=/\\ \t") {
+			return fmt.Errorf("plugin %q: invalid name", p.Name)
+		}
+		if slices.Contains(builtinPluginNames, p.Name) {
+			return fmt.Errorf("plugin %q: name is reserved for a built-in generator", p.Name)
+		}
+		if _, ok := seen[p.Name]; ok {
+			return fmt.Errorf("plugin %q: duplicate name", p.Name)
+		}
+		seen[p.Name] = struct{}{}
+		if _, err := NewLanguages(p.Languages); err != nil {
+			return fmt.Errorf("plugin %q: %w", p.Name, err)
+		}
+		if err := validateGlobs(p.Match); err != nil {
+			return fmt.Errorf("plugin %q: %w", p.Name, err)
+		}
+		if p.Out != "" && !filepath.IsLocal(p.Out) {
+			return fmt.Errorf("plugin %q: out %q must be a relative path inside the project directory", p.Name, p.Out)
+		}
+	}
+	return nil
+}
+
+// discoverCustomPlugins resolves the configured custom plugins enabled for
+// the given languages.
+func discoverCustomPlugins(cfg *Config, projectDir, toolsBin string, langs Languages) ([]*Plugin, error) {
+	var plugins []*Plugin
+	for _, custom := range cfg.CustomPlugins {
+		if len(custom.Languages) != 0 && !slices.ContainsFunc(custom.Languages, func(lang string) bool {
+			return langs.Has(Language(lang))
+		}) {
+			continue
+		}
+
+		binary := custom.GetBinary()
+		var path string
+		var err error
+		if strings.HasSuffix(binary, wasmPluginExt) {
+			var moduleDir string
+			moduleDir, err = cfg.GetModuleDir()
+			if err == nil {
+				path, err = findWASMPluginPath(projectDir, filepath.Join(moduleDir, "vendor"), binary)
+			}
+		} else {
+			path, err = findCustomPluginPath(projectDir, toolsBin, binary)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("plugin %q: %w", custom.Name, err)
+		}
+
+		var outDir string
+		if custom.Out != "" {
+			outDir = filepath.Join(projectDir, custom.Out)
+		}
+
+		options := make(map[string]string, len(custom.Options))
+		for k, v := range custom.Options {
+			options[k] = v
+		}
+		plugins = append(plugins, &Plugin{
+			Name:       custom.Name,
+			BinaryName: "protoc-gen-" + custom.Name,
+			Path:       path,
+			Type:       PluginTypeCustom,
+			OutFlag:    custom.Name + "_out",
+			Options:    options,
+			OutDir:     outDir,
+			Match:      custom.Match,
+			Outputs:    custom.Outputs,
+		})
+	}
+	return plugins, nil
+}
+
+// findCustomPluginPath resolves a custom plugin executable. Values containing
+// a path separator are resolved against the project directory; bare names are
+// looked up in the tools bin directory, node_modules/.bin and PATH.
+func findCustomPluginPath(projectDir, toolsBin, binary string) (string, error) {
+	if strings.ContainsRune(binary, '/') || strings.ContainsRune(binary, filepath.Separator) {
+		path := binary
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(projectDir, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+
+	toolsPath := filepath.Join(toolsBin, binary)
+	if _, err := os.Stat(toolsPath); err == nil {
+		return toolsPath, nil
+	}
+	if nodePath := discoverNodePlugin(projectDir, binary); nodePath != "" {
+		return nodePath, nil
+	}
+	if path, err := exec.LookPath(binary); err == nil {
+		return path, nil
+	}
+	return "", fmt.Errorf("%s not found in %s, node_modules/.bin or PATH", binary, toolsBin)
+}
+
+// ForDir returns the plugins that apply to a project-relative package
+// directory, removing custom plugins whose globs do not match it.
+func (p *Plugins) ForDir(dir string) *Plugins {
+	custom := make([]*Plugin, 0, len(p.Custom))
+	for _, c := range p.Custom {
+		if len(c.Match) == 0 || matchesAnyGlob(dir, c.Match) {
+			custom = append(custom, c)
+		}
+	}
+	if len(custom) == len(p.Custom) {
+		return p
+	}
+	filtered := *p
+	filtered.Custom = custom
+	return &filtered
+}
+
+// rebaseOutDirs returns the plugins with custom output directories under
+// oldRoot moved to the same relative location under newRoot.
+func (p *Plugins) rebaseOutDirs(oldRoot, newRoot string) *Plugins {
+	if p == nil || len(p.Custom) == 0 {
+		return p
+	}
+	rebased := *p
+	rebased.Custom = make([]*Plugin, len(p.Custom))
+	for i, c := range p.Custom {
+		rebased.Custom[i] = c
+		if c.OutDir == "" {
+			continue
+		}
+		rel, err := filepath.Rel(oldRoot, c.OutDir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		moved := *c
+		moved.OutDir = filepath.Join(newRoot, rel)
+		rebased.Custom[i] = &moved
+	}
+	return &rebased
+}
+
+// customKey identifies the set of enabled custom plugins.
+func (p *Plugins) customKey() string {
+	names := make([]string, len(p.Custom))
+	for i, c := range p.Custom {
+		names[i] = c.Name
+	}
+	return strings.Join(names, ",")
+}
+
+// FindGeneratedFiles returns the outputs of the built-in and custom plugins
+// that exist for a proto file, as sorted project-relative paths.
+func (p *Plugins) FindGeneratedFiles(protoFile, projectDir, vendorDir, modulePath string) ([]string, error) {
+	files, err := FindGeneratedFilesForProto(protoFile, projectDir, vendorDir, modulePath, p.Languages, p.RPCLibraries)
+	if err != nil || len(p.Custom) == 0 {
+		return files, err
+	}
+
+	protoDir := filepath.ToSlash(filepath.Dir(protoFile))
+	baseName := strings.TrimSuffix(filepath.Base(protoFile), ".proto")
+	replacer := strings.NewReplacer("{dir}", protoDir, "{name}", baseName)
+	for _, c := range p.Custom {
+		for _, output := range c.Outputs {
+			pattern := filepath.Clean(filepath.FromSlash(replacer.Replace(output)))
+			matches, err := filepath.Glob(filepath.Join(projectDir, pattern))
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				rel, err := filepath.Rel(projectDir, match)
+				if err != nil {
+					return nil, err
+				}
+				if !slices.Contains(files, rel) {
+					files = append(files, rel)
+				}
+			}
+		}
+	}
+	slices.Sort(files)
+	return files, nil
+}
@@ -0,0 +1,166 @@
+package protogen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// appendProtoBytes appends a length-delimited protobuf field.
+func appendProtoBytes(buf []byte, field int, data []byte) []byte {
+	buf = append(buf, byte(field<<3|2))
+	n := len(data)
+	for n >= 0x80 {
+		buf = append(buf, byte(n)|0x80)
+		n >>= 7
+	}
+	buf = append(buf, byte(n))
+	return append(buf, data...)
+}
+
+// writeStaticPlugin writes a protoc plugin script that replies with a
+// CodeGeneratorResponse containing a single file.
+func writeStaticPlugin(t *testing.T, dir, name, fileName, content string) string {
+	t.Helper()
+	var file []byte
+	file = appendProtoBytes(file, 1, []byte(fileName))
+	file = appendProtoBytes(file, 15, []byte(content))
+	resp := appendProtoBytes(nil, 15, file)
+
+	respPath := filepath.Join(dir, name+".bin")
+	if err := os.WriteFile(respPath, resp, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\ncat > /dev/null\ncat '" + respPath + "'\n"
+	scriptPath := filepath.Join(dir, "protoc-gen-"+name)
+	if err := os.WriteFile(scriptPath, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return scriptPath
+}
+
+func TestDiscoverCustomPlugins(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "go.mod"), []byte("module example.com/project\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	toolsBin := filepath.Join(projectDir, ".tools", "bin")
+	if err := os.MkdirAll(toolsBin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeStaticPlugin(t, toolsBin, "validate", "x", "")
+	if err := os.MkdirAll(filepath.Join(projectDir, "hack"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeStaticPlugin(t, filepath.Join(projectDir, "hack"), "docs", "x", "")
+
+	cfg := NewConfig()
+	cfg.ProjectDir = projectDir
+	cfg.Languages = []string{"cpp"}
+	cfg.CustomPlugins = []*CustomPlugin{
+		{Name: "validate", Options: map[string]string{"lang": "go"}},
+		{Name: "docs", Binary: "./hack/protoc-gen-docs", Out: "docs"},
+		{Name: "tsonly", Languages: []string{"ts"}},
+	}
+	if err := cfg.ValidateCustomPlugins(); err != nil {
+		t.Fatal(err)
+	}
+	plugins, err := DiscoverPlugins(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plugins.Custom) != 2 {
+		t.Fatalf("expected 2 custom plugins, got %d", len(plugins.Custom))
+	}
+	if plugins.Custom[0].Path != filepath.Join(toolsBin, "protoc-gen-validate") {
+		t.Fatalf("unexpected validate path %q", plugins.Custom[0].Path)
+	}
+
+	args := plugins.GetProtocArgs("/out", projectDir)
+	for _, want := range []string{
+		"--validate_out=/out",
+		"--validate_opt=lang=go",
+		"--docs_out=" + filepath.Join(projectDir, "docs"),
+	} {
+		if !slices.Contains(args, want) {
+			t.Fatalf("protoc args %v missing %s", args, want)
+		}
+	}
+
+	handler := NewNativePluginHandler(plugins, false)
+	if got := handler.findPluginPath("protoc-gen-docs", false); got != filepath.Join(projectDir, "hack", "protoc-gen-docs") {
+		t.Fatalf("unexpected docs plugin path %q", got)
+	}
+}
+
+func TestValidateCustomPlugins(t *testing.T) {
+	for name, plugins := range map[string][]*CustomPlugin{
+		"missing name": {{}},
+		"builtin":      {{Name: "go-lite"}},
+		"duplicate":    {{Name: "a"}, {Name: "a"}},
+		"bad language": {{Name: "a", Languages: []string{"cobol"}}},
+		"bad name":     {{Name: "a=b"}},
+		"absolute out": {{Name: "a", Out: "/tmp/out"}},
+		"parent out":   {{Name: "a", Out: "../out"}},
+	} {
+		cfg := NewConfig()
+		cfg.CustomPlugins = plugins
+		if err := cfg.ValidateCustomPlugins(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestGenerateRunsCustomPlugins(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"a/a.proto": "syntax = \"proto3\";\npackage a;\nmessage A { string id = 1; }\n",
+		"b/b.proto": "syntax = \"proto3\";\npackage b;\nmessage B { int32 n = 1; }\n",
+	})
+	pluginPath := writeStaticPlugin(t, t.TempDir(), "notes", "example.com/project/a/a.notes.txt", "notes for a\n")
+	g.Plugins.Custom = []*Plugin{{
+		Name:       "notes",
+		BinaryName: "protoc-gen-notes",
+		Path:       pluginPath,
+		Type:       PluginTypeCustom,
+		OutFlag:    "notes_out",
+		Options:    map[string]string{},
+		Match:      []string{"a"},
+		Outputs:    []string{"{dir}/{name}.notes.txt"},
+	}}
+
+	ctx := context.Background()
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(g.ProjectDir, "a", "a.notes.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "notes for a\n" {
+		t.Fatalf("unexpected plugin output %q", data)
+	}
+
+	aInfo := g.Cache.Packages[GetPackageKey(g.ModulePath, "a/a.proto")]
+	if !slices.Contains(aInfo.GeneratedFiles, filepath.Join("a", "a.notes.txt")) {
+		t.Fatalf("custom output not tracked: %v", aInfo.GeneratedFiles)
+	}
+	bInfo := g.Cache.Packages[GetPackageKey(g.ModulePath, "b/b.proto")]
+	if aInfo.ProtocFlagsHash == bInfo.ProtocFlagsHash {
+		t.Fatal("custom plugin did not change the flags hash of the matching package")
+	}
+
+	// Dropping the plugin removes its tracked output as a stale file.
+	g.Plugins.Custom = nil
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("regenerate: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(g.ProjectDir, "a", "a.notes.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected stale custom output to be removed, got %v", err)
+	}
+	if strings.Contains(strings.Join(g.Cache.Packages[GetPackageKey(g.ModulePath, "a/a.proto")].GeneratedFiles, ","), "notes") {
+		t.Fatal("stale custom output still tracked")
+	}
+}
@@ -0,0 +1,673 @@
+package protogen
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Field numbers of descriptor.proto elements that appear in source code info
+// paths. See google/protobuf/descriptor.proto.
+const (
+	fileDescriptorMessageField = 4
+	fileDescriptorEnumField    = 5
+	fileDescriptorServiceField = 6
+	messageFieldField          = 2
+	messageNestedField         = 3
+	messageEnumField           = 4
+	messageReservedRangeField  = 9
+	messageReservedNameField   = 10
+	enumValueField             = 2
+	enumReservedRangeField     = 4
+	enumReservedNameField      = 5
+	serviceMethodField         = 2
+)
+
+// FieldDescriptorProto.Label values.
+const (
+	fieldLabelRequired = 2
+	fieldLabelRepeated = 3
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// maxVarintLen is the maximum length of an encoded 64-bit varint.
+const maxVarintLen = 10
+
+// FileDescriptor is the subset of a google.protobuf.FileDescriptorProto used
+// to compare proto schemas.
+type FileDescriptor struct {
+	// Name is the file name relative to the protoc include path.
+	Name string
+	// Package is the proto package.
+	Package string
+	// Dependencies are the imported file names.
+	Dependencies []string
+	// Syntax is "proto2", "proto3" or "editions". Empty means proto2.
+	Syntax string
+	// Messages are the top-level message types.
+	Messages []*MessageDescriptor
+	// Enums are the top-level enum types.
+	Enums []*EnumDescriptor
+	// Services are the RPC services.
+	Services []*ServiceDescriptor
+	// GoPackage is the go_package file option.
+	GoPackage string
+
+	// locations maps source code info paths to source locations.
+	locations map[string]*sourceLocation
+}
+
+// sourceLocation is a decoded google.protobuf.SourceCodeInfo.Location.
+// Lines and columns are zero-based, with tabs advancing the column to the
+// next multiple of 8 as in the protoc tokenizer.
+type sourceLocation struct {
+	startLine, startCol int
+	endLine, endCol     int
+	leadingComments     string
+}
+
+// MessageDescriptor is a decoded google.protobuf.DescriptorProto.
+type MessageDescriptor struct {
+	// Name is the short message name.
+	Name string
+	// Fields are the message fields.
+	Fields []*FieldDescriptor
+	// Nested are the nested message types.
+	Nested []*MessageDescriptor
+	// Enums are the nested enum types.
+	Enums []*EnumDescriptor
+	// ReservedRanges are the reserved field numbers. End is exclusive.
+	ReservedRanges []ReservedRange
+	// ReservedNames are the reserved field names.
+	ReservedNames []string
+	// MapEntry is set for the synthesized entry types of map fields.
+	MapEntry bool
+
+	path []int32
+}
+
+// FieldDescriptor is a decoded google.protobuf.FieldDescriptorProto.
+type FieldDescriptor struct {
+	// Name is the field name.
+	Name string
+	// Number is the field number.
+	Number int32
+	// Label is the FieldDescriptorProto.Label value.
+	Label int32
+	// Type is the FieldDescriptorProto.Type value.
+	Type int32
+	// TypeName is the fully qualified message or enum type name.
+	TypeName string
+	// OneofIndex is the index of the containing oneof, or -1.
+	OneofIndex int32
+	// Proto3Optional is set for proto3 fields with explicit presence.
+	Proto3Optional bool
+
+	path []int32
+}
+
+// EnumDescriptor is a decoded google.protobuf.EnumDescriptorProto.
+type EnumDescriptor struct {
+	// Name is the short enum name.
+	Name string
+	// Values are the enum values.
+	Values []*EnumValueDescriptor
+	// ReservedRanges are the reserved value numbers. End is exclusive.
+	ReservedRanges []ReservedRange
+	// ReservedNames are the reserved value names.
+	ReservedNames []string
+
+	path []int32
+}
+
+// EnumValueDescriptor is a decoded google.protobuf.EnumValueDescriptorProto.
+type EnumValueDescriptor struct {
+	// Name is the value name.
+	Name string
+	// Number is the value number.
+	Number int32
+
+	path []int32
+}
+
+// ServiceDescriptor is a decoded google.protobuf.ServiceDescriptorProto.
+type ServiceDescriptor struct {
+	// Name is the short service name.
+	Name string
+	// Methods are the RPC methods.
+	Methods []*MethodDescriptor
+
+	path []int32
+}
+
+// MethodDescriptor is a decoded google.protobuf.MethodDescriptorProto.
+type MethodDescriptor struct {
+	// Name is the method name.
+	Name string
+	// InputType is the fully qualified request type name.
+	InputType string
+	// OutputType is the fully qualified response type name.
+	OutputType string
+	// ClientStreaming is set for client streaming methods.
+	ClientStreaming bool
+	// ServerStreaming is set for server streaming methods.
+	ServerStreaming bool
+
+	path []int32
+}
+
+// ReservedRange is a range of reserved field or enum value numbers.
+type ReservedRange struct {
+	// Start is the first reserved number.
+	Start int32
+	// End is one past the last reserved number.
+	End int32
+}
+
+// Contains returns true if the number is inside the range.
+func (r ReservedRange) Contains(n int32) bool {
+	return n >= r.Start && n < r.End
+}
+
+// Line returns the 1-based source line of the element at the descriptor path,
+// or 0 if the file was compiled without source info.
+func (f *FileDescriptor) Line(path []int32) int {
+	if loc := f.locations[descriptorPathKey(path)]; loc != nil {
+		return loc.startLine + 1
+	}
+	return 0
+}
+
+// LeadingComments returns the comments attached before the element at the
+// descriptor path.
+func (f *FileDescriptor) LeadingComments(path []int32) string {
+	if loc := f.locations[descriptorPathKey(path)]; loc != nil {
+		return loc.leadingComments
+	}
+	return ""
+}
+
+// location returns the source location of the element at the descriptor
+// path, or nil if there is none.
+func (f *FileDescriptor) location(path []int32) *sourceLocation {
+	return f.locations[descriptorPathKey(path)]
+}
+
+// ParseFileDescriptorSet decodes a serialized google.protobuf.FileDescriptorSet.
+func ParseFileDescriptorSet(data []byte) ([]*FileDescriptor, error) {
+	var files []*FileDescriptor
+	err := walkProtoFields(data, func(num int, wt int, v uint64, b []byte) error {
+		if num != 1 || wt != wireBytes {
+			return nil
+		}
+		f, err := parseFileDescriptor(b)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %w", err)
+	}
+	return files, nil
+}
+
+// canonicalFileDescriptors decodes a serialized FileDescriptorSet into a
+// canonical encoding of each file keyed by file name. Two files have the same
+// canonical encoding if they declare the same schema, regardless of source
+// info and the order of their imports.
+func canonicalFileDescriptors(data []byte) (map[string]string, error) {
+	files := make(map[string]string)
+	err := walkProtoFields(data, func(num int, wt int, v uint64, b []byte) error {
+		if num != 1 || wt != wireBytes {
+			return nil
+		}
+		var name string
+		var sb strings.Builder
+		var deps []string
+		flags := make(map[int]string)
+		err := walkProtoFields(b, func(num int, wt int, v uint64, b []byte) error {
+			switch num {
+			case 1:
+				name = string(b)
+			case 3:
+				deps = append(deps, string(b))
+				return nil
+			case 9:
+				// source_code_info
+				return nil
+			case 10, 11:
+				flag := "public"
+				if num == 11 {
+					flag = "weak"
+				}
+				if wt == wireVarint {
+					flags[int(v)] += flag
+					return nil
+				}
+				for len(b) != 0 {
+					idx, n := decodeVarint(b)
+					if n == 0 {
+						return errors.New("truncated varint")
+					}
+					flags[int(idx)] += flag
+					b = b[n:]
+				}
+				return nil
+			}
+			fmt.Fprintf(&sb, "%d:%d:%d:%q\n", num, wt, v, b)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for i := range deps {
+			deps[i] += " " + flags[i]
+		}
+		slices.Sort(deps)
+		for _, dep := range deps {
+			fmt.Fprintf(&sb, "dependency:%q\n", dep)
+		}
+		files[name] = sb.String()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %w", err)
+	}
+	return files, nil
+}
+
+// parseFileDescriptor decodes a google.protobuf.FileDescriptorProto.
+func parseFileDescriptor(data []byte) (*FileDescriptor, error) {
+	f := &FileDescriptor{}
+	err := walkProtoFields(data, func(num int, wt int, v uint64, b []byte) error {
+		if wt != wireBytes {
+			return nil
+		}
+		var err error
+		switch num {
+		case 1:
+			f.Name = string(b)
+		case 2:
+			f.Package = string(b)
+		case 3:
+			f.Dependencies = append(f.Dependencies, string(b))
+		case fileDescriptorMessageField:
+			var m *MessageDescriptor
+			m, err = parseMessageDescriptor(b, []int32{fileDescriptorMessageField, int32(len(f.Messages))})
+			if err == nil {
+				f.Messages = append(f.Messages, m)
+			}
+		case fileDescriptorEnumField:
+			var e *EnumDescriptor
+			e, err = parseEnumDescriptor(b, []int32{fileDescriptorEnumField, int32(len(f.Enums))})
+			if err == nil {
+				f.Enums = append(f.Enums, e)
+			}
+		case fileDescriptorServiceField:
+			var s *ServiceDescriptor
+			s, err = parseServiceDescriptor(b, []int32{fileDescriptorServiceField, int32(len(f.Services))})
+			if err == nil {
+				f.Services = append(f.Services, s)
+			}
+		case 8:
+			// FileOptions.go_package
+			err = walkProtoFields(b, func(num int, wt int, v uint64, b []byte) error {
+				if num == 11 && wt == wireBytes {
+					f.GoPackage = string(b)
+				}
+				return nil
+			})
+		case 9:
+			f.locations, err = parseSourceCodeInfo(b)
+		case 12:
+			f.Syntax = string(b)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// parseMessageDescriptor decodes a google.protobuf.DescriptorProto.
+func parseMessageDescriptor(data []byte, path []int32) (*MessageDescriptor, error) {
+	m := &MessageDescriptor{path: path}
+	err := walkProtoFields(data, func(num int, wt int, v uint64, b []byte) error {
+		if wt != wireBytes {
+			return nil
+		}
+		var err error
+		switch num {
+		case 1:
+			m.Name = string(b)
+		case messageFieldField:
+			var fd *FieldDescriptor
+			fd, err = parseFieldDescriptor(b, childPath(path, messageFieldField, len(m.Fields)))
+			if err == nil {
+				m.Fields = append(m.Fields, fd)
+			}
+		case messageNestedField:
+			var nested *MessageDescriptor
+			nested, err = parseMessageDescriptor(b, childPath(path, messageNestedField, len(m.Nested)))
+			if err == nil {
+				m.Nested = append(m.Nested, nested)
+			}
+		case messageEnumField:
+			var e *EnumDescriptor
+			e, err = parseEnumDescriptor(b, childPath(path, messageEnumField, len(m.Enums)))
+			if err == nil {
+				m.Enums = append(m.Enums, e)
+			}
+		case 7:
+			// MessageOptions.map_entry
+			err = walkProtoFields(b, func(num int, wt int, v uint64, _ []byte) error {
+				if num == 7 && wt == wireVarint {
+					m.MapEntry = v != 0
+				}
+				return nil
+			})
+		case messageReservedRangeField:
+			var r ReservedRange
+			r, err = parseReservedRange(b)
+			if err == nil {
+				m.ReservedRanges = append(m.ReservedRanges, r)
+			}
+		case messageReservedNameField:
+			m.ReservedNames = append(m.ReservedNames, string(b))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseFieldDescriptor decodes a google.protobuf.FieldDescriptorProto.
+func parseFieldDescriptor(data []byte, path []int32) (*FieldDescriptor, error) {
+	fd := &FieldDescriptor{OneofIndex: -1, path: path}
+	err := walkProtoFields(data, func(num int, wt int, v uint64, b []byte) error {
+		switch {
+		case num == 1 && wt == wireBytes:
+			fd.Name = string(b)
+		case num == 3 && wt == wireVarint:
+			fd.Number = int32(v)
+		case num == 4 && wt == wireVarint:
+			fd.Label = int32(v)
+		case num == 5 && wt == wireVarint:
+			fd.Type = int32(v)
+		case num == 6 && wt == wireBytes:
+			fd.TypeName = string(b)
+		case num == 9 && wt == wireVarint:
+			fd.OneofIndex = int32(v)
+		case num == 17 && wt == wireVarint:
+			fd.Proto3Optional = v != 0
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fd, nil
+}
+
+// parseEnumDescriptor decodes a google.protobuf.EnumDescriptorProto.
+func parseEnumDescriptor(data []byte, path []int32) (*EnumDescriptor, error) {
+	e := &EnumDescriptor{path: path}
+	err := walkProtoFields(data, func(num int, wt int, v uint64, b []byte) error {
+		if wt != wireBytes {
+			return nil
+		}
+		var err error
+		switch num {
+		case 1:
+			e.Name = string(b)
+		case enumValueField:
+			value := &EnumValueDescriptor{path: childPath(path, enumValueField, len(e.Values))}
+			err = walkProtoFields(b, func(num int, wt int, v uint64, b []byte) error {
+				switch {
+				case num == 1 && wt == wireBytes:
+					value.Name = string(b)
+				case num == 2 && wt == wireVarint:
+					value.Number = int32(v)
+				}
+				return nil
+			})
+			if err == nil {
+				e.Values = append(e.Values, value)
+			}
+		case enumReservedRangeField:
+			var r ReservedRange
+			r, err = parseReservedRange(b)
+			if err == nil {
+				// Enum reserved ranges are inclusive.
+				r.End++
+				e.ReservedRanges = append(e.ReservedRanges, r)
+			}
+		case enumReservedNameField:
+			e.ReservedNames = append(e.ReservedNames, string(b))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// parseServiceDescriptor decodes a google.protobuf.ServiceDescriptorProto.
+func parseServiceDescriptor(data []byte, path []int32) (*ServiceDescriptor, error) {
+	s := &ServiceDescriptor{path: path}
+	err := walkProtoFields(data, func(num int, wt int, v uint64, b []byte) error {
+		if wt != wireBytes {
+			return nil
+		}
+		switch num {
+		case 1:
+			s.Name = string(b)
+		case serviceMethodField:
+			method := &MethodDescriptor{path: childPath(path, serviceMethodField, len(s.Methods))}
+			err := walkProtoFields(b, func(num int, wt int, v uint64, b []byte) error {
+				switch {
+				case num == 1 && wt == wireBytes:
+					method.Name = string(b)
+				case num == 2 && wt == wireBytes:
+					method.InputType = string(b)
+				case num == 3 && wt == wireBytes:
+					method.OutputType = string(b)
+				case num == 5 && wt == wireVarint:
+					method.ClientStreaming = v != 0
+				case num == 6 && wt == wireVarint:
+					method.ServerStreaming = v != 0
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			s.Methods = append(s.Methods, method)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseReservedRange decodes a reserved range message with start and end
+// fields.
+func parseReservedRange(data []byte) (ReservedRange, error) {
+	var r ReservedRange
+	err := walkProtoFields(data, func(num int, wt int, v uint64, _ []byte) error {
+		if wt != wireVarint {
+			return nil
+		}
+		switch num {
+		case 1:
+			r.Start = int32(v)
+		case 2:
+			r.End = int32(v)
+		}
+		return nil
+	})
+	return r, err
+}
+
+// parseSourceCodeInfo decodes the locations of a google.protobuf.SourceCodeInfo
+// keyed by descriptor path. The first location of each path is kept.
+func parseSourceCodeInfo(data []byte) (map[string]*sourceLocation, error) {
+	locations := make(map[string]*sourceLocation)
+	err := walkProtoFields(data, func(num int, wt int, v uint64, b []byte) error {
+		if num != 1 || wt != wireBytes {
+			return nil
+		}
+		var path, span []int32
+		var leading string
+		err := walkProtoFields(b, func(num int, wt int, v uint64, b []byte) error {
+			var dst *[]int32
+			switch num {
+			case 1:
+				dst = &path
+			case 2:
+				dst = &span
+			case 3:
+				leading = string(b)
+				return nil
+			default:
+				return nil
+			}
+			if wt == wireVarint {
+				*dst = append(*dst, int32(v))
+				return nil
+			}
+			if wt != wireBytes {
+				return nil
+			}
+			// Packed repeated int32.
+			for len(b) != 0 {
+				n, l := decodeVarint(b)
+				if l == 0 {
+					return errors.New("truncated packed varint")
+				}
+				*dst = append(*dst, int32(n))
+				b = b[l:]
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		key := descriptorPathKey(path)
+		if _, ok := locations[key]; ok || len(span) < 3 {
+			return nil
+		}
+		// Spans are [startLine, startCol, endLine, endCol], with endLine
+		// omitted when it equals startLine.
+		loc := &sourceLocation{
+			startLine:       int(span[0]),
+			startCol:        int(span[1]),
+			endLine:         int(span[0]),
+			endCol:          int(span[2]),
+			leadingComments: leading,
+		}
+		if len(span) >= 4 {
+			loc.endLine, loc.endCol = int(span[2]), int(span[3])
+		}
+		locations[key] = loc
+		return nil
+	})
+	return locations, err
+}
+
+// childPath returns the source code info path of the index-th element of a
+// repeated field in the descriptor at path.
+func childPath(path []int32, field int32, index int) []int32 {
+	child := make([]int32, len(path), len(path)+2)
+	copy(child, path)
+	return append(child, field, int32(index))
+}
+
+// descriptorPathKey formats a source code info path as a map key.
+func descriptorPathKey(path []int32) string {
+	parts := make([]string, len(path))
+	for i, p := range path {
+		parts[i] = strconv.Itoa(int(p))
+	}
+	return strings.Join(parts, ".")
+}
+
+// walkProtoFields calls fn for each field of a serialized protobuf message.
+// Varint and fixed-width fields pass their value in v, length-delimited fields
+// their payload in b.
+func walkProtoFields(data []byte, fn func(num int, wt int, v uint64, b []byte) error) error {
+	for len(data) != 0 {
+		tag, n := decodeVarint(data)
+		if n == 0 {
+			return errors.New("truncated tag")
+		}
+		data = data[n:]
+		num, wt := int(tag>>3), int(tag&7)
+		var v uint64
+		var b []byte
+		switch wt {
+		case wireVarint:
+			v, n = decodeVarint(data)
+			if n == 0 {
+				return errors.New("truncated varint")
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errors.New("truncated fixed64")
+			}
+			v = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireBytes:
+			l, n := decodeVarint(data)
+			if n == 0 || uint64(len(data)-n) < l {
+				return errors.New("truncated length-delimited field")
+			}
+			b = data[n : n+int(l)]
+			data = data[n+int(l):]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errors.New("truncated fixed32")
+			}
+			v = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d", wt)
+		}
+		if err := fn(num, wt, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeVarint decodes a base 128 varint, returning the value and the number
+// of bytes read. Returns a length of 0 if the varint is truncated or too long.
+func decodeVarint(data []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(data) && i < maxVarintLen; i++ {
+		v |= uint64(data[i]&0x7f) << (7 * i)
+		if data[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
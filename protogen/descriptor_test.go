@@ -0,0 +1,95 @@
+package protogen
+
+import "testing"
+
+// appendProtoVarint appends a varint protobuf field.
+func appendProtoVarint(buf []byte, field int, v uint64) []byte {
+	buf = append(buf, byte(field<<3))
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
+
+func TestParseFileDescriptorSet(t *testing.T) {
+	var field []byte
+	field = appendProtoBytes(field, 1, []byte("ids"))
+	field = appendProtoVarint(field, 3, 2)
+	field = appendProtoVarint(field, 4, fieldLabelRepeated)
+	field = appendProtoVarint(field, 5, 9)
+
+	var reserved []byte
+	reserved = appendProtoVarint(reserved, 1, 5)
+	reserved = appendProtoVarint(reserved, 2, 10)
+
+	var msg []byte
+	msg = appendProtoBytes(msg, 1, []byte("Foo"))
+	msg = appendProtoBytes(msg, messageFieldField, field)
+	msg = appendProtoBytes(msg, messageReservedRangeField, reserved)
+	msg = appendProtoBytes(msg, messageReservedNameField, []byte("old"))
+
+	var method []byte
+	method = appendProtoBytes(method, 1, []byte("Get"))
+	method = appendProtoBytes(method, 2, []byte(".foo.Foo"))
+	method = appendProtoBytes(method, 3, []byte(".foo.Foo"))
+	method = appendProtoVarint(method, 6, 1)
+	svc := appendProtoBytes(appendProtoBytes(nil, 1, []byte("Svc")), serviceMethodField, method)
+
+	// Location of the field at path [4, 0, 2, 0] spanning line 7 (0-based 6).
+	var loc []byte
+	loc = appendProtoBytes(loc, 1, []byte{fileDescriptorMessageField, 0, messageFieldField, 0})
+	loc = appendProtoBytes(loc, 2, []byte{6, 2, 20})
+	sourceInfo := appendProtoBytes(nil, 1, loc)
+
+	var file []byte
+	file = appendProtoBytes(file, 1, []byte("foo/foo.proto"))
+	file = appendProtoBytes(file, 2, []byte("foo"))
+	file = appendProtoBytes(file, fileDescriptorMessageField, msg)
+	file = appendProtoBytes(file, fileDescriptorServiceField, svc)
+	file = appendProtoBytes(file, 9, sourceInfo)
+	file = appendProtoBytes(file, 12, []byte("proto3"))
+	set := appendProtoBytes(nil, 1, file)
+
+	files, err := ParseFileDescriptorSet(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(files))
+	}
+	f := files[0]
+	if f.Name != "foo/foo.proto" || f.Package != "foo" || f.Syntax != "proto3" {
+		t.Fatalf("unexpected file %+v", f)
+	}
+	if len(f.Messages) != 1 || len(f.Messages[0].Fields) != 1 {
+		t.Fatalf("unexpected messages %+v", f.Messages)
+	}
+	m := f.Messages[0]
+	fd := m.Fields[0]
+	if fd.Name != "ids" || fd.Number != 2 || fd.Label != fieldLabelRepeated || fd.Type != 9 || fd.OneofIndex != -1 {
+		t.Fatalf("unexpected field %+v", fd)
+	}
+	if len(m.ReservedRanges) != 1 || !m.ReservedRanges[0].Contains(9) || m.ReservedRanges[0].Contains(10) {
+		t.Fatalf("unexpected reserved ranges %+v", m.ReservedRanges)
+	}
+	if len(m.ReservedNames) != 1 || m.ReservedNames[0] != "old" {
+		t.Fatalf("unexpected reserved names %v", m.ReservedNames)
+	}
+	if len(f.Services) != 1 || len(f.Services[0].Methods) != 1 {
+		t.Fatalf("unexpected services %+v", f.Services)
+	}
+	if method := f.Services[0].Methods[0]; method.Name != "Get" || method.InputType != ".foo.Foo" || !method.ServerStreaming || method.ClientStreaming {
+		t.Fatalf("unexpected method %+v", method)
+	}
+	if line := f.Line(fd.path); line != 7 {
+		t.Fatalf("expected field on line 7, got %d", line)
+	}
+	if line := f.lineOf(f.Services[0].path); line != 1 {
+		t.Fatalf("expected fallback line 1, got %d", line)
+	}
+
+	if _, err := ParseFileDescriptorSet([]byte{0x0a, 0x05, 0x01}); err == nil {
+		t.Fatal("expected error for truncated descriptor set")
+	}
+}
@@ -0,0 +1,568 @@
+package protogen
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FieldDescriptorProto.Type values of the fields in descriptor.proto.
+const (
+	fieldTypeDouble  = 1
+	fieldTypeInt64   = 3
+	fieldTypeUint64  = 4
+	fieldTypeInt32   = 5
+	fieldTypeBool    = 8
+	fieldTypeString  = 9
+	fieldTypeMessage = 11
+	fieldTypeBytes   = 12
+	fieldTypeEnum    = 14
+)
+
+// descriptorSchemaField is a field of a message in descriptor.proto.
+type descriptorSchemaField struct {
+	// number is the field number.
+	number int
+	// jsonName is the lowerCamelCase JSON name.
+	jsonName string
+	// typ is the FieldDescriptorProto.Type value.
+	typ int32
+	// typeName is the message or enum type name relative to google.protobuf.
+	typeName string
+	// repeated is set for repeated fields.
+	repeated bool
+}
+
+// EncodeDescriptorSetJSON converts a serialized google.protobuf.FileDescriptorSet
+// to the protobuf JSON format. Extensions, such as custom option values, are
+// not part of the descriptor.proto schema and are left out.
+func EncodeDescriptorSetJSON(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeDescriptorJSON(&buf, data, "FileDescriptorSet"); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %w", err)
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// writeDescriptorJSON writes a serialized descriptor.proto message as a JSON
+// object with the fields in declaration order.
+func writeDescriptorJSON(buf *bytes.Buffer, data []byte, message string) error {
+	fields := descriptorSchema[message]
+	values := make([][]string, len(fields))
+	err := walkProtoFields(data, func(num int, wt int, v uint64, b []byte) error {
+		i := slices.IndexFunc(fields, func(f descriptorSchemaField) bool { return f.number == num })
+		if i < 0 {
+			return nil
+		}
+		f := fields[i]
+		if f.repeated && wt == wireBytes && descriptorWireType(f.typ) == wireVarint {
+			// Packed repeated varints.
+			for len(b) != 0 {
+				n, l := decodeVarint(b)
+				if l == 0 {
+					return errors.New("truncated packed varint")
+				}
+				values[i] = append(values[i], descriptorJSONValue(f, n))
+				b = b[l:]
+			}
+			return nil
+		}
+		if wt != descriptorWireType(f.typ) {
+			return nil
+		}
+		var value string
+		if f.typ == fieldTypeMessage {
+			var nested bytes.Buffer
+			if err := writeDescriptorJSON(&nested, b, f.typeName); err != nil {
+				return err
+			}
+			value = nested.String()
+		} else {
+			value = descriptorJSONScalar(f, v, b)
+		}
+		if f.repeated {
+			values[i] = append(values[i], value)
+		} else {
+			values[i] = []string{value}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	buf.WriteByte('{')
+	first := true
+	for i, f := range fields {
+		if len(values[i]) == 0 {
+			continue
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		buf.WriteString(strconv.Quote(f.jsonName) + ":")
+		if f.repeated {
+			buf.WriteString("[" + strings.Join(values[i], ",") + "]")
+		} else {
+			buf.WriteString(values[i][0])
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// descriptorWireType returns the wire type of a field type in descriptor.proto.
+func descriptorWireType(typ int32) int {
+	switch typ {
+	case fieldTypeMessage, fieldTypeString, fieldTypeBytes:
+		return wireBytes
+	case fieldTypeDouble:
+		return wireFixed64
+	default:
+		return wireVarint
+	}
+}
+
+// descriptorJSONScalar encodes a scalar field value as JSON.
+func descriptorJSONScalar(f descriptorSchemaField, v uint64, b []byte) string {
+	switch f.typ {
+	case fieldTypeString:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(string(b))
+		return strings.TrimSuffix(buf.String(), "\n")
+	case fieldTypeBytes:
+		return strconv.Quote(base64.StdEncoding.EncodeToString(b))
+	}
+	return descriptorJSONValue(f, v)
+}
+
+// descriptorJSONValue encodes a numeric, bool or enum field value as JSON.
+// 64-bit integers are strings and enums are their value names, as in the
+// protobuf JSON mapping.
+func descriptorJSONValue(f descriptorSchemaField, v uint64) string {
+	switch f.typ {
+	case fieldTypeBool:
+		return strconv.FormatBool(v != 0)
+	case fieldTypeInt64:
+		return strconv.Quote(strconv.FormatInt(int64(v), 10))
+	case fieldTypeUint64:
+		return strconv.Quote(strconv.FormatUint(v, 10))
+	case fieldTypeDouble:
+		d := math.Float64frombits(v)
+		switch {
+		case math.IsNaN(d):
+			return `"NaN"`
+		case math.IsInf(d, 1):
+			return `"Infinity"`
+		case math.IsInf(d, -1):
+			return `"-Infinity"`
+		}
+		return strconv.FormatFloat(d, 'g', -1, 64)
+	case fieldTypeEnum:
+		if name, ok := descriptorSchemaEnums[f.typeName][int32(v)]; ok {
+			return strconv.Quote(name)
+		}
+	}
+	return strconv.FormatInt(int64(int32(v)), 10)
+}
+
+// descriptorSchema lists the fields of the messages in
+// google/protobuf/descriptor.proto in declaration order, keyed by the message
+// name relative to google.protobuf.
+var descriptorSchema = map[string][]descriptorSchemaField{
+	"FileDescriptorSet": {
+		{1, "file", fieldTypeMessage, "FileDescriptorProto", true},
+	},
+	"FileDescriptorProto": {
+		{1, "name", fieldTypeString, "", false},
+		{2, "package", fieldTypeString, "", false},
+		{3, "dependency", fieldTypeString, "", true},
+		{10, "publicDependency", fieldTypeInt32, "", true},
+		{11, "weakDependency", fieldTypeInt32, "", true},
+		{15, "optionDependency", fieldTypeString, "", true},
+		{4, "messageType", fieldTypeMessage, "DescriptorProto", true},
+		{5, "enumType", fieldTypeMessage, "EnumDescriptorProto", true},
+		{6, "service", fieldTypeMessage, "ServiceDescriptorProto", true},
+		{7, "extension", fieldTypeMessage, "FieldDescriptorProto", true},
+		{8, "options", fieldTypeMessage, "FileOptions", false},
+		{9, "sourceCodeInfo", fieldTypeMessage, "SourceCodeInfo", false},
+		{12, "syntax", fieldTypeString, "", false},
+		{14, "edition", fieldTypeEnum, "Edition", false},
+	},
+	"DescriptorProto": {
+		{1, "name", fieldTypeString, "", false},
+		{2, "field", fieldTypeMessage, "FieldDescriptorProto", true},
+		{6, "extension", fieldTypeMessage, "FieldDescriptorProto", true},
+		{3, "nestedType", fieldTypeMessage, "DescriptorProto", true},
+		{4, "enumType", fieldTypeMessage, "EnumDescriptorProto", true},
+		{5, "extensionRange", fieldTypeMessage, "DescriptorProto.ExtensionRange", true},
+		{8, "oneofDecl", fieldTypeMessage, "OneofDescriptorProto", true},
+		{7, "options", fieldTypeMessage, "MessageOptions", false},
+		{9, "reservedRange", fieldTypeMessage, "DescriptorProto.ReservedRange", true},
+		{10, "reservedName", fieldTypeString, "", true},
+		{11, "visibility", fieldTypeEnum, "SymbolVisibility", false},
+	},
+	"DescriptorProto.ExtensionRange": {
+		{1, "start", fieldTypeInt32, "", false},
+		{2, "end", fieldTypeInt32, "", false},
+		{3, "options", fieldTypeMessage, "ExtensionRangeOptions", false},
+	},
+	"DescriptorProto.ReservedRange": {
+		{1, "start", fieldTypeInt32, "", false},
+		{2, "end", fieldTypeInt32, "", false},
+	},
+	"ExtensionRangeOptions": {
+		{999, "uninterpretedOption", fieldTypeMessage, "UninterpretedOption", true},
+		{2, "declaration", fieldTypeMessage, "ExtensionRangeOptions.Declaration", true},
+		{50, "features", fieldTypeMessage, "FeatureSet", false},
+		{3, "verification", fieldTypeEnum, "ExtensionRangeOptions.VerificationState", false},
+	},
+	"ExtensionRangeOptions.Declaration": {
+		{1, "number", fieldTypeInt32, "", false},
+		{2, "fullName", fieldTypeString, "", false},
+		{3, "type", fieldTypeString, "", false},
+		{5, "reserved", fieldTypeBool, "", false},
+		{6, "repeated", fieldTypeBool, "", false},
+	},
+	"FieldDescriptorProto": {
+		{1, "name", fieldTypeString, "", false},
+		{3, "number", fieldTypeInt32, "", false},
+		{4, "label", fieldTypeEnum, "FieldDescriptorProto.Label", false},
+		{5, "type", fieldTypeEnum, "FieldDescriptorProto.Type", false},
+		{6, "typeName", fieldTypeString, "", false},
+		{2, "extendee", fieldTypeString, "", false},
+		{7, "defaultValue", fieldTypeString, "", false},
+		{9, "oneofIndex", fieldTypeInt32, "", false},
+		{10, "jsonName", fieldTypeString, "", false},
+		{8, "options", fieldTypeMessage, "FieldOptions", false},
+		{17, "proto3Optional", fieldTypeBool, "", false},
+	},
+	"OneofDescriptorProto": {
+		{1, "name", fieldTypeString, "", false},
+		{2, "options", fieldTypeMessage, "OneofOptions", false},
+	},
+	"EnumDescriptorProto": {
+		{1, "name", fieldTypeString, "", false},
+		{2, "value", fieldTypeMessage, "EnumValueDescriptorProto", true},
+		{3, "options", fieldTypeMessage, "EnumOptions", false},
+		{4, "reservedRange", fieldTypeMessage, "EnumDescriptorProto.EnumReservedRange", true},
+		{5, "reservedName", fieldTypeString, "", true},
+		{6, "visibility", fieldTypeEnum, "SymbolVisibility", false},
+	},
+	"EnumDescriptorProto.EnumReservedRange": {
+		{1, "start", fieldTypeInt32, "", false},
+		{2, "end", fieldTypeInt32, "", false},
+	},
+	"EnumValueDescriptorProto": {
+		{1, "name", fieldTypeString, "", false},
+		{2, "number", fieldTypeInt32, "", false},
+		{3, "options", fieldTypeMessage, "EnumValueOptions", false},
+	},
+	"ServiceDescriptorProto": {
+		{1, "name", fieldTypeString, "", false},
+		{2, "method", fieldTypeMessage, "MethodDescriptorProto", true},
+		{3, "options", fieldTypeMessage, "ServiceOptions", false},
+	},
+	"MethodDescriptorProto": {
+		{1, "name", fieldTypeString, "", false},
+		{2, "inputType", fieldTypeString, "", false},
+		{3, "outputType", fieldTypeString, "", false},
+		{4, "options", fieldTypeMessage, "MethodOptions", false},
+		{5, "clientStreaming", fieldTypeBool, "", false},
+		{6, "serverStreaming", fieldTypeBool, "", false},
+	},
+	"FileOptions": {
+		{1, "javaPackage", fieldTypeString, "", false},
+		{8, "javaOuterClassname", fieldTypeString, "", false},
+		{10, "javaMultipleFiles", fieldTypeBool, "", false},
+		{20, "javaGenerateEqualsAndHash", fieldTypeBool, "", false},
+		{27, "javaStringCheckUtf8", fieldTypeBool, "", false},
+		{9, "optimizeFor", fieldTypeEnum, "FileOptions.OptimizeMode", false},
+		{11, "goPackage", fieldTypeString, "", false},
+		{16, "ccGenericServices", fieldTypeBool, "", false},
+		{17, "javaGenericServices", fieldTypeBool, "", false},
+		{18, "pyGenericServices", fieldTypeBool, "", false},
+		{23, "deprecated", fieldTypeBool, "", false},
+		{31, "ccEnableArenas", fieldTypeBool, "", false},
+		{36, "objcClassPrefix", fieldTypeString, "", false},
+		{37, "csharpNamespace", fieldTypeString, "", false},
+		{39, "swiftPrefix", fieldTypeString, "", false},
+		{40, "phpClassPrefix", fieldTypeString, "", false},
+		{41, "phpNamespace", fieldTypeString, "", false},
+		{44, "phpMetadataNamespace", fieldTypeString, "", false},
+		{45, "rubyPackage", fieldTypeString, "", false},
+		{50, "features", fieldTypeMessage, "FeatureSet", false},
+		{999, "uninterpretedOption", fieldTypeMessage, "UninterpretedOption", true},
+	},
+	"MessageOptions": {
+		{1, "messageSetWireFormat", fieldTypeBool, "", false},
+		{2, "noStandardDescriptorAccessor", fieldTypeBool, "", false},
+		{3, "deprecated", fieldTypeBool, "", false},
+		{7, "mapEntry", fieldTypeBool, "", false},
+		{11, "deprecatedLegacyJsonFieldConflicts", fieldTypeBool, "", false},
+		{12, "features", fieldTypeMessage, "FeatureSet", false},
+		{999, "uninterpretedOption", fieldTypeMessage, "UninterpretedOption", true},
+	},
+	"FieldOptions": {
+		{1, "ctype", fieldTypeEnum, "FieldOptions.CType", false},
+		{2, "packed", fieldTypeBool, "", false},
+		{6, "jstype", fieldTypeEnum, "FieldOptions.JSType", false},
+		{5, "lazy", fieldTypeBool, "", false},
+		{15, "unverifiedLazy", fieldTypeBool, "", false},
+		{3, "deprecated", fieldTypeBool, "", false},
+		{10, "weak", fieldTypeBool, "", false},
+		{16, "debugRedact", fieldTypeBool, "", false},
+		{17, "retention", fieldTypeEnum, "FieldOptions.OptionRetention", false},
+		{19, "targets", fieldTypeEnum, "FieldOptions.OptionTargetType", true},
+		{20, "editionDefaults", fieldTypeMessage, "FieldOptions.EditionDefault", true},
+		{21, "features", fieldTypeMessage, "FeatureSet", false},
+		{22, "featureSupport", fieldTypeMessage, "FieldOptions.FeatureSupport", false},
+		{999, "uninterpretedOption", fieldTypeMessage, "UninterpretedOption", true},
+	},
+	"FieldOptions.EditionDefault": {
+		{3, "edition", fieldTypeEnum, "Edition", false},
+		{2, "value", fieldTypeString, "", false},
+	},
+	"FieldOptions.FeatureSupport": {
+		{1, "editionIntroduced", fieldTypeEnum, "Edition", false},
+		{2, "editionDeprecated", fieldTypeEnum, "Edition", false},
+		{3, "deprecationWarning", fieldTypeString, "", false},
+		{4, "editionRemoved", fieldTypeEnum, "Edition", false},
+	},
+	"OneofOptions": {
+		{1, "features", fieldTypeMessage, "FeatureSet", false},
+		{999, "uninterpretedOption", fieldTypeMessage, "UninterpretedOption", true},
+	},
+	"EnumOptions": {
+		{2, "allowAlias", fieldTypeBool, "", false},
+		{3, "deprecated", fieldTypeBool, "", false},
+		{6, "deprecatedLegacyJsonFieldConflicts", fieldTypeBool, "", false},
+		{7, "features", fieldTypeMessage, "FeatureSet", false},
+		{999, "uninterpretedOption", fieldTypeMessage, "UninterpretedOption", true},
+	},
+	"EnumValueOptions": {
+		{1, "deprecated", fieldTypeBool, "", false},
+		{2, "features", fieldTypeMessage, "FeatureSet", false},
+		{3, "debugRedact", fieldTypeBool, "", false},
+		{4, "featureSupport", fieldTypeMessage, "FieldOptions.FeatureSupport", false},
+		{999, "uninterpretedOption", fieldTypeMessage, "UninterpretedOption", true},
+	},
+	"ServiceOptions": {
+		{34, "features", fieldTypeMessage, "FeatureSet", false},
+		{33, "deprecated", fieldTypeBool, "", false},
+		{999, "uninterpretedOption", fieldTypeMessage, "UninterpretedOption", true},
+	},
+	"MethodOptions": {
+		{33, "deprecated", fieldTypeBool, "", false},
+		{34, "idempotencyLevel", fieldTypeEnum, "MethodOptions.IdempotencyLevel", false},
+		{35, "features", fieldTypeMessage, "FeatureSet", false},
+		{999, "uninterpretedOption", fieldTypeMessage, "UninterpretedOption", true},
+	},
+	"UninterpretedOption": {
+		{2, "name", fieldTypeMessage, "UninterpretedOption.NamePart", true},
+		{3, "identifierValue", fieldTypeString, "", false},
+		{4, "positiveIntValue", fieldTypeUint64, "", false},
+		{5, "negativeIntValue", fieldTypeInt64, "", false},
+		{6, "doubleValue", fieldTypeDouble, "", false},
+		{7, "stringValue", fieldTypeBytes, "", false},
+		{8, "aggregateValue", fieldTypeString, "", false},
+	},
+	"UninterpretedOption.NamePart": {
+		{1, "namePart", fieldTypeString, "", false},
+		{2, "isExtension", fieldTypeBool, "", false},
+	},
+	"FeatureSet": {
+		{1, "fieldPresence", fieldTypeEnum, "FeatureSet.FieldPresence", false},
+		{2, "enumType", fieldTypeEnum, "FeatureSet.EnumType", false},
+		{3, "repeatedFieldEncoding", fieldTypeEnum, "FeatureSet.RepeatedFieldEncoding", false},
+		{4, "utf8Validation", fieldTypeEnum, "FeatureSet.Utf8Validation", false},
+		{5, "messageEncoding", fieldTypeEnum, "FeatureSet.MessageEncoding", false},
+		{6, "jsonFormat", fieldTypeEnum, "FeatureSet.JsonFormat", false},
+		{7, "enforceNamingStyle", fieldTypeEnum, "FeatureSet.EnforceNamingStyle", false},
+		{8, "defaultSymbolVisibility", fieldTypeEnum, "FeatureSet.VisibilityFeature.DefaultSymbolVisibility", false},
+	},
+	"FeatureSet.VisibilityFeature": {},
+	"FeatureSetDefaults": {
+		{1, "defaults", fieldTypeMessage, "FeatureSetDefaults.FeatureSetEditionDefault", true},
+		{4, "minimumEdition", fieldTypeEnum, "Edition", false},
+		{5, "maximumEdition", fieldTypeEnum, "Edition", false},
+	},
+	"FeatureSetDefaults.FeatureSetEditionDefault": {
+		{3, "edition", fieldTypeEnum, "Edition", false},
+		{4, "overridableFeatures", fieldTypeMessage, "FeatureSet", false},
+		{5, "fixedFeatures", fieldTypeMessage, "FeatureSet", false},
+	},
+	"SourceCodeInfo": {
+		{1, "location", fieldTypeMessage, "SourceCodeInfo.Location", true},
+	},
+	"SourceCodeInfo.Location": {
+		{1, "path", fieldTypeInt32, "", true},
+		{2, "span", fieldTypeInt32, "", true},
+		{3, "leadingComments", fieldTypeString, "", false},
+		{4, "trailingComments", fieldTypeString, "", false},
+		{6, "leadingDetachedComments", fieldTypeString, "", true},
+	},
+	"GeneratedCodeInfo": {
+		{1, "annotation", fieldTypeMessage, "GeneratedCodeInfo.Annotation", true},
+	},
+	"GeneratedCodeInfo.Annotation": {
+		{1, "path", fieldTypeInt32, "", true},
+		{2, "sourceFile", fieldTypeString, "", false},
+		{3, "begin", fieldTypeInt32, "", false},
+		{4, "end", fieldTypeInt32, "", false},
+		{5, "semantic", fieldTypeEnum, "GeneratedCodeInfo.Annotation.Semantic", false},
+	},
+}
+
+// descriptorSchemaEnums maps the enums in google/protobuf/descriptor.proto to
+// their value names, keyed by the enum name relative to google.protobuf.
+var descriptorSchemaEnums = map[string]map[int32]string{
+	"ExtensionRangeOptions.VerificationState": {
+		0: "DECLARATION",
+		1: "UNVERIFIED",
+	},
+	"FieldDescriptorProto.Type": {
+		1:  "TYPE_DOUBLE",
+		2:  "TYPE_FLOAT",
+		3:  "TYPE_INT64",
+		4:  "TYPE_UINT64",
+		5:  "TYPE_INT32",
+		6:  "TYPE_FIXED64",
+		7:  "TYPE_FIXED32",
+		8:  "TYPE_BOOL",
+		9:  "TYPE_STRING",
+		10: "TYPE_GROUP",
+		11: "TYPE_MESSAGE",
+		12: "TYPE_BYTES",
+		13: "TYPE_UINT32",
+		14: "TYPE_ENUM",
+		15: "TYPE_SFIXED32",
+		16: "TYPE_SFIXED64",
+		17: "TYPE_SINT32",
+		18: "TYPE_SINT64",
+	},
+	"FieldDescriptorProto.Label": {
+		1: "LABEL_OPTIONAL",
+		3: "LABEL_REPEATED",
+		2: "LABEL_REQUIRED",
+	},
+	"FileOptions.OptimizeMode": {
+		1: "SPEED",
+		2: "CODE_SIZE",
+		3: "LITE_RUNTIME",
+	},
+	"FieldOptions.CType": {
+		0: "STRING",
+		1: "CORD",
+		2: "STRING_PIECE",
+	},
+	"FieldOptions.JSType": {
+		0: "JS_NORMAL",
+		1: "JS_STRING",
+		2: "JS_NUMBER",
+	},
+	"FieldOptions.OptionRetention": {
+		0: "RETENTION_UNKNOWN",
+		1: "RETENTION_RUNTIME",
+		2: "RETENTION_SOURCE",
+	},
+	"FieldOptions.OptionTargetType": {
+		0: "TARGET_TYPE_UNKNOWN",
+		1: "TARGET_TYPE_FILE",
+		2: "TARGET_TYPE_EXTENSION_RANGE",
+		3: "TARGET_TYPE_MESSAGE",
+		4: "TARGET_TYPE_FIELD",
+		5: "TARGET_TYPE_ONEOF",
+		6: "TARGET_TYPE_ENUM",
+		7: "TARGET_TYPE_ENUM_ENTRY",
+		8: "TARGET_TYPE_SERVICE",
+		9: "TARGET_TYPE_METHOD",
+	},
+	"MethodOptions.IdempotencyLevel": {
+		0: "IDEMPOTENCY_UNKNOWN",
+		1: "NO_SIDE_EFFECTS",
+		2: "IDEMPOTENT",
+	},
+	"FeatureSet.VisibilityFeature.DefaultSymbolVisibility": {
+		0: "DEFAULT_SYMBOL_VISIBILITY_UNKNOWN",
+		1: "EXPORT_ALL",
+		2: "EXPORT_TOP_LEVEL",
+		3: "LOCAL_ALL",
+		4: "STRICT",
+	},
+	"FeatureSet.FieldPresence": {
+		0: "FIELD_PRESENCE_UNKNOWN",
+		1: "EXPLICIT",
+		2: "IMPLICIT",
+		3: "LEGACY_REQUIRED",
+	},
+	"FeatureSet.EnumType": {
+		0: "ENUM_TYPE_UNKNOWN",
+		1: "OPEN",
+		2: "CLOSED",
+	},
+	"FeatureSet.RepeatedFieldEncoding": {
+		0: "REPEATED_FIELD_ENCODING_UNKNOWN",
+		1: "PACKED",
+		2: "EXPANDED",
+	},
+	"FeatureSet.Utf8Validation": {
+		0: "UTF8_VALIDATION_UNKNOWN",
+		2: "VERIFY",
+		3: "NONE",
+	},
+	"FeatureSet.MessageEncoding": {
+		0: "MESSAGE_ENCODING_UNKNOWN",
+		1: "LENGTH_PREFIXED",
+		2: "DELIMITED",
+	},
+	"FeatureSet.JsonFormat": {
+		0: "JSON_FORMAT_UNKNOWN",
+		1: "ALLOW",
+		2: "LEGACY_BEST_EFFORT",
+	},
+	"FeatureSet.EnforceNamingStyle": {
+		0: "ENFORCE_NAMING_STYLE_UNKNOWN",
+		1: "STYLE2024",
+		2: "STYLE_LEGACY",
+	},
+	"GeneratedCodeInfo.Annotation.Semantic": {
+		0: "NONE",
+		1: "SET",
+		2: "ALIAS",
+	},
+	"Edition": {
+		0:          "EDITION_UNKNOWN",
+		900:        "EDITION_LEGACY",
+		998:        "EDITION_PROTO2",
+		999:        "EDITION_PROTO3",
+		1000:       "EDITION_2023",
+		1001:       "EDITION_2024",
+		9999:       "EDITION_UNSTABLE",
+		1:          "EDITION_1_TEST_ONLY",
+		2:          "EDITION_2_TEST_ONLY",
+		99997:      "EDITION_99997_TEST_ONLY",
+		99998:      "EDITION_99998_TEST_ONLY",
+		99999:      "EDITION_99999_TEST_ONLY",
+		2147483647: "EDITION_MAX",
+	},
+	"SymbolVisibility": {
+		0: "VISIBILITY_UNSET",
+		1: "VISIBILITY_LOCAL",
+		2: "VISIBILITY_EXPORT",
+	},
+}
@@ -0,0 +1,78 @@
+package protogen
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEncodeDescriptorSetJSON(t *testing.T) {
+	var field []byte
+	field = appendProtoBytes(field, 1, []byte("id"))
+	field = appendProtoVarint(field, 3, 1)
+	field = appendProtoVarint(field, 4, fieldLabelRepeated)
+	field = appendProtoVarint(field, 5, 3)
+	var msg []byte
+	msg = appendProtoBytes(msg, 1, []byte("Foo"))
+	msg = appendProtoBytes(msg, 2, field)
+	var location []byte
+	location = appendProtoBytes(location, 1, []byte{4, 0})
+	location = appendProtoBytes(location, 3, []byte(" Foo <doc>.\n"))
+	var file []byte
+	file = appendProtoBytes(file, 1, []byte("foo.proto"))
+	file = appendProtoBytes(file, 4, msg)
+	file = appendProtoBytes(file, 9, appendProtoBytes(nil, 1, location))
+	// FileOptions.java_multiple_files and custom option 50000 = 7.
+	file = appendProtoBytes(file, 8, append(appendProtoVarint(nil, 10, 1), 0x80, 0xb5, 0x18, 7))
+	set := appendProtoBytes(nil, 1, file)
+
+	data, err := EncodeDescriptorSetJSON(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, data)
+	}
+	want := `{
+  "file": [
+    {
+      "name": "foo.proto",
+      "messageType": [
+        {
+          "name": "Foo",
+          "field": [
+            {
+              "name": "id",
+              "number": 1,
+              "label": "LABEL_REPEATED",
+              "type": "TYPE_INT64"
+            }
+          ]
+        }
+      ],
+      "options": {
+        "javaMultipleFiles": true
+      },
+      "sourceCodeInfo": {
+        "location": [
+          {
+            "path": [
+              4,
+              0
+            ],
+            "leadingComments": " Foo <doc>.\n"
+          }
+        ]
+      }
+    }
+  ]
+}
+`
+	if string(data) != want {
+		t.Fatalf("unexpected JSON:\n%s", data)
+	}
+
+	if _, err := EncodeDescriptorSetJSON([]byte{0x0a, 0x05}); err == nil {
+		t.Fatal("expected error for a truncated descriptor set")
+	}
+}
@@ -0,0 +1,114 @@
+package protogen
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ValidateDescriptorSets checks that every descriptor set path is a unique
+// path inside the project directory.
+func (c *Config) ValidateDescriptorSets() error {
+	seen := make(map[string]struct{}, len(c.DescriptorSets))
+	for _, p := range c.DescriptorSets {
+		if !filepath.IsLocal(p) {
+			return fmt.Errorf("descriptor set %q: must be a relative path inside the project directory", p)
+		}
+		clean := filepath.Clean(p)
+		if _, ok := seen[clean]; ok {
+			return fmt.Errorf("descriptor set %q: duplicate path", p)
+		}
+		seen[clean] = struct{}{}
+	}
+	return nil
+}
+
+// isJSONDescriptorSet returns true if the descriptor set path selects the
+// protobuf JSON format.
+func isJSONDescriptorSet(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// writeDescriptorSets writes the configured descriptor set files for the
+// proto files if the cache says they are out of date, and removes the files
+// written by a previous run that are no longer configured. The project
+// symlinks must be set up. Removed files are counted in summary.
+func (g *Generator) writeDescriptorSets(ctx context.Context, protoFiles []string, toolVersions string, importGraph *protoImportGraph, summary *GenerateSummary) error {
+	outputs := make([]string, len(g.Config.DescriptorSets))
+	for i, p := range g.Config.DescriptorSets {
+		outputs[i] = filepath.Clean(p)
+	}
+	if !g.Cache.NeedsDescriptorSet(protoFiles, outputs, g.ProjectDir, toolVersions, g.Config.Force, importGraph.Hash) {
+		if len(outputs) != 0 && g.Verbose {
+			fmt.Fprintln(g.Stdout, "Skipping descriptor sets (up to date)")
+		}
+		return nil
+	}
+
+	if len(outputs) != 0 {
+		tmpDir, err := os.MkdirTemp("", "aptre-descriptor-set-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpDir)
+
+		outPath := filepath.Join(tmpDir, "descriptor.binpb")
+		if _, err := g.execDescriptorSet(ctx, protoFiles, outPath, "--include_imports", "--include_source_info"); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(outPath)
+		if err != nil {
+			return err
+		}
+
+		var jsonData []byte
+		for _, out := range outputs {
+			content := data
+			if isJSONDescriptorSet(out) {
+				if jsonData == nil {
+					jsonData, err = EncodeDescriptorSetJSON(data)
+					if err != nil {
+						return err
+					}
+				}
+				content = jsonData
+			}
+			path := filepath.Join(g.ProjectDir, out)
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, content, 0o644); err != nil {
+				return err
+			}
+			if g.Verbose {
+				fmt.Fprintf(g.Stdout, "Wrote descriptor set %s\n", out)
+			}
+			g.emit(&Event{Type: EventDescriptorSet, Path: out})
+		}
+	}
+
+	if previous := g.Cache.DescriptorSet; previous != nil {
+		for _, old := range previous.GeneratedFiles {
+			if slices.Contains(outputs, old) {
+				continue
+			}
+			if err := os.Remove(filepath.Join(g.ProjectDir, old)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove stale descriptor set %s: %w", old, err)
+			}
+			g.emit(&Event{Type: EventRemove, Path: old})
+			summary.Removed++
+		}
+	}
+	var imports map[string]string
+	if len(outputs) != 0 {
+		var err error
+		imports, err = importGraph.Closure(protoFiles)
+		if err != nil {
+			return fmt.Errorf("failed to resolve imports: %w", err)
+		}
+	}
+	return g.Cache.UpdateDescriptorSet(protoFiles, outputs, g.ProjectDir, imports)
+}
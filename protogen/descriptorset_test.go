@@ -0,0 +1,151 @@
+package protogen
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGenerateWritesDescriptorSets(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"foo/foo.proto": "syntax = \"proto3\";\npackage foo;\nimport \"example.com/project/bar/bar.proto\";\n\n// Foo is a foo.\nmessage Foo { bar.Bar bar = 1; }\n",
+		"bar/bar.proto": "syntax = \"proto3\";\npackage bar;\nmessage Bar { int64 id = 1; }\n",
+	})
+	g.Config.DescriptorSets = []string{"gen/api.binpb", "gen/api.json"}
+	ctx := context.Background()
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(g.ProjectDir, "gen", "api.binpb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := ParseFileDescriptorSet(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	if !slices.Equal(names, []string{"example.com/project/bar/bar.proto", "example.com/project/foo/foo.proto"}) {
+		t.Fatalf("unexpected files in descriptor set: %v", names)
+	}
+	if files[1].LeadingComments([]int32{fileDescriptorMessageField, 0}) != " Foo is a foo.\n" {
+		t.Fatal("descriptor set is missing source info")
+	}
+
+	data, err = os.ReadFile(filepath.Join(g.ProjectDir, "gen", "api.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var set struct {
+		File []struct {
+			Name           string          `json:"name"`
+			Dependency     []string        `json:"dependency"`
+			SourceCodeInfo json.RawMessage `json:"sourceCodeInfo"`
+		} `json:"file"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		t.Fatalf("invalid JSON descriptor set: %v", err)
+	}
+	if len(set.File) != 2 || set.File[1].Name != "example.com/project/foo/foo.proto" ||
+		!slices.Equal(set.File[1].Dependency, []string{"example.com/project/bar/bar.proto"}) || set.File[1].SourceCodeInfo == nil {
+		t.Fatalf("unexpected JSON descriptor set: %s", data)
+	}
+
+	if info := g.Cache.DescriptorSet; info == nil || !slices.Equal(info.GeneratedFiles, g.Config.DescriptorSets) {
+		t.Fatalf("descriptor sets not recorded in the cache: %+v", info)
+	}
+	if g.Cache.NeedsDescriptorSet([]string{"bar/bar.proto", "foo/foo.proto"}, g.Config.DescriptorSets, g.ProjectDir, g.Cache.ToolVersions, false, g.newProtoImportGraph().Hash) {
+		t.Fatal("descriptor sets reported out of date after generation")
+	}
+
+	// Dropping an output removes the file written by the previous run.
+	g.Config.DescriptorSets = []string{"gen/api.json"}
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(g.ProjectDir, "gen", "api.binpb")); !os.IsNotExist(err) {
+		t.Fatal("stale descriptor set was not removed")
+	}
+	if stale, err := g.Check(ctx); err != nil || len(stale) != 0 {
+		t.Fatalf("check: %v %v", stale, err)
+	}
+
+	if err := g.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(g.ProjectDir, "gen", "api.json")); !os.IsNotExist(err) {
+		t.Fatal("clean did not remove the descriptor set")
+	}
+}
+
+func TestDescriptorSetTracksImportsAndOutputs(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"foo/foo.proto":                    "syntax = \"proto3\";\npackage foo;\nimport \"example.com/dep/dep.proto\";\nmessage Foo { dep.Dep dep = 1; }\n",
+		"vendor/example.com/dep/dep.proto": "syntax = \"proto3\";\npackage dep;\nmessage Dep {}\n",
+	})
+	g.Config.DescriptorSets = []string{"gen/api.binpb"}
+	ctx := context.Background()
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	assertPlanned := func(want bool) {
+		t.Helper()
+		plan, err := g.Plan()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(plan.DescriptorSets) != 0; got != want {
+			t.Fatalf("descriptor set planned = %v, want %v", got, want)
+		}
+	}
+	assertPlanned(false)
+
+	// Changing a vendored import makes the descriptor set stale.
+	depPath := filepath.Join(g.VendorDir, "example.com", "dep", "dep.proto")
+	if err := os.WriteFile(depPath, []byte("syntax = \"proto3\";\npackage dep;\nmessage Dep { string id = 1; }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assertPlanned(true)
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	assertPlanned(false)
+
+	// A deleted output is written again.
+	outPath := filepath.Join(g.ProjectDir, "gen", "api.binpb")
+	if err := os.Remove(outPath); err != nil {
+		t.Fatal(err)
+	}
+	assertPlanned(true)
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if _, err := os.Stat(outPath); err != nil {
+		t.Fatalf("descriptor set was not rewritten: %v", err)
+	}
+}
+
+func TestValidateDescriptorSets(t *testing.T) {
+	for _, paths := range [][]string{
+		{"../api.binpb"},
+		{"/tmp/api.binpb"},
+		{""},
+		{"gen/api.binpb", "./gen/api.binpb"},
+	} {
+		cfg := &Config{DescriptorSets: paths}
+		if err := cfg.ValidateDescriptorSets(); err == nil {
+			t.Errorf("expected validation error for %v", paths)
+		}
+	}
+	cfg := &Config{DescriptorSets: []string{"api.binpb", "gen/api.json"}}
+	if err := cfg.ValidateDescriptorSets(); err != nil {
+		t.Fatal(err)
+	}
+}
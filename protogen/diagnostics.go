@@ -0,0 +1,253 @@
+package protogen
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// ProtocDiagnostic is an error or warning reported by protoc.
+type ProtocDiagnostic struct {
+	// File is the proto file, project-relative for project files and the
+	// import path for other files. Empty for messages without a file.
+	File string `json:"file,omitempty"`
+	// Line is the 1-based line, or zero if unknown.
+	Line int `json:"line,omitempty"`
+	// Column is the 1-based column, or zero if unknown.
+	Column int `json:"column,omitempty"`
+	// Message is the message printed by protoc.
+	Message string `json:"message"`
+	// Warning is set for warnings.
+	Warning bool `json:"warning,omitempty"`
+	// Import is the import path of an unresolved import statement.
+	Import string `json:"import,omitempty"`
+	// Module is the Go module expected to provide Import.
+	Module string `json:"module,omitempty"`
+	// Suggestion explains why Import could not be resolved and how to fix it.
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// String formats the diagnostic as "file:line:col: message", followed by the
+// suggestion on an indented line.
+func (d *ProtocDiagnostic) String() string {
+	var sb strings.Builder
+	if d.File != "" {
+		sb.WriteString(d.File)
+		if d.Line != 0 {
+			fmt.Fprintf(&sb, ":%d:%d", d.Line, d.Column)
+		}
+		sb.WriteString(": ")
+	}
+	if d.Warning {
+		sb.WriteString("warning: ")
+	}
+	sb.WriteString(d.Message)
+	if d.Suggestion != "" {
+		sb.WriteString("\n  ")
+		sb.WriteString(d.Suggestion)
+	}
+	return sb.String()
+}
+
+// ProtocError is returned when protoc exits with an error.
+type ProtocError struct {
+	// ExitCode is the protoc exit code.
+	ExitCode int
+	// Diagnostics are the messages parsed from protoc's output.
+	Diagnostics []*ProtocDiagnostic
+}
+
+// Error formats the exit code and the diagnostics, one per line.
+func (e *ProtocError) Error() string {
+	if len(e.Diagnostics) == 0 {
+		return fmt.Sprintf("protoc failed with exit code %d", e.ExitCode)
+	}
+	lines := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		lines[i] = d.String()
+	}
+	return fmt.Sprintf("protoc failed with exit code %d:\n%s", e.ExitCode, strings.Join(lines, "\n"))
+}
+
+var (
+	// protocPositionRe matches "file:line:col: message".
+	protocPositionRe = regexp.MustCompile(`^(.+?):(\d+):(\d+): (.*)$`)
+	// protocFileRe matches "file: message".
+	protocFileRe = regexp.MustCompile(`^(\S+?\.proto): (.*)$`)
+	// protocImportRe matches the message of an import that failed.
+	protocImportRe = regexp.MustCompile(`^Import "([^"]+)" was not found or had errors\.$`)
+)
+
+// protocFileNotFound is the message protoc prints for a missing file.
+const protocFileNotFound = "File not found."
+
+// ParseProtocDiagnostics parses the errors and warnings printed by protoc.
+// Lines that are not in a known format are kept as messages without a file.
+func ParseProtocDiagnostics(output string) []*ProtocDiagnostic {
+	var diags []*ProtocDiagnostic
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		d := &ProtocDiagnostic{Message: line}
+		if m := protocPositionRe.FindStringSubmatch(line); m != nil {
+			d.File, d.Message = m[1], m[4]
+			d.Line, _ = strconv.Atoi(m[2])
+			d.Column, _ = strconv.Atoi(m[3])
+		} else if m := protocFileRe.FindStringSubmatch(line); m != nil {
+			d.File, d.Message = m[1], m[2]
+		}
+		if msg, ok := strings.CutPrefix(d.Message, "warning: "); ok {
+			d.Warning, d.Message = true, msg
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+// newProtocError builds the error for a failed protoc run. Project files are
+// reported relative to the project directory and unresolved imports are
+// annotated with the module expected to provide them.
+func (g *Generator) newProtocError(exitCode int, output string) *ProtocError {
+	diags := ParseProtocDiagnostics(output)
+	notFound := make(map[string]struct{})
+	for _, d := range diags {
+		if d.Line == 0 && d.Message == protocFileNotFound {
+			notFound[d.File] = struct{}{}
+		}
+		d.File = g.diagnosticFile(d.File)
+	}
+	for _, d := range diags {
+		m := protocImportRe.FindStringSubmatch(d.Message)
+		if m == nil {
+			continue
+		}
+		// Imports that were found but had errors are reported separately.
+		if _, ok := notFound[m[1]]; !ok {
+			continue
+		}
+		d.Import = m[1]
+		d.Module, d.Suggestion = g.diagnoseImport(m[1])
+	}
+	return &ProtocError{ExitCode: exitCode, Diagnostics: diags}
+}
+
+// diagnosticFile converts a file reported by protoc to a project-relative
+// path for project files. Other files are returned unchanged.
+func (g *Generator) diagnosticFile(file string) string {
+	for _, prefix := range []string{
+		filepath.Join(g.VendorDir, g.ModulePath) + string(filepath.Separator),
+		g.ProjectDir + string(filepath.Separator),
+	} {
+		if rel, ok := strings.CutPrefix(file, prefix); ok {
+			return filepath.ToSlash(rel)
+		}
+	}
+	if rel, ok := strings.CutPrefix(file, g.ModulePath+"/"); ok {
+		return rel
+	}
+	return file
+}
+
+// diagnoseImport returns the module expected to provide an import path that
+// protoc could not find, and a suggestion explaining how to make it
+// resolvable.
+func (g *Generator) diagnoseImport(importPath string) (string, string) {
+	if rel, ok := strings.CutPrefix(importPath, g.ModulePath+"/"); ok {
+		return g.ModulePath, fmt.Sprintf("%s does not exist in the project", rel)
+	}
+	for _, m := range g.workspaceSiblings() {
+		if rel, ok := strings.CutPrefix(importPath, m.Path+"/"); ok {
+			return m.Path, fmt.Sprintf("workspace module %s does not contain %s", m.Path, rel)
+		}
+	}
+
+	// The well-known types are provided by the protobuf module's src dir.
+	filePath := importPath
+	if strings.HasPrefix(importPath, "google/protobuf/") {
+		filePath = path.Join(protobufModulePath, "src", importPath)
+	}
+
+	modulePath, version := g.requiredModule(filePath)
+	if modulePath == "" {
+		modulePath = guessModulePath(filePath)
+		return modulePath, fmt.Sprintf("no module in go.mod provides %s: run go get %s", importPath, modulePath)
+	}
+	rel := strings.TrimPrefix(filePath, modulePath+"/")
+
+	if vendored, ok := readVendoredModules(g.VendorDir); ok {
+		if _, ok := vendored[modulePath]; !ok {
+			return modulePath, fmt.Sprintf("%s is required by go.mod but missing from vendor/modules.txt: run go mod vendor", modulePath)
+		}
+		return modulePath, fmt.Sprintf("%s@%s is vendored without %s: check the import path, or run go get %s@latest and go mod vendor", modulePath, version, rel, modulePath)
+	}
+	if modules := g.goModules(); modules != nil && modules.Dir(modulePath) == "" {
+		return modulePath, fmt.Sprintf("%s@%s is required by go.mod but not downloaded: run go mod download %s", modulePath, version, modulePath)
+	}
+	return modulePath, fmt.Sprintf("%s@%s does not contain %s: check the import path, or run go get %s@latest", modulePath, version, rel, modulePath)
+}
+
+// requiredModule returns the module required by go.mod with the longest path
+// that is a prefix of the file path, and its version.
+func (g *Generator) requiredModule(filePath string) (string, string) {
+	goModPath := filepath.Join(g.ModuleDir, "go.mod")
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		return "", ""
+	}
+	modFile, err := modfile.ParseLax(goModPath, data, nil)
+	if err != nil {
+		return "", ""
+	}
+	var modulePath, version string
+	for _, req := range modFile.Require {
+		if strings.HasPrefix(filePath, req.Mod.Path+"/") && len(req.Mod.Path) > len(modulePath) {
+			modulePath, version = req.Mod.Path, req.Mod.Version
+		}
+	}
+	return modulePath, version
+}
+
+// readVendoredModules returns the modules listed in vendor/modules.txt by
+// path. Returns false if the project does not vendor its dependencies.
+func readVendoredModules(vendorDir string) (map[string]string, bool) {
+	data, err := os.ReadFile(filepath.Join(vendorDir, "modules.txt"))
+	if err != nil {
+		return nil, false
+	}
+	modules := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "#" {
+			continue
+		}
+		var version string
+		if len(fields) >= 3 && fields[2] != "=>" {
+			version = fields[2]
+		}
+		modules[fields[1]] = version
+	}
+	return modules, true
+}
+
+// guessModulePath guesses the module providing an import path that is not
+// required by go.mod. Paths on well-known code hosts use the repository root.
+func guessModulePath(importPath string) string {
+	parts := strings.Split(path.Dir(importPath), "/")
+	switch parts[0] {
+	case "github.com", "gitlab.com", "bitbucket.org":
+		if len(parts) >= 3 {
+			return strings.Join(parts[:3], "/")
+		}
+	}
+	return path.Dir(importPath)
+}
@@ -0,0 +1,84 @@
+package protogen
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseProtocDiagnostics(t *testing.T) {
+	diags := ParseProtocDiagnostics("google/protobuf/timestamp.proto: File not found.\n" +
+		"/p/vendor/example.com/p/a.proto:3:1: Import \"google/protobuf/timestamp.proto\" was not found or had errors.\n" +
+		"a.proto:5:10: warning: Import b.proto is unused.\n" +
+		"something unexpected\n")
+	want := []ProtocDiagnostic{
+		{File: "google/protobuf/timestamp.proto", Message: "File not found."},
+		{File: "/p/vendor/example.com/p/a.proto", Line: 3, Column: 1, Message: "Import \"google/protobuf/timestamp.proto\" was not found or had errors."},
+		{File: "a.proto", Line: 5, Column: 10, Message: "Import b.proto is unused.", Warning: true},
+		{Message: "something unexpected"},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	for i, d := range diags {
+		if *d != want[i] {
+			t.Errorf("diagnostic %d: want %+v, got %+v", i, want[i], *d)
+		}
+	}
+}
+
+func TestGenerateReportsUnresolvedImports(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"go.mod": "module example.com/project\n\ngo 1.24\n\nrequire example.com/dep v1.2.0\n",
+		"a/a.proto": "syntax = \"proto3\";\npackage a;\n" +
+			"import \"github.com/acme/types/v1/types.proto\";\n" +
+			"import \"example.com/dep/api/api.proto\";\n" +
+			"import \"google/protobuf/timestamp.proto\";\n",
+	})
+	if err := os.WriteFile(filepath.Join(g.VendorDir, "modules.txt"), []byte("# example.com/dep v1.2.0\n## explicit\nexample.com/dep/api\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := g.Generate(context.Background())
+	var perr *ProtocError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected a protoc error, got %v", err)
+	}
+	got := make(map[string]*ProtocDiagnostic)
+	for _, d := range perr.Diagnostics {
+		if d.Import != "" {
+			got[d.Import] = d
+		}
+	}
+	for importPath, want := range map[string]struct {
+		line              int
+		module, suggested string
+	}{
+		"github.com/acme/types/v1/types.proto": {3, "github.com/acme/types", "run go get github.com/acme/types"},
+		"example.com/dep/api/api.proto":        {4, "example.com/dep", "example.com/dep@v1.2.0 is vendored without api/api.proto"},
+		"google/protobuf/timestamp.proto":      {5, "github.com/aperturerobotics/protobuf", "run go get github.com/aperturerobotics/protobuf"},
+	} {
+		d := got[importPath]
+		if d == nil {
+			t.Errorf("no diagnostic for %s in %v", importPath, err)
+			continue
+		}
+		if d.File != "a/a.proto" || d.Line != want.line || d.Module != want.module || !strings.Contains(d.Suggestion, want.suggested) {
+			t.Errorf("unexpected diagnostic for %s: %+v", importPath, d)
+		}
+	}
+	if !strings.Contains(err.Error(), "a/a.proto:3:1: Import") {
+		t.Errorf("error does not report the project file: %v", err)
+	}
+
+	// Modules required by go.mod but not vendored.
+	if err := os.WriteFile(filepath.Join(g.VendorDir, "modules.txt"), []byte("# example.com/other v1.0.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, suggestion := g.diagnoseImport("example.com/dep/api/api.proto"); suggestion != "example.com/dep is required by go.mod but missing from vendor/modules.txt: run go mod vendor" {
+		t.Errorf("unexpected suggestion: %s", suggestion)
+	}
+}
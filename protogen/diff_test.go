@@ -0,0 +1,67 @@
+package protogen
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	b := "one\ntwo\nthree\nfour\nFIVE\nsix\nseven\neight\nnine\nten\neleven\n"
+	got := UnifiedDiff("a/f.txt", "b/f.txt", []byte(a), []byte(b))
+	want := `--- a/f.txt
++++ b/f.txt
+@@ -2,9 +2,10 @@
+ two
+ three
+ four
+-five
++FIVE
+ six
+ seven
+ eight
+ nine
+ ten
++eleven
+`
+	if got != want {
+		t.Fatalf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiffSeparateHunksAndMissingNewline(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\nlast\n"
+	b := "1\nX\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\nlast"
+	got := UnifiedDiff("a", "b", []byte(a), []byte(b))
+	want := `--- a
++++ b
+@@ -1,5 +1,5 @@
+ 1
+-2
++X
+ 3
+ 4
+ 5
+@@ -10,4 +10,4 @@
+ 10
+ 11
+ 12
+-last
++last
+\ No newline at end of file
+`
+	if got != want {
+		t.Fatalf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiffIdentical(t *testing.T) {
+	if got := UnifiedDiff("a", "b", []byte("x\n"), []byte("x\n")); got != "" {
+		t.Fatalf("identical inputs produced diff %q", got)
+	}
+}
+
+func TestUnifiedDiffNewFile(t *testing.T) {
+	got := UnifiedDiff("/dev/null", "b/new.txt", nil, []byte("a\nb\n"))
+	want := "--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1,2 @@\n+a\n+b\n"
+	if got != want {
+		t.Fatalf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
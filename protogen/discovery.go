@@ -1,85 +1,179 @@
 package protogen
 
 import (
-	"bufio"
 	"bytes"
+	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/mod/modfile"
 )
 
 // DiscoverProtoFiles finds proto files matching the given patterns.
-// Uses git ls-files to find tracked proto files.
+// Walks the project directory natively, honouring .gitignore and .ignore
+// files and skipping the vendor, node_modules and .tools directories and
+// nested Go modules.
+// See matchesPatternList for the pattern syntax.
 // excludePatterns allows excluding files that match certain patterns.
-func DiscoverProtoFiles(projectDir string, patterns, excludePatterns []string) ([]string, error) {
+// If includeUntracked is false and the project is a git checkout, only files
+// tracked by git are returned. Returns the project-relative files, sorted.
+func DiscoverProtoFiles(projectDir string, patterns, excludePatterns []string, includeUntracked bool) ([]string, error) {
+	if err := validatePatternList(patterns); err != nil {
+		return nil, err
+	}
+	if err := validatePatternList(excludePatterns); err != nil {
+		return nil, err
+	}
+
+	candidates, err := walkProtoFiles(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	if !includeUntracked {
+		if tracked := gitTrackedFiles(projectDir); tracked != nil {
+			candidates = slices.DeleteFunc(candidates, func(f string) bool {
+				_, ok := tracked[f]
+				return !ok
+			})
+		}
+	}
+
 	var allFiles []string
-	seen := make(map[string]struct{})
+	for _, f := range candidates {
+		if matchesPatternList(f, patterns) && !matchesPatternList(f, excludePatterns) {
+			allFiles = append(allFiles, f)
+		}
+	}
+	return allFiles, nil
+}
 
+// matchesPatternList checks if a slash-separated project-relative file is
+// selected by the glob patterns.
+//
+// Patterns are evaluated in order and the last matching pattern wins. A
+// pattern starting with "!" deselects the files it matches. See
+// matchFilePattern for the pattern syntax.
+func matchesPatternList(file string, patterns []string) bool {
+	var matched bool
 	for _, pattern := range patterns {
-		files, err := discoverPattern(projectDir, pattern)
-		if err != nil {
-			return nil, err
+		pattern, negate := strings.CutPrefix(pattern, "!")
+		if matchFilePattern(pattern, file) {
+			matched = !negate
 		}
-		for _, f := range files {
-			if _, ok := seen[f]; !ok {
-				// Check if file matches any exclude pattern
-				if matchesAnyPattern(f, excludePatterns) {
-					continue
-				}
-				seen[f] = struct{}{}
-				allFiles = append(allFiles, f)
+	}
+	return matched
+}
+
+// validatePatternList checks that every pattern in the list is a valid glob.
+func validatePatternList(patterns []string) error {
+	for _, pattern := range patterns {
+		for _, expanded := range expandBraces(strings.TrimPrefix(pattern, "!")) {
+			if _, err := path.Match(expanded, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
 			}
 		}
 	}
-
-	return allFiles, nil
+	return nil
 }
 
-// matchesAnyPattern checks if a file path matches any of the given glob patterns.
-func matchesAnyPattern(filePath string, patterns []string) bool {
-	for _, pattern := range patterns {
-		// Try matching against the full path
-		matched, err := filepath.Match(pattern, filePath)
-		if err == nil && matched {
-			return true
+// matchFilePattern matches a slash-separated project-relative file against a
+// file pattern. Patterns keep the semantics of git pathspecs: they are
+// relative to the project directory, a leading "./" is dropped, and "*" and
+// "?" also match "/", so "api/*.proto" selects every proto file below api/.
+// In addition "**/" matches zero or more directories, "{a,b}" matches either
+// alternative, and patterns without a "/" match the file name at any depth.
+func matchFilePattern(pattern, file string) bool {
+	for _, expanded := range expandBraces(pattern) {
+		expanded = strings.TrimPrefix(path.Clean("/"+expanded), "/")
+		name := file
+		if !strings.Contains(expanded, "/") {
+			name = path.Base(file)
 		}
-		// Also try matching against just the filename
-		matched, err = filepath.Match(pattern, filepath.Base(filePath))
-		if err == nil && matched {
+		if matchPathspec(expanded, name) {
 			return true
 		}
 	}
 	return false
 }
 
-// discoverPattern finds proto files matching a single pattern using git ls-files.
-func discoverPattern(projectDir, pattern string) ([]string, error) {
-	cmd := exec.Command("git", "ls-files", pattern)
+// matchPathspec matches a slash-separated name against a glob pattern where
+// "*" and "?" also match "/" and a "**/" element matches zero or more
+// directories. Character classes use path.Match syntax, with "[!...]" as an
+// alias for "[^...]".
+func matchPathspec(pattern, name string) bool {
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/') &&
+				matchPathspec(pattern[i+3:], name) {
+				return true
+			}
+			rest := strings.TrimLeft(pattern[i:], "*")
+			for j := 0; j <= len(name); j++ {
+				if matchPathspec(rest, name[j:]) {
+					return true
+				}
+			}
+			return false
+		case '?', '[':
+			if name == "" {
+				return false
+			}
+			_, size := utf8.DecodeRuneInString(name)
+			if c == '[' {
+				end := strings.IndexByte(pattern[i+1:], ']')
+				if end < 0 {
+					return false
+				}
+				class := pattern[i : i+end+2]
+				if strings.HasPrefix(class, "[!") {
+					class = "[^" + class[2:]
+				}
+				if ok, err := path.Match(class, name[:size]); err != nil || !ok {
+					return false
+				}
+				i += end + 1
+			}
+			name = name[size:]
+		default:
+			if c == '\\' && i+1 < len(pattern) {
+				i++
+				c = pattern[i]
+			}
+			if name == "" || name[0] != c {
+				return false
+			}
+			name = name[1:]
+		}
+	}
+	return name == ""
+}
+
+// gitTrackedFiles returns the set of files tracked by git relative to the
+// project directory. Returns nil if the directory is not a git checkout.
+func gitTrackedFiles(projectDir string) map[string]struct{} {
+	cmd := exec.Command("git", "ls-files", "-z")
 	cmd.Dir = projectDir
 
-	var stdout, stderr bytes.Buffer
+	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
 	if err := cmd.Run(); err != nil {
-		// If git ls-files fails, fall back to filepath.Glob
-		return filepath.Glob(filepath.Join(projectDir, pattern))
+		// Not a git checkout or git is not installed.
+		return nil
 	}
 
-	var files []string
-	scanner := bufio.NewScanner(&stdout)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" && strings.HasSuffix(line, ".proto") {
-			files = append(files, line)
+	tracked := make(map[string]struct{})
+	for _, f := range bytes.Split(stdout.Bytes(), []byte{0}) {
+		if len(f) != 0 {
+			tracked[string(f)] = struct{}{}
 		}
 	}
-
-	return files, scanner.Err()
+	return tracked
 }
 
 // GetGoModule reads the module path from go.mod in the given directory.
@@ -98,44 +192,109 @@ func GetGoModule(projectDir string) (string, error) {
 	return modFile.Module.Mod.Path, nil
 }
 
-// GetGeneratedFiles returns the expected generated file paths for a proto file.
-func GetGeneratedFiles(protoFile, projectDir, modulePath string, hasGo, hasTS bool) []string {
-	protoDir := filepath.Dir(protoFile)
-	baseName := strings.TrimSuffix(filepath.Base(protoFile), ".proto")
-
-	var files []string
+// generatedFileKind is a file written by a built-in generator for each proto
+// file.
+type generatedFileKind struct {
+	// suffix is appended to the proto file base name.
+	suffix string
+	// lang is the language of the file.
+	lang Language
+	// rpc is the RPC library of the file, empty for messages.
+	rpc RPCLibrary
+}
 
-	// C++ files are always generated
-	files = append(
-		files,
-		filepath.Join("vendor", modulePath, protoDir, baseName+".pb.cc"),
-		filepath.Join("vendor", modulePath, protoDir, baseName+".pb.h"),
-	)
+// generatedFileKinds are the outputs of the built-in generators, longest
+// suffixes first.
+var generatedFileKinds = []generatedFileKind{
+	{"_srpc.pb.go", LanguageGo, RPCLibraryStarpc},
+	{"_srpc.pb.ts", LanguageTypeScript, RPCLibraryStarpc},
+	{"_srpc.pb.hpp", LanguageCpp, RPCLibraryStarpc},
+	{"_srpc.pb.cpp", LanguageCpp, RPCLibraryStarpc},
+	{"_srpc.pb.rs", LanguageRust, RPCLibraryStarpc},
+	{"_srpc.pyi", LanguagePython, RPCLibraryStarpcPython},
+	{"_srpc.py", LanguagePython, RPCLibraryStarpcPython},
+	{".pb.go", LanguageGo, ""},
+	{".pb.ts", LanguageTypeScript, ""},
+	{".pb.cc", LanguageCpp, ""},
+	{".pb.h", LanguageCpp, ""},
+	{".pb.rs", LanguageRust, ""},
+	{"_pb2.pyi", LanguagePython, ""},
+	{"_pb2.py", LanguagePython, ""},
+	{".cs", LanguageCSharp, ""},
+}
 
-	if hasGo {
-		files = append(
-			files,
-			filepath.Join("vendor", modulePath, protoDir, baseName+".pb.go"),
-			filepath.Join("vendor", modulePath, protoDir, baseName+"_srpc.pb.go"),
-		)
+// path returns the project-relative path of the file of this kind generated
+// for a proto file. C# files are written to the project root and named in
+// PascalCase, the others next to the proto file.
+func (k generatedFileKind) path(protoFile string) string {
+	baseName := strings.TrimSuffix(filepath.Base(protoFile), ".proto")
+	if k.lang == LanguageCSharp {
+		return csharpFileName(baseName) + k.suffix
 	}
+	return filepath.Join(filepath.Dir(protoFile), baseName+k.suffix)
+}
 
-	if hasTS {
-		files = append(
-			files,
-			filepath.Join("vendor", modulePath, protoDir, baseName+".pb.ts"),
-			filepath.Join("vendor", modulePath, protoDir, baseName+"_srpc.pb.ts"),
-		)
+// GetGeneratedFiles returns the expected generated file paths for a proto file.
+func GetGeneratedFiles(protoFile, projectDir, modulePath string, hasGo, hasTS bool) []string {
+	var files []string
+	for _, kind := range generatedFileKinds {
+		// C++ files are always generated
+		if (kind.lang == LanguageCpp && kind.rpc == "") ||
+			(hasGo && kind.lang == LanguageGo) ||
+			(hasTS && kind.lang == LanguageTypeScript) {
+			files = append(files, filepath.Join("vendor", modulePath, kind.path(protoFile)))
+		}
 	}
-
 	return files
 }
 
 // FindGeneratedFilesForProto finds actual enabled outputs for a proto file.
 func FindGeneratedFilesForProto(protoFile, projectDir, vendorDir, modulePath string, langs Languages, rpcs RPCLibraries) ([]string, error) {
-	protoDir := filepath.Dir(protoFile)
-	baseName := strings.TrimSuffix(filepath.Base(protoFile), ".proto")
+	// Deduplicate by resolving to real paths. Prefer project-local paths over
+	// their vendor-symlink aliases.
+	seen := make(map[string]string)
+	for _, kind := range generatedFileKinds {
+		if !langs.Has(kind.lang) || (kind.rpc != "" && !rpcs.Has(kind.rpc)) {
+			continue
+		}
+		rel := kind.path(protoFile)
+		candidates := []string{filepath.Join(projectDir, rel)}
+		if kind.lang != LanguageCSharp {
+			candidates = append(candidates, filepath.Join(vendorDir, modulePath, rel))
+		}
+		for _, candidate := range candidates {
+			if _, err := os.Lstat(candidate); err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+			realPath, err := filepath.EvalSymlinks(candidate)
+			if err != nil {
+				realPath = candidate
+			}
+			if _, exists := seen[realPath]; exists {
+				continue
+			}
+			rel, err := filepath.Rel(projectDir, candidate)
+			if err != nil {
+				rel = candidate
+			}
+			seen[realPath] = rel
+		}
+	}
+
+	relPaths := make([]string, 0, len(seen))
+	for _, rel := range seen {
+		relPaths = append(relPaths, rel)
+	}
+	slices.Sort(relPaths)
+	return relPaths, nil
+}
 
+// csharpFileName returns the base name of the C# file protoc generates for a
+// proto file base name.
+func csharpFileName(baseName string) string {
 	var csharpBase strings.Builder
 	capNext := true
 	for i := range len(baseName) {
@@ -162,74 +321,5 @@ func FindGeneratedFilesForProto(protoFile, projectDir, vendorDir, modulePath str
 		strings.HasPrefix(baseName, "_") {
 		csharpFile = "_" + csharpFile
 	}
-
-	var relativePatterns []string
-	if langs.Has(LanguageCpp) {
-		relativePatterns = append(relativePatterns, baseName+".pb.cc", baseName+".pb.h")
-	}
-	if langs.Has(LanguageGo) {
-		relativePatterns = append(relativePatterns, baseName+"*.pb.go")
-	}
-	if langs.Has(LanguageTypeScript) {
-		relativePatterns = append(relativePatterns, baseName+"*.pb.ts")
-	}
-	if langs.Has(LanguageRust) {
-		relativePatterns = append(relativePatterns, baseName+"*.pb.rs")
-	}
-	if langs.Has(LanguagePython) {
-		relativePatterns = append(relativePatterns, baseName+"_pb2.py", baseName+"_pb2.pyi")
-		if rpcs.Has(RPCLibraryStarpcPython) {
-			relativePatterns = append(relativePatterns, baseName+"_srpc.py", baseName+"_srpc.pyi")
-		}
-	}
-
-	searches := []struct {
-		dir      string
-		patterns []string
-	}{
-		{dir: filepath.Join(projectDir, protoDir), patterns: relativePatterns},
-		{dir: filepath.Join(vendorDir, modulePath, protoDir), patterns: relativePatterns},
-	}
-	if langs.Has(LanguageCSharp) {
-		searches = append(searches, struct {
-			dir      string
-			patterns []string
-		}{
-			dir:      projectDir,
-			patterns: []string{csharpFile + ".cs"},
-		})
-	}
-
-	// Deduplicate by resolving to real paths. Prefer project-local paths over
-	// their vendor-symlink aliases.
-	seen := make(map[string]string)
-	for _, search := range searches {
-		for _, pattern := range search.patterns {
-			matches, err := filepath.Glob(filepath.Join(search.dir, pattern))
-			if err != nil {
-				return nil, err
-			}
-			for _, match := range matches {
-				realPath, err := filepath.EvalSymlinks(match)
-				if err != nil {
-					realPath = match
-				}
-				if _, exists := seen[realPath]; exists {
-					continue
-				}
-				rel, err := filepath.Rel(projectDir, match)
-				if err != nil {
-					rel = match
-				}
-				seen[realPath] = rel
-			}
-		}
-	}
-
-	relPaths := make([]string, 0, len(seen))
-	for _, rel := range seen {
-		relPaths = append(relPaths, rel)
-	}
-	slices.Sort(relPaths)
-	return relPaths, nil
+	return csharpFile
 }
@@ -2,11 +2,168 @@ package protogen
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"slices"
 	"testing"
 )
 
+func TestDiscoverProtoFiles(t *testing.T) {
+	dir, _ := writeProtoTree(t, map[string]string{
+		".gitignore":                   "/build/\n*_tmp.proto\n",
+		"a.proto":                      "",
+		"api/v1/api.proto":             "",
+		"api/v1/scratch_tmp.proto":     "",
+		"api/internal/.ignore":         "*\n!keep.proto\n",
+		"api/internal/keep.proto":      "",
+		"api/internal/drop.proto":      "",
+		"build/out.proto":              "",
+		"vendor/example.com/x/x.proto": "",
+		"node_modules/pkg/pkg.proto":   "",
+		".tools/include/tool.proto":    "",
+		"docs/notes.txt":               "",
+		"docs/nested/build/keep.proto": "",
+		"nested/go.mod":                "module example.com/nested\n",
+		"nested/nested.proto":          "",
+	})
+
+	// Discovery works without a git checkout.
+	got, err := DiscoverProtoFiles(dir, []string{"./*.proto"}, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a.proto", "api/internal/keep.proto", "api/v1/api.proto", "docs/nested/build/keep.proto"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("discovered files: want %v, got %v", want, got)
+	}
+
+	got, err = DiscoverProtoFiles(dir, []string{"api/**/*.proto", "a.proto"}, []string{"keep.proto"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.proto", "api/v1/api.proto"}; !slices.Equal(got, want) {
+		t.Fatalf("discovered files: want %v, got %v", want, got)
+	}
+
+	// Untracked files are only skipped in a git checkout when requested.
+	cmd := exec.Command("git", "init", "-q")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	got, err = DiscoverProtoFiles(dir, []string{"*.proto"}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("untracked files discovered: %v", got)
+	}
+	commitAll(t, dir)
+	if err := os.Remove(filepath.Join(dir, "a.proto")); err != nil {
+		t.Fatal(err)
+	}
+	got, err = DiscoverProtoFiles(dir, []string{"*.proto"}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := want[1:]; !slices.Equal(got, want) {
+		t.Fatalf("tracked files: want %v, got %v", want, got)
+	}
+}
+
+func TestDiscoverProtoFilesInRepoSubdirectory(t *testing.T) {
+	dir, _ := writeProtoTree(t, map[string]string{
+		".git/info/exclude":        "project/local.proto\n",
+		".gitignore":               "project/gen/\n*_draft.proto\n",
+		"project/.gitignore":       "!keep_draft.proto\n",
+		"project/a.proto":          "",
+		"project/gen/g.proto":      "",
+		"project/x_draft.proto":    "",
+		"project/keep_draft.proto": "",
+		"project/local.proto":      "",
+	})
+
+	got, err := DiscoverProtoFiles(filepath.Join(dir, "project"), []string{"./*.proto"}, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.proto", "keep_draft.proto"}; !slices.Equal(got, want) {
+		t.Fatalf("discovered files: want %v, got %v", want, got)
+	}
+}
+
+func TestDiscoverProtoFilesPatterns(t *testing.T) {
+	dir, _ := writeProtoTree(t, map[string]string{
+		"root.proto":                         "",
+		"api/a.proto":                        "",
+		"api/v1/b.proto":                     "",
+		"api/v1/testdata/fixture.proto":      "",
+		"api/v1/internal/secret.proto":       "",
+		"api/v1/internal/public.proto":       "",
+		"proto/c.proto":                      "",
+		"proto/deep/nested/d.proto":          "",
+		"proto/deep/nested/testdata/e.proto": "",
+		"other/f.proto":                      "",
+	})
+	for _, tc := range []struct {
+		name     string
+		patterns []string
+		exclude  []string
+		want     []string
+	}{{
+		name:     "recursive",
+		patterns: []string{"api/**/*.proto"},
+		want:     []string{"api/a.proto", "api/v1/b.proto", "api/v1/internal/public.proto", "api/v1/internal/secret.proto", "api/v1/testdata/fixture.proto"},
+	}, {
+		// "*" matches "/" like in git pathspecs.
+		name:     "star crosses directories",
+		patterns: []string{"./api/*.proto"},
+		want:     []string{"api/a.proto", "api/v1/b.proto", "api/v1/internal/public.proto", "api/v1/internal/secret.proto", "api/v1/testdata/fixture.proto"},
+	}, {
+		name:     "question mark crosses directories",
+		patterns: []string{"api/v1?b.proto"},
+		want:     []string{"api/v1/b.proto"},
+	}, {
+		name:     "patterns with a slash are anchored",
+		patterns: []string{"v1/*.proto", "deep/**/*.proto"},
+		want:     nil,
+	}, {
+		name:     "name without a slash at any depth",
+		patterns: []string{"b.proto", "proto/[!d].proto"},
+		want:     []string{"api/v1/b.proto", "proto/c.proto"},
+	}, {
+		name:     "negated target",
+		patterns: []string{"**/*.proto", "!**/testdata/**"},
+		want:     []string{"api/a.proto", "api/v1/b.proto", "api/v1/internal/public.proto", "api/v1/internal/secret.proto", "other/f.proto", "proto/c.proto", "proto/deep/nested/d.proto", "root.proto"},
+	}, {
+		name:     "braces",
+		patterns: []string{"{api,proto/deep}/**/{a,b,d}.proto"},
+		want:     []string{"api/a.proto", "api/v1/b.proto", "proto/deep/nested/d.proto"},
+	}, {
+		name:     "exclude with re-include",
+		patterns: []string{"./*.proto"},
+		exclude:  []string{"api/**", "!api/**/public.proto", "testdata/*.proto", "{other,proto/deep}/**"},
+		want:     []string{"api/v1/internal/public.proto", "proto/c.proto", "root.proto"},
+	}, {
+		name:     "exclude by name at any depth",
+		patterns: []string{"./*.proto"},
+		exclude:  []string{"testdata", "*.proto", "!{c,d}.proto"},
+		want:     []string{"proto/c.proto", "proto/deep/nested/d.proto"},
+	}} {
+		got, err := DiscoverProtoFiles(dir, tc.patterns, tc.exclude, true)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: want %v, got %v", tc.name, tc.want, got)
+		}
+	}
+
+	if _, err := DiscoverProtoFiles(dir, []string{"api/[.proto"}, nil, true); err == nil {
+		t.Fatal("expected an error for an invalid pattern")
+	}
+}
+
 func TestFindGeneratedFilesForProtoBuiltInLanguages(t *testing.T) {
 	projectDir := t.TempDir()
 	vendorDir := filepath.Join(projectDir, "vendor")
@@ -111,3 +268,23 @@ func TestFindGeneratedFilesForProtoStarpcPythonUnselected(t *testing.T) {
 		t.Fatalf("unexpected outputs without starpc-python: %v", got)
 	}
 }
+
+func TestFindGeneratedFilesForProtoClaimsCppStarpcOutputs(t *testing.T) {
+	projectDir := t.TempDir()
+	for _, name := range []string{"echo.pb.cc", "echo.pb.h", "echo_srpc.pb.hpp", "echo_srpc.pb.cpp", "echo_extra.pb.go"} {
+		if err := os.WriteFile(filepath.Join(projectDir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := FindGeneratedFilesForProto(
+		"echo.proto", projectDir, projectDir+"/vendor", "example.com/project",
+		Languages{LanguageCpp: {}, LanguageGo: {}}, RPCLibraries{RPCLibraryStarpc: {}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"echo.pb.cc", "echo.pb.h", "echo_srpc.pb.cpp", "echo_srpc.pb.hpp"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
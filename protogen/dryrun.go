@@ -0,0 +1,209 @@
+package protogen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Plan is the work Generate would do, computed without running protoc or
+// writing to the project or the cache.
+type Plan struct {
+	// ProtoFiles are the discovered proto files.
+	ProtoFiles []string `json:"protoFiles"`
+	// Packages are the package directories that would be regenerated.
+	Packages []*PlannedPackage `json:"packages"`
+	// Skipped are the package directories that are up to date.
+	Skipped []string `json:"skipped"`
+	// Commands are the protoc command lines that would run.
+	Commands [][]string `json:"commands"`
+	// DescriptorSets are the descriptor set files that would be written.
+	DescriptorSets []string `json:"descriptorSets,omitempty"`
+}
+
+// PlannedPackage is a package directory that would be regenerated.
+type PlannedPackage struct {
+	// Dir is the project-relative package directory.
+	Dir string `json:"dir"`
+	// Profile is the profile the package is generated with.
+	Profile string `json:"profile,omitempty"`
+	// Reason is why the package would be regenerated.
+	Reason StaleReason `json:"reason"`
+	// Details describe what changed.
+	Details []string `json:"details,omitempty"`
+	// Outputs are the expected generated files for each proto file.
+	Outputs []*PlannedOutputs `json:"outputs"`
+}
+
+// PlannedOutputs are the files expected to be generated for a proto file.
+type PlannedOutputs struct {
+	// ProtoFile is the project-relative proto file.
+	ProtoFile string `json:"protoFile"`
+	// Files are the project-relative expected generated files. Custom plugin
+	// outputs are listed as their configured globs.
+	Files []string `json:"files"`
+}
+
+// Plan runs discovery and evaluates the cache, returning the packages Generate
+// would regenerate, the protoc commands it would run and the files it expects
+// to generate.
+func (g *Generator) Plan() (*Plan, error) {
+	protoFiles, err := DiscoverProtoFiles(g.ProjectDir, g.Config.Targets, g.Config.Exclude, g.Config.IncludeUntracked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover proto files: %w", err)
+	}
+	plan := &Plan{ProtoFiles: protoFiles}
+	if len(protoFiles) == 0 {
+		return plan, nil
+	}
+
+	toolVersions := g.getToolVersions()
+	importGraph := g.newProtoImportGraph()
+	groups, packages := g.evaluatePackages(protoFiles, toolVersions, importGraph)
+	for _, pkg := range packages {
+		if pkg.reason == StaleReasonNone {
+			plan.Skipped = append(plan.Skipped, pkg.dir)
+			continue
+		}
+		pkg.group.staleDirs = append(pkg.group.staleDirs, pkg.dir)
+
+		planned := &PlannedPackage{
+			Dir:     pkg.dir,
+			Profile: pkg.group.profile,
+			Reason:  pkg.reason,
+			Details: g.explainPackage(pkg, toolVersions).Details(),
+		}
+		for _, f := range pkg.files {
+			data, err := os.ReadFile(filepath.Join(g.ProjectDir, f))
+			if err != nil {
+				return nil, err
+			}
+			hasServices, err := protoHasServices(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f, err)
+			}
+			planned.Outputs = append(planned.Outputs, &PlannedOutputs{
+				ProtoFile: f,
+				Files:     pkg.group.plugins.ExpectedGeneratedFiles(f, hasServices),
+			})
+		}
+		plan.Packages = append(plan.Packages, planned)
+	}
+
+	// Mirror the protoc invocations of runProtoc.
+	for _, group := range groups {
+		var groupFiles []string
+		for _, dir := range group.staleDirs {
+			for _, pkg := range packages {
+				if pkg.dir == dir {
+					groupFiles = append(groupFiles, pkg.files...)
+				}
+			}
+		}
+		if len(groupFiles) == 0 {
+			continue
+		}
+		shards := shardProtoFiles(groupFiles)
+		if min(g.Config.GetJobs(), len(shards)) <= 1 {
+			shards = [][]string{groupFiles}
+		}
+		for _, shard := range shards {
+			plan.Commands = append(plan.Commands, g.protocCommand(group.plugins, shard))
+		}
+	}
+
+	outputs := make([]string, len(g.Config.DescriptorSets))
+	for i, p := range g.Config.DescriptorSets {
+		outputs[i] = filepath.Clean(p)
+	}
+	if len(outputs) != 0 && g.Cache.NeedsDescriptorSet(protoFiles, outputs, g.ProjectDir, toolVersions, g.Config.Force, importGraph.Hash) {
+		plan.DescriptorSets = outputs
+	}
+	return plan, nil
+}
+
+// ExpectedGeneratedFiles returns the project-relative files the enabled
+// generators are expected to write for a proto file, sorted. RPC stubs are
+// only expected if the file declares services. Custom plugin outputs are
+// returned as their configured globs.
+func (p *Plugins) ExpectedGeneratedFiles(protoFile string, hasServices bool) []string {
+	var files []string
+	for _, kind := range generatedFileKinds {
+		if (kind.rpc == "" || hasServices) && p.writesKind(kind) {
+			files = append(files, kind.path(protoFile))
+		}
+	}
+
+	protoDir := filepath.Dir(protoFile)
+	baseName := strings.TrimSuffix(filepath.Base(protoFile), ".proto")
+	replacer := strings.NewReplacer("{dir}", filepath.ToSlash(protoDir), "{name}", baseName)
+	for _, c := range p.Custom {
+		for _, output := range c.Outputs {
+			pattern := filepath.Clean(filepath.FromSlash(replacer.Replace(output)))
+			if !slices.Contains(files, pattern) {
+				files = append(files, pattern)
+			}
+		}
+	}
+	slices.Sort(files)
+	return files
+}
+
+// writesKind returns true if the enabled plugins write files of the kind.
+// C++, Python and C# messages are generated by protoc itself.
+func (p *Plugins) writesKind(kind generatedFileKind) bool {
+	if !p.Languages.Has(kind.lang) {
+		return false
+	}
+	var plugin *Plugin
+	switch {
+	case kind.rpc == RPCLibraryStarpcPython:
+		plugin = p.StarpcPython
+	case kind.rpc == RPCLibraryStarpc && kind.lang == LanguageGo:
+		plugin = p.GoStarpc
+	case kind.rpc == RPCLibraryStarpc && kind.lang == LanguageTypeScript:
+		plugin = p.ESStarpc
+	case kind.rpc == RPCLibraryStarpc && kind.lang == LanguageCpp:
+		plugin = p.CppStarpc
+	case kind.rpc == RPCLibraryStarpc && kind.lang == LanguageRust:
+		plugin = p.RustStarpc
+	case kind.lang == LanguageGo:
+		plugin = p.GoLite
+	case kind.lang == LanguageTypeScript:
+		plugin = p.ESLite
+	case kind.lang == LanguageRust:
+		plugin = p.RustProst
+	default:
+		return true
+	}
+	return plugin != nil
+}
+
+// protoHasServices returns true if the proto source declares a service.
+func protoHasServices(src []byte) (bool, error) {
+	tokens, err := tokenizeProto(string(src))
+	if err != nil {
+		return false, err
+	}
+	depth := 0
+	stmtStart := true
+	for i := range tokens {
+		tok := &tokens[i]
+		if tok.kind == protoTokenComment {
+			continue
+		}
+		if depth == 0 && stmtStart && tok.is("service") {
+			return true, nil
+		}
+		switch {
+		case tok.is("{"):
+			depth++
+		case tok.is("}"):
+			depth--
+		}
+		stmtStart = tok.is(";") || tok.is("{") || tok.is("}")
+	}
+	return false, nil
+}
@@ -0,0 +1,81 @@
+package protogen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"a/a.proto": "syntax = \"proto3\";\npackage a;\nmessage A {}\nservice Svc { rpc Get(A) returns (A); }\n",
+		"b/b.proto": "syntax = \"proto3\";\npackage b;\nmessage B {}\n",
+	})
+	g.Plugins.CppStarpc = &Plugin{Name: "starpc-cpp", OutFlag: "starpc-cpp_out"}
+
+	plan, err := g.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Packages) != 2 || len(plan.Skipped) != 0 || len(plan.Commands) != 1 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	a := plan.Packages[0]
+	if a.Dir != "a" || a.Reason != StaleReasonNewPackage || len(a.Outputs) != 1 {
+		t.Fatalf("unexpected planned package: %+v", a)
+	}
+	want := []string{"a/a.pb.cc", "a/a.pb.h", "a/a_srpc.pb.cpp", "a/a_srpc.pb.hpp"}
+	if !slices.Equal(a.Outputs[0].Files, want) {
+		t.Fatalf("unexpected outputs for a/a.proto: %v", a.Outputs[0].Files)
+	}
+	if files := plan.Packages[1].Outputs[0].Files; !slices.Equal(files, []string{"b/b.pb.cc", "b/b.pb.h"}) {
+		t.Fatalf("unexpected outputs for b/b.proto: %v", files)
+	}
+	args := plan.Commands[0]
+	if args[0] != "protoc" || !slices.Contains(args, "--starpc-cpp_out="+g.OutDir) ||
+		args[len(args)-1] != filepath.Join(g.VendorDir, g.ModulePath, "b", "b.proto") {
+		t.Fatalf("unexpected protoc command: %v", args)
+	}
+
+	// Planning writes nothing.
+	if _, err := os.Stat(filepath.Join(g.ProjectDir, "a", "a.pb.cc")); !os.IsNotExist(err) {
+		t.Fatal("plan generated files")
+	}
+	cacheFile, err := g.Config.GetCacheFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cacheFile); !os.IsNotExist(err) {
+		t.Fatal("plan wrote the cache")
+	}
+
+	g.Plugins.CppStarpc = nil
+	if err := g.Generate(context.Background()); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	plan, err = g.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Packages) != 0 || len(plan.Commands) != 0 || !slices.Equal(plan.Skipped, []string{"a", "b"}) {
+		t.Fatalf("unexpected plan after generation: %+v", plan)
+	}
+}
+
+func TestProtoHasServices(t *testing.T) {
+	for src, want := range map[string]bool{
+		"syntax = \"proto3\";\nservice Svc {}\n":                                            true,
+		"// service Svc {}\nmessage service { string service = 1; }\n":                      false,
+		"message A { option (x) = { service: 1 }; }\nservice B { rpc C(A) returns (A); }\n": true,
+	} {
+		got, err := protoHasServices([]byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("protoHasServices(%q) = %v, want %v", src, got, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package protogen
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventType identifies the kind of a generator Event.
+type EventType string
+
+const (
+	// EventDiscovery reports the proto files found by discovery.
+	EventDiscovery EventType = "discovery"
+	// EventPackage reports the cache decision for a package directory.
+	EventPackage EventType = "package"
+	// EventProtoc reports a finished protoc invocation.
+	EventProtoc EventType = "protoc"
+	// EventPlugin reports a finished plugin invocation within protoc.
+	EventPlugin EventType = "plugin"
+	// EventPostProcess reports the post-processing of a proto file's outputs.
+	EventPostProcess EventType = "postprocess"
+	// EventFormat reports the formatting of the generated files.
+	EventFormat EventType = "format"
+	// EventDescriptorSet reports a written descriptor set file.
+	EventDescriptorSet EventType = "descriptor-set"
+	// EventRemove reports a removed stale generated file.
+	EventRemove EventType = "remove"
+	// EventSummary reports the totals at the end of generation.
+	EventSummary EventType = "summary"
+)
+
+// Event is a structured progress event emitted during generation.
+// Only the fields relevant to the event type are set.
+type Event struct {
+	// Type is the kind of event.
+	Type EventType `json:"type"`
+	// Dir is the project-relative package directory.
+	Dir string `json:"dir,omitempty"`
+	// Package is the package cache key.
+	Package string `json:"package,omitempty"`
+	// Profile is the profile the package is generated with.
+	Profile string `json:"profile,omitempty"`
+	// Action is "generate" or "skip" for package events.
+	Action string `json:"action,omitempty"`
+	// Reason is why a package is regenerated.
+	Reason StaleReason `json:"reason,omitempty"`
+	// Details describe what changed for a regenerated package when the
+	// generator explains its decisions.
+	Details []string `json:"details,omitempty"`
+	// Plugin is the plugin program name.
+	Plugin string `json:"plugin,omitempty"`
+	// Path is the project-relative file the event refers to.
+	Path string `json:"path,omitempty"`
+	// Files are the project-relative files the event refers to.
+	Files []string `json:"files,omitempty"`
+	// Args are the protoc arguments.
+	Args []string `json:"args,omitempty"`
+	// DurationMs is the duration of the step in milliseconds.
+	DurationMs float64 `json:"durationMs,omitempty"`
+	// Error is the error message if the step failed.
+	Error string `json:"error,omitempty"`
+	// Diagnostics are the parsed protoc errors if protoc failed.
+	Diagnostics []*ProtocDiagnostic `json:"diagnostics,omitempty"`
+	// Summary contains the totals for summary events.
+	Summary *GenerateSummary `json:"summary,omitempty"`
+}
+
+// GenerateSummary contains the totals reported at the end of generation.
+type GenerateSummary struct {
+	// ProtoFiles is the number of discovered proto files.
+	ProtoFiles int `json:"protoFiles"`
+	// Packages is the number of package directories.
+	Packages int `json:"packages"`
+	// Generated is the number of regenerated package directories.
+	Generated int `json:"generated"`
+	// Skipped is the number of up to date package directories.
+	Skipped int `json:"skipped"`
+	// Removed is the number of removed stale generated files.
+	Removed int `json:"removed"`
+}
+
+// NewJSONEventWriter returns an event handler that writes each event to w as
+// one line of JSON. The handler is safe for concurrent use.
+func NewJSONEventWriter(w io.Writer) func(*Event) {
+	var mtx sync.Mutex
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return func(ev *Event) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		_ = enc.Encode(ev)
+	}
+}
+
+// emit sends an event to the event handler, if any.
+func (g *Generator) emit(ev *Event) {
+	if g.Events != nil {
+		g.Events(ev)
+	}
+}
+
+// durationMs returns the time since start in milliseconds.
+func durationMs(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
+
+// errorString returns the error message, or an empty string if err is nil.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
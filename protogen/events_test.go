@@ -0,0 +1,87 @@
+package protogen
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// decodeEvents parses the JSON event lines written by NewJSONEventWriter.
+func decodeEvents(t *testing.T, data []byte) []*Event {
+	t.Helper()
+	var events []*Event
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var ev Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("invalid event line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, &ev)
+	}
+	return events
+}
+
+func TestGenerateEmitsEvents(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"a/a.proto": "syntax = \"proto3\";\npackage a;\nmessage A {}\n",
+		"b/b.proto": "syntax = \"proto3\";\npackage b;\nmessage B {}\n",
+	})
+	var out bytes.Buffer
+	g.Events = NewJSONEventWriter(&out)
+	ctx := context.Background()
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	events := decodeEvents(t, out.Bytes())
+	if len(events) == 0 || events[0].Type != EventDiscovery || len(events[0].Files) != 2 {
+		t.Fatalf("expected discovery event first, got %s", out.String())
+	}
+	last := events[len(events)-1]
+	if last.Type != EventSummary || last.Summary == nil ||
+		*last.Summary != (GenerateSummary{ProtoFiles: 2, Packages: 2, Generated: 2}) {
+		t.Fatalf("unexpected summary event: %s", out.String())
+	}
+	counts := make(map[EventType]int)
+	for _, ev := range events {
+		counts[ev.Type]++
+		switch ev.Type {
+		case EventPackage:
+			if ev.Action != "generate" || ev.Reason != StaleReasonNewPackage {
+				t.Fatalf("unexpected package event: %+v", ev)
+			}
+		case EventProtoc:
+			if len(ev.Args) == 0 || ev.Error != "" {
+				t.Fatalf("unexpected protoc event: %+v", ev)
+			}
+		}
+	}
+	if counts[EventPackage] != 2 || counts[EventProtoc] != 1 || counts[EventPostProcess] != 2 {
+		t.Fatalf("unexpected event counts %v:\n%s", counts, out.String())
+	}
+
+	// Editing one package regenerates it for the content change only.
+	if err := os.WriteFile(filepath.Join(g.ProjectDir, "a", "a.proto"), []byte("syntax = \"proto3\";\npackage a;\nmessage A { int32 id = 1; }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	actions := make(map[string]*Event)
+	for _, ev := range decodeEvents(t, out.Bytes()) {
+		if ev.Type == EventPackage {
+			actions[ev.Dir] = ev
+		}
+	}
+	if ev := actions["a"]; ev == nil || ev.Action != "generate" || ev.Reason != StaleReasonContent {
+		t.Fatalf("unexpected event for a: %+v", ev)
+	}
+	if ev := actions["b"]; ev == nil || ev.Action != "skip" || ev.Reason != StaleReasonNone {
+		t.Fatalf("unexpected event for b: %+v", ev)
+	}
+}
@@ -0,0 +1,153 @@
+package protogen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ValueChange is a changed value. Old is empty for added values and New is
+// empty for removed values.
+type ValueChange struct {
+	// Old is the cached value.
+	Old string `json:"old,omitempty"`
+	// New is the current value.
+	New string `json:"new,omitempty"`
+}
+
+// Regeneration explains why a cached package needs regeneration.
+type Regeneration struct {
+	// Reason is the first condition that triggered.
+	Reason StaleReason `json:"reason"`
+	// ToolVersions are the changed tool version entries.
+	ToolVersions []ValueChange `json:"toolVersions,omitempty"`
+	// Flags are the changed protoc flags, relativized to the module root.
+	Flags []ValueChange `json:"flags,omitempty"`
+	// AddedFiles are the proto files that are new in the package.
+	AddedFiles []string `json:"addedFiles,omitempty"`
+	// RemovedFiles are the proto files that were removed from the package.
+	RemovedFiles []string `json:"removedFiles,omitempty"`
+	// ChangedFiles are the proto files whose content changed.
+	ChangedFiles []string `json:"changedFiles,omitempty"`
+	// ChangedImports are the imported files whose content changed.
+	ChangedImports []string `json:"changedImports,omitempty"`
+}
+
+// RegenerationReason explains why a cached package needs regeneration with
+// the given protoc flags. rootDir is the module root the flags are relativized
+// to. Returns nil if the package is up to date. The import closure is not
+// checked, see ChangedImports.
+func (c *Cache) RegenerationReason(packageKey string, protoFiles []string, projectDir string, flags []string, rootDir string, toolVersions string, force bool) *Regeneration {
+	reason := c.staleReason(packageKey, protoFiles, projectDir, HashProtocFlags(flags, rootDir), toolVersions, force)
+	if reason == StaleReasonNone {
+		return nil
+	}
+
+	r := &Regeneration{Reason: reason}
+	info := c.Packages[packageKey]
+	switch reason {
+	case StaleReasonToolVersions:
+		r.ToolVersions = diffValues(splitToolVersions(c.ToolVersions), splitToolVersions(toolVersions))
+	case StaleReasonFlags:
+		r.Flags = diffValues(info.ProtocFlags, relativizeProtocFlags(flags, rootDir))
+	case StaleReasonProtoFiles:
+		for _, f := range protoFiles {
+			if !slices.Contains(info.ProtoFiles, f) {
+				r.AddedFiles = append(r.AddedFiles, f)
+			}
+		}
+		for _, f := range info.ProtoFiles {
+			if !slices.Contains(protoFiles, f) {
+				r.RemovedFiles = append(r.RemovedFiles, f)
+			}
+		}
+	case StaleReasonContent:
+		hashes, err := hashEachProtoFile(protoFiles, projectDir)
+		if err != nil {
+			break
+		}
+		for _, f := range protoFiles {
+			if info.FileHashes[f] != hashes[f] {
+				r.ChangedFiles = append(r.ChangedFiles, f)
+			}
+		}
+	}
+	return r
+}
+
+// Details returns one human-readable line per change.
+func (r *Regeneration) Details() []string {
+	var lines []string
+	for _, ch := range r.ToolVersions {
+		lines = append(lines, "tool version "+describeChange(ch))
+	}
+	for _, ch := range r.Flags {
+		lines = append(lines, "flag "+describeChange(ch))
+	}
+	for _, f := range r.AddedFiles {
+		lines = append(lines, "proto file added: "+f)
+	}
+	for _, f := range r.RemovedFiles {
+		lines = append(lines, "proto file removed: "+f)
+	}
+	for _, f := range r.ChangedFiles {
+		lines = append(lines, "proto file changed: "+f)
+	}
+	for _, f := range r.ChangedImports {
+		lines = append(lines, "import changed: "+f)
+	}
+	return lines
+}
+
+// describeChange formats a value change for Details.
+func describeChange(ch ValueChange) string {
+	switch {
+	case ch.Old == "":
+		return "added: " + ch.New
+	case ch.New == "":
+		return "removed: " + ch.Old
+	default:
+		return fmt.Sprintf("changed: %s -> %s", ch.Old, ch.New)
+	}
+}
+
+// splitToolVersions splits a tool versions string into its entries.
+func splitToolVersions(versions string) []string {
+	if versions == "" {
+		return nil
+	}
+	return strings.Split(versions, ",")
+}
+
+// diffValues returns the values removed from old and added in next in order.
+// A removed and an added value with the same name before "=" are reported as
+// one change.
+func diffValues(old, next []string) []ValueChange {
+	var changes []ValueChange
+	var added []string
+	for _, v := range next {
+		if !slices.Contains(old, v) {
+			added = append(added, v)
+		}
+	}
+	for _, v := range old {
+		if slices.Contains(next, v) {
+			continue
+		}
+		name, _, _ := strings.Cut(v, "=")
+		i := slices.IndexFunc(added, func(a string) bool {
+			aName, _, _ := strings.Cut(a, "=")
+			return aName == name
+		})
+		if i < 0 {
+			changes = append(changes, ValueChange{Old: v})
+			continue
+		}
+		changes = append(changes, ValueChange{Old: v, New: added[i]})
+		added = slices.Delete(added, i, i+1)
+	}
+	for _, v := range added {
+		changes = append(changes, ValueChange{New: v})
+	}
+	return changes
+}
@@ -0,0 +1,69 @@
+package protogen
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRegenerationReason(t *testing.T) {
+	dir, files := writeProtoTree(t, map[string]string{
+		"foo/a.proto": "syntax = \"proto3\";\npackage foo;\n",
+		"foo/b.proto": "syntax = \"proto3\";\npackage foo;\nmessage B {}\n",
+	})
+	flags := []string{"-I", filepath.Join(dir, "vendor"), "--go-lite_out=" + filepath.Join(dir, "vendor"), "--go-lite_opt=features=marshaler"}
+	tools := "protoc=embedded,starpc=v0.1.0"
+
+	cache := NewCache()
+	cache.ToolVersions = tools
+	if err := cache.UpdatePackage("example/foo", files, nil, dir); err != nil {
+		t.Fatal(err)
+	}
+	cache.SetPackageProtocFlags("example/foo", flags, dir)
+
+	if r := cache.RegenerationReason("example/foo", files, dir, flags, dir, tools, false); r != nil {
+		t.Fatalf("up to date package explained as stale: %+v", r)
+	}
+	if r := cache.RegenerationReason("example/bar", files, dir, flags, dir, tools, false); r == nil || r.Reason != StaleReasonNewPackage {
+		t.Fatalf("unexpected reason for a new package: %+v", r)
+	}
+
+	r := cache.RegenerationReason("example/foo", files, dir, flags, dir, "protoc=embedded,starpc=v0.2.0,uv.lock=abc", false)
+	if r == nil || r.Reason != StaleReasonToolVersions {
+		t.Fatalf("unexpected reason: %+v", r)
+	}
+	want := []string{
+		"tool version changed: starpc=v0.1.0 -> starpc=v0.2.0",
+		"tool version added: uv.lock=abc",
+	}
+	if details := r.Details(); !slices.Equal(details, want) {
+		t.Fatalf("unexpected details: %q", details)
+	}
+
+	next := slices.Clone(flags)
+	next[3] = "--go-lite_opt=features=marshaler+size"
+	r = cache.RegenerationReason("example/foo", files, dir, next, dir, tools, false)
+	if r == nil || r.Reason != StaleReasonFlags {
+		t.Fatalf("unexpected reason: %+v", r)
+	}
+	if details := r.Details(); !slices.Equal(details, []string{"flag changed: --go-lite_opt=features=marshaler -> --go-lite_opt=features=marshaler+size"}) {
+		t.Fatalf("unexpected details: %q", details)
+	}
+
+	r = cache.RegenerationReason("example/foo", files[:1], dir, flags, dir, tools, false)
+	if r == nil || r.Reason != StaleReasonProtoFiles || !slices.Equal(r.RemovedFiles, files[1:]) {
+		t.Fatalf("unexpected explanation: %+v", r)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "foo", "b.proto"), []byte("syntax = \"proto3\";\npackage foo;\nmessage B { int32 id = 1; }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r = cache.RegenerationReason("example/foo", files, dir, flags, dir, tools, false)
+	if r == nil || r.Reason != StaleReasonContent {
+		t.Fatalf("unexpected reason: %+v", r)
+	}
+	if details := r.Details(); !slices.Equal(details, []string{"proto file changed: foo/b.proto"}) {
+		t.Fatalf("unexpected details: %q", details)
+	}
+}
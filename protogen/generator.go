@@ -6,15 +6,20 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
+	"testing/fstest"
 	"time"
 
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
 	protoc "github.com/aperturerobotics/go-protoc-wasi"
 	"github.com/tetratelabs/wazero"
 )
@@ -25,6 +30,9 @@ type Generator struct {
 	Config *Config
 	// Plugins contains the discovered plugins.
 	Plugins *Plugins
+	// ProfilePlugins maps profile names to the plugins discovered with the
+	// profile's overrides applied.
+	ProfilePlugins map[string]*Plugins
 	// Cache is the manifest cache.
 	Cache *Cache
 	// ProjectDir is the resolved project directory.
@@ -33,18 +41,35 @@ type Generator struct {
 	ModuleDir string
 	// ModulePath is the Go module path.
 	ModulePath string
+	// Workspace is the Go workspace containing the module, or nil. Proto
+	// imports from sibling members resolve to their module directories.
+	Workspace *Workspace
 	// VendorDir is the vendor directory.
 	VendorDir string
 	// TsImportBoundaries are module-relative boundaries that trigger @go/ rewrites.
 	TsImportBoundaries []string
+	// GoImportRemaps maps Go import paths to their replacements in generated
+	// Go files, including the built-in protobuf-go-lite remaps.
+	GoImportRemaps map[string]string
 	// OutDir is the output directory (same as VendorDir).
 	OutDir string
 	// Verbose enables verbose output.
 	Verbose bool
+	// Explain prints why each package is regenerated.
+	Explain bool
 	// Stdout is where to write standard output.
 	Stdout io.Writer
 	// Stderr is where to write error output.
 	Stderr io.Writer
+	// Events receives structured progress events if set. It may be called
+	// concurrently while protoc runs with multiple jobs.
+	Events func(*Event)
+
+	// compilationCache keeps compiled WASM modules warm across protoc runs.
+	compilationCache wazero.CompilationCache
+	// moduleCache resolves imports from the Go module cache if the project
+	// does not vendor its dependencies.
+	moduleCache *moduleCache
 }
 
 // NewGenerator creates a new Generator.
@@ -79,27 +104,56 @@ func NewGenerator(cfg *Config) (*Generator, error) {
 		return nil, fmt.Errorf("failed to discover plugins: %w", err)
 	}
 
+	if err := cfg.ValidateProfiles(); err != nil {
+		return nil, err
+	}
+	if err := cfg.ValidateCustomPlugins(); err != nil {
+		return nil, err
+	}
+	if err := cfg.ValidateDescriptorSets(); err != nil {
+		return nil, err
+	}
+	if err := cfg.ValidateGoImportRemaps(); err != nil {
+		return nil, err
+	}
+	profilePlugins := make(map[string]*Plugins, len(cfg.Profiles))
+	for _, profile := range cfg.Profiles {
+		profilePlugins[profile.Name], err = DiscoverPlugins(cfg.ForProfile(profile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover plugins for profile %q: %w", profile.Name, err)
+		}
+	}
+
 	tsImportBoundaries, err := cfg.GetTsImportBoundaries()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ts import boundaries: %w", err)
 	}
 
+	workspace, err := cfg.GetWorkspace()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load go.work: %w", err)
+	}
+
 	vendorDir := filepath.Join(moduleDir, "vendor")
 	outDir := vendorDir
 
 	return &Generator{
 		Config:             cfg,
 		Plugins:            plugins,
+		ProfilePlugins:     profilePlugins,
 		Cache:              cache,
 		ProjectDir:         projectDir,
 		ModuleDir:          moduleDir,
 		ModulePath:         modulePath,
+		Workspace:          workspace,
 		VendorDir:          vendorDir,
 		TsImportBoundaries: tsImportBoundaries,
+		GoImportRemaps:     cfg.GetGoImportRemaps(),
 		OutDir:             outDir,
 		Verbose:            cfg.Verbose,
 		Stdout:             os.Stdout,
 		Stderr:             os.Stderr,
+		moduleCache:        newModuleCache(moduleDir, vendorDir),
 	}, nil
 }
 
@@ -111,15 +165,17 @@ func (g *Generator) Generate(ctx context.Context) error {
 	}
 
 	// Discover proto files
-	protoFiles, err := DiscoverProtoFiles(g.ProjectDir, g.Config.Targets, g.Config.Exclude)
+	protoFiles, err := DiscoverProtoFiles(g.ProjectDir, g.Config.Targets, g.Config.Exclude, g.Config.IncludeUntracked)
 	if err != nil {
 		return fmt.Errorf("failed to discover proto files: %w", err)
 	}
+	g.emit(&Event{Type: EventDiscovery, Files: protoFiles})
 
 	if len(protoFiles) == 0 {
 		if g.Verbose {
 			fmt.Fprintln(g.Stdout, "No proto files found")
 		}
+		g.emit(&Event{Type: EventSummary, Summary: &GenerateSummary{}})
 		return nil
 	}
 
@@ -127,63 +183,83 @@ func (g *Generator) Generate(ctx context.Context) error {
 		fmt.Fprintf(g.Stdout, "Found %d proto files\n", len(protoFiles))
 	}
 
-	// Get tool versions for cache invalidation.
-	toolVersions := g.getToolVersions()
+	return g.generateFiles(ctx, protoFiles)
+}
 
-	// Build protoc arguments
-	protocArgs := g.buildProtocArgs()
-	flagsHash := HashProtocFlags(protocArgs, g.ModuleDir)
+// generateFiles runs protoc and post-processing for the discovered proto files
+// that need regeneration and updates the cache.
+func (g *Generator) generateFiles(ctx context.Context, protoFiles []string) error {
+	start := time.Now()
+	summary := &GenerateSummary{ProtoFiles: len(protoFiles)}
 
-	// Group proto files by directory for cache tracking
-	filesByDir := make(map[string][]string)
-	for _, f := range protoFiles {
-		dir := filepath.Dir(f)
-		filesByDir[dir] = append(filesByDir[dir], f)
-	}
-
-	// Sort directories for deterministic processing order.
-	dirs := make([]string, 0, len(filesByDir))
-	for dir := range filesByDir {
-		dirs = append(dirs, dir)
-	}
-	slices.Sort(dirs)
+	// Get tool versions for cache invalidation.
+	toolVersions := g.getToolVersions()
 
 	// Track current packages and determine which need regeneration
-	currentPackages := make(map[string]struct{})
+	importGraph := g.newProtoImportGraph()
+	groups, packages := g.evaluatePackages(protoFiles, toolVersions, importGraph)
+	filesByDir := make(map[string][]string, len(packages))
+	currentPackages := make(map[string]struct{}, len(packages))
 	var filesToGenerate []string
 
-	for _, dir := range dirs {
-		files := filesByDir[dir]
-		packageKey := GetPackageKey(g.ModulePath, files[0])
-		currentPackages[packageKey] = struct{}{}
-
-		// Check if regeneration is needed
-		needsRegen, err := g.Cache.NeedsRegeneration(packageKey, files, g.ProjectDir, flagsHash, toolVersions, g.Config.Force)
-		if err != nil {
-			return fmt.Errorf("failed to check cache for %s: %w", dir, err)
-		}
+	for _, pkg := range packages {
+		dir, files, group := pkg.dir, pkg.files, pkg.group
+		filesByDir[dir] = files
+		currentPackages[pkg.packageKey] = struct{}{}
+		summary.Packages++
 
-		if !needsRegen {
+		ev := &Event{Type: EventPackage, Dir: dir, Package: pkg.packageKey, Profile: group.profile, Files: files}
+		if pkg.reason == StaleReasonNone {
 			if g.Verbose {
 				fmt.Fprintf(g.Stdout, "Skipping %s (up to date)\n", dir)
 			}
+			ev.Action = "skip"
+			g.emit(ev)
+			summary.Skipped++
 			continue
 		}
+		if pkg.reason == StaleReasonImports && g.Verbose {
+			fmt.Fprintf(g.Stdout, "Imports of %s changed\n", dir)
+		}
+		ev.Action, ev.Reason = "generate", pkg.reason
+		if g.Explain {
+			ev.Details = g.explainPackage(pkg, toolVersions).Details()
+			fmt.Fprintf(g.Stdout, "Regenerating %s (%s)\n", dir, pkg.reason)
+			for _, line := range ev.Details {
+				fmt.Fprintf(g.Stdout, "  %s\n", line)
+			}
+		}
+		g.emit(ev)
+		summary.Generated++
 
 		if g.Verbose {
-			fmt.Fprintf(g.Stdout, "Will generate %s\n", dir)
+			if group.profile != "" {
+				fmt.Fprintf(g.Stdout, "Will generate %s (profile %s)\n", dir, group.profile)
+			} else {
+				fmt.Fprintf(g.Stdout, "Will generate %s\n", dir)
+			}
 		}
+		group.staleDirs = append(group.staleDirs, dir)
 		filesToGenerate = append(filesToGenerate, files...)
 	}
 
-	// Run protoc once for all files that need regeneration
+	// Run protoc once per group for all files that need regeneration
 	if len(filesToGenerate) > 0 {
 		if g.Verbose {
 			fmt.Fprintf(g.Stdout, "Generating %d proto files\n", len(filesToGenerate))
 		}
 
-		if err := g.runProtoc(ctx, filesToGenerate); err != nil {
-			return fmt.Errorf("failed to generate protos: %w", err)
+		for _, group := range groups {
+			var groupFiles []string
+			for _, dir := range group.staleDirs {
+				groupFiles = append(groupFiles, filesByDir[dir]...)
+			}
+			if len(groupFiles) == 0 {
+				continue
+			}
+			if err := g.runProtoc(ctx, group.plugins, groupFiles); err != nil {
+				return fmt.Errorf("failed to generate protos: %w", err)
+			}
 		}
 
 		// Post-process and update cache for each directory
@@ -194,60 +270,78 @@ func (g *Generator) Generate(ctx context.Context) error {
 			g.TsImportBoundaries,
 			g.Verbose,
 		)
-		for _, dir := range dirs {
-			files := filesByDir[dir]
-			// Skip if not in files to generate
-			shouldProcess := false
-			for _, f := range files {
-				if slices.Contains(filesToGenerate, f) {
-					shouldProcess = true
-				}
-				if shouldProcess {
-					break
+		postProcessor.GoImportRemaps = g.GoImportRemaps
+		for _, group := range groups {
+			for _, dir := range group.staleDirs {
+				files := filesByDir[dir]
+
+				// Post-process generated files
+				for _, f := range files {
+					postStart := time.Now()
+					err := postProcessor.ProcessGeneratedFiles(f)
+					g.emit(&Event{Type: EventPostProcess, Dir: dir, Path: f, DurationMs: durationMs(postStart), Error: errorString(err)})
+					if err != nil {
+						return fmt.Errorf("failed to post-process %s: %w", f, err)
+					}
 				}
-			}
-			if !shouldProcess {
-				continue
-			}
 
-			// Post-process generated files
-			for _, f := range files {
-				if err := postProcessor.ProcessGeneratedFiles(f); err != nil {
-					return fmt.Errorf("failed to post-process %s: %w", f, err)
+				// Find generated files and update cache
+				packageKey := GetPackageKey(g.ModulePath, files[0])
+				var generatedFiles []string
+				for _, f := range files {
+					gf, err := group.plugins.FindGeneratedFiles(f, g.ProjectDir, g.VendorDir, g.ModulePath)
+					if err != nil {
+						return fmt.Errorf("failed to find generated files for %s: %w", f, err)
+					}
+					generatedFiles = append(generatedFiles, gf...)
 				}
-			}
 
-			// Find generated files and update cache
-			packageKey := GetPackageKey(g.ModulePath, files[0])
-			var generatedFiles []string
-			for _, f := range files {
-				gf, err := FindGeneratedFilesForProto(f, g.ProjectDir, g.VendorDir, g.ModulePath, g.Plugins.Languages, g.Plugins.RPCLibraries)
+				if previous := g.Cache.Packages[packageKey]; previous != nil {
+					for _, old := range previous.GeneratedFiles {
+						if slices.Contains(generatedFiles, old) {
+							continue
+						}
+						if err := os.Remove(filepath.Join(g.ProjectDir, old)); err != nil && !os.IsNotExist(err) {
+							return fmt.Errorf("failed to remove stale generated file %s: %w", old, err)
+						}
+						g.emit(&Event{Type: EventRemove, Dir: dir, Path: old})
+						summary.Removed++
+					}
+				}
+				if err := g.Cache.UpdatePackage(packageKey, files, generatedFiles, g.ProjectDir); err != nil {
+					return fmt.Errorf("failed to update cache for %s: %w", dir, err)
+				}
+				g.Cache.SetPackageProtocFlags(packageKey, group.protocArgs, g.ModuleDir)
+				imports, err := importGraph.Closure(files)
 				if err != nil {
-					return fmt.Errorf("failed to find generated files for %s: %w", f, err)
+					return fmt.Errorf("failed to resolve imports of %s: %w", dir, err)
 				}
-				generatedFiles = append(generatedFiles, gf...)
+				g.Cache.SetPackageImports(packageKey, imports)
 			}
+		}
+	}
 
-			if previous := g.Cache.Packages[packageKey]; previous != nil {
-				for _, old := range previous.GeneratedFiles {
-					if slices.Contains(generatedFiles, old) {
-						continue
-					}
-					if err := os.Remove(filepath.Join(g.ProjectDir, old)); err != nil && !os.IsNotExist(err) {
-						return fmt.Errorf("failed to remove stale generated file %s: %w", old, err)
-					}
-				}
-			}
-			if err := g.Cache.UpdatePackage(packageKey, files, generatedFiles, g.ProjectDir); err != nil {
-				return fmt.Errorf("failed to update cache for %s: %w", dir, err)
-			}
+	if err := g.writeDescriptorSets(ctx, protoFiles, toolVersions, importGraph, summary); err != nil {
+		return fmt.Errorf("failed to write descriptor sets: %w", err)
+	}
+
+	// Remove the outputs of packages whose proto files were deleted
+	removed, err := g.removeOrphanedOutputs(currentPackages)
+	for _, f := range removed {
+		if g.Verbose {
+			fmt.Fprintf(g.Stdout, "Removed orphaned %s\n", f)
 		}
+		g.emit(&Event{Type: EventRemove, Path: f})
+		summary.Removed++
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove orphaned generated files: %w", err)
 	}
 
 	// Clean orphaned packages from cache
 	g.Cache.CleanOrphanedPackages(currentPackages)
 
-	g.Cache.SetProtocFlags(protocArgs, g.ModuleDir)
+	g.Cache.SetProtocFlags(g.buildProtocArgs(g.Plugins), g.ModuleDir)
 	g.Cache.SetToolVersions(toolVersions)
 	// Save cache
 	cacheFile, _ := g.Config.GetCacheFilePath()
@@ -262,10 +356,153 @@ func (g *Generator) Generate(ctx context.Context) error {
 		}
 	}
 
+	g.emit(&Event{Type: EventSummary, DurationMs: durationMs(start), Summary: summary})
 	return nil
 }
 
-// setupProjectSymlinks maps protoc's native and Python module paths to the project.
+// packageState is the cache decision for a package directory.
+type packageState struct {
+	// group is the profile group the package is generated with.
+	group *profileGroup
+	// dir is the project-relative package directory.
+	dir string
+	// packageKey is the package cache key.
+	packageKey string
+	// files are the package's proto files.
+	files []string
+	// reason is why the package needs regeneration, if it does.
+	reason StaleReason
+	// changedImports are the changed files in the import closure.
+	changedImports []string
+}
+
+// evaluatePackages groups the proto files by package directory and profile and
+// checks each package against the cache. Returns the groups and the package
+// states in processing order. Nothing is written.
+func (g *Generator) evaluatePackages(protoFiles []string, toolVersions string, importGraph *protoImportGraph) ([]*profileGroup, []*packageState) {
+	// Group proto files by directory for cache tracking
+	filesByDir := make(map[string][]string)
+	for _, f := range protoFiles {
+		dir := filepath.Dir(f)
+		filesByDir[dir] = append(filesByDir[dir], f)
+	}
+
+	// Sort directories for deterministic processing order.
+	dirs := make([]string, 0, len(filesByDir))
+	for dir := range filesByDir {
+		dirs = append(dirs, dir)
+	}
+	slices.Sort(dirs)
+
+	// Group directories by profile, each with its own protoc arguments.
+	groups := g.groupDirsByProfile(dirs)
+
+	var packages []*packageState
+	for _, group := range groups {
+		for _, dir := range group.dirs {
+			files := filesByDir[dir]
+			pkg := &packageState{
+				group:      group,
+				dir:        dir,
+				packageKey: GetPackageKey(g.ModulePath, files[0]),
+				files:      files,
+			}
+			pkg.reason = g.Cache.staleReason(pkg.packageKey, files, g.ProjectDir, group.flagsHash, toolVersions, g.Config.Force)
+			if pkg.reason == StaleReasonNone {
+				pkg.changedImports = g.Cache.ChangedImports(pkg.packageKey, importGraph.Hash)
+				if len(pkg.changedImports) != 0 {
+					pkg.reason = StaleReasonImports
+				}
+			}
+			packages = append(packages, pkg)
+		}
+	}
+	return groups, packages
+}
+
+// explainPackage returns what changed for a package that needs regeneration.
+func (g *Generator) explainPackage(pkg *packageState, toolVersions string) *Regeneration {
+	r := g.Cache.RegenerationReason(pkg.packageKey, pkg.files, g.ProjectDir, pkg.group.protocArgs, g.ModuleDir, toolVersions, g.Config.Force)
+	if r == nil {
+		r = &Regeneration{Reason: pkg.reason, ChangedImports: pkg.changedImports}
+	}
+	return r
+}
+
+// profileGroup is a set of package directories generated with the same
+// profile and custom plugins.
+type profileGroup struct {
+	// profile is the profile name, empty for the top-level settings.
+	profile string
+	// plugins are the plugins enabled for the profile.
+	plugins *Plugins
+	// protocArgs are the protoc arguments for the profile, followed by the
+	// post-processing settings that change the outputs as pseudo flags.
+	protocArgs []string
+	// flagsHash is the hash of protocArgs stored per package in the cache.
+	flagsHash string
+	// dirs are the package directories in the group, sorted.
+	dirs []string
+	// staleDirs are the package directories that need regeneration.
+	staleDirs []string
+}
+
+// groupDirsByProfile partitions the sorted package directories by their
+// matching profile and the custom plugins that apply to them. Groups are
+// ordered by their first directory.
+func (g *Generator) groupDirsByProfile(dirs []string) []*profileGroup {
+	var groups []*profileGroup
+	byKey := make(map[string]*profileGroup)
+	for _, dir := range dirs {
+		name := g.profileForDir(dir)
+		plugins := g.pluginsForProfile(name).ForDir(dir)
+		key := name + "\x00" + plugins.customKey()
+		group := byKey[key]
+		if group == nil {
+			protocArgs := g.buildProtocArgs(plugins)
+			if plugins.Languages.Has(LanguageGo) {
+				protocArgs = append(protocArgs, goImportRemapFlags(g.GoImportRemaps)...)
+			}
+			group = &profileGroup{
+				profile:    name,
+				plugins:    plugins,
+				protocArgs: protocArgs,
+				flagsHash:  HashProtocFlags(protocArgs, g.ModuleDir),
+			}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.dirs = append(group.dirs, dir)
+	}
+	return groups
+}
+
+// profileForDir returns the name of the profile for a project-relative
+// package directory, or an empty string for the top-level settings.
+func (g *Generator) profileForDir(dir string) string {
+	if profile := g.Config.MatchProfile(dir); profile != nil {
+		return profile.Name
+	}
+	return ""
+}
+
+// pluginsForProfile returns the plugins for the named profile, falling back
+// to the top-level plugins.
+func (g *Generator) pluginsForProfile(name string) *Plugins {
+	if plugins := g.ProfilePlugins[name]; plugins != nil {
+		return plugins
+	}
+	return g.Plugins
+}
+
+// pluginsForFile returns the plugins used to generate a proto file.
+func (g *Generator) pluginsForFile(protoFile string) *Plugins {
+	dir := filepath.Dir(protoFile)
+	return g.pluginsForProfile(g.profileForDir(dir)).ForDir(dir)
+}
+
+// setupProjectSymlinks maps protoc's native and Python module paths to the
+// project.
 func (g *Generator) setupProjectSymlinks() error {
 	for _, modulePath := range []string{
 		g.ModulePath,
@@ -301,46 +538,240 @@ func pythonModulePath(modulePath string) string {
 	return strings.ReplaceAll(modulePath, "-", "_")
 }
 
-// buildProtocArgs builds the protoc command arguments.
-func (g *Generator) buildProtocArgs() []string {
+// buildProtocArgs builds the protoc command arguments for the given plugins.
+func (g *Generator) buildProtocArgs(plugins *Plugins) []string {
+	args := g.protocIncludeArgs()
+
+	// Output and plugin arguments
+	args = append(args, plugins.GetProtocArgs(g.OutDir, g.ProjectDir)...)
+
+	// Extra arguments from config
+	args = append(args, g.Config.ExtraArgs...)
+
+	return args
+}
+
+// protocIncludeArgs returns the protoc include path arguments.
+func (g *Generator) protocIncludeArgs() []string {
 	var args []string
 
 	// Include paths
 	args = append(args, "-I", g.OutDir)
 	args = append(args, "--proto_path", g.OutDir)
 
+	// Sibling workspace modules resolve from their own mounts.
+	if len(g.workspaceSiblings()) != 0 {
+		args = append(args, "-I", WorkspaceMountDir)
+	}
+
+	// Dependencies that are not vendored resolve from the module cache.
+	if len(g.goModules().Modules()) != 0 {
+		args = append(args, "-I", ModuleCacheMountDir)
+	}
+
 	// Add include path for google well-known proto types (timestamp.proto, any.proto, etc.)
 	// These are located at vendor/github.com/aperturerobotics/protobuf/src
 	protobufSrcDir := filepath.Join(g.VendorDir, "github.com", "aperturerobotics", "protobuf", "src")
 	if _, err := os.Stat(protobufSrcDir); err == nil {
 		args = append(args, "-I", protobufSrcDir)
+	} else if g.goModules().Dir(protobufModulePath) != "" {
+		args = append(args, "-I", path.Join(ModuleCacheMountDir, protobufModulePath, "src"))
 	}
+	return args
+}
 
-	// Output and plugin arguments
-	args = append(args, g.Plugins.GetProtocArgs(g.OutDir, g.ProjectDir)...)
+// runProtoc runs protoc for the given proto files.
+// With a single job all files go to one protoc invocation. With more jobs the
+// files are sharded by package directory and the shards run concurrently in
+// separate runtimes that share one compilation of the WASM modules. Output and
+// errors are reported in shard order so results stay deterministic.
+func (g *Generator) runProtoc(ctx context.Context, plugins *Plugins, protoFiles []string) error {
+	for _, custom := range plugins.Custom {
+		if custom.OutDir == "" {
+			continue
+		}
+		if err := os.MkdirAll(custom.OutDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory for plugin %s: %w", custom.Name, err)
+		}
+	}
 
-	// Extra arguments from config
-	args = append(args, g.Config.ExtraArgs...)
+	shards := shardProtoFiles(protoFiles)
+	jobs := min(g.Config.GetJobs(), len(shards))
+	if jobs <= 1 {
+		return g.runProtocShard(ctx, plugins, protoFiles, g.Stdout)
+	}
+
+	// Compile the WASM modules once before the shards start so they do not
+	// race to populate the compilation cache.
+	if err := g.compileWASMModules(ctx, plugins); err != nil {
+		return err
+	}
+
+	outputs := make([]bytes.Buffer, len(shards))
+	errs := make([]error, len(shards))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = g.runProtocShard(ctx, plugins, shards[i], &outputs[i])
+			}
+		}()
+	}
+	for i := range shards {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for i := range shards {
+		if outputs[i].Len() != 0 {
+			fmt.Fprint(g.Stdout, outputs[i].String())
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shardProtoFiles groups proto files by package directory, preserving order.
+func shardProtoFiles(protoFiles []string) [][]string {
+	var shards [][]string
+	index := make(map[string]int)
+	for _, f := range protoFiles {
+		dir := filepath.Dir(f)
+		i, ok := index[dir]
+		if !ok {
+			i = len(shards)
+			index[dir] = i
+			shards = append(shards, nil)
+		}
+		shards[i] = append(shards[i], f)
+	}
+	return shards
+}
+
+// compileWASMModules compiles the protoc, prost and WASM plugin modules into
+// the shared compilation cache.
+func (g *Generator) compileWASMModules(ctx context.Context, plugins *Plugins) error {
+	runtime := wazero.NewRuntimeWithConfig(ctx, g.newRuntimeConfig())
+	defer runtime.Close(ctx)
+
+	if _, err := protoc.CompileProtoc(ctx, runtime); err != nil {
+		return fmt.Errorf("failed to compile protoc: %w", err)
+	}
+	if plugins.RustProst != nil {
+		if _, err := prost.CompileProtocGenProst(ctx, runtime); err != nil {
+			return fmt.Errorf("failed to compile prost: %w", err)
+		}
+	}
+	for _, plugin := range plugins.wasmPlugins() {
+		if _, err := compileWASMPlugin(ctx, runtime, plugin.Path); err != nil {
+			return fmt.Errorf("failed to compile plugin %s: %w", plugin.Name, err)
+		}
+	}
+	return nil
+}
+
+// runProtocShard runs a single protoc invocation for the given proto files
+// using go-protoc-wasi, writing verbose output to out.
+func (g *Generator) runProtocShard(ctx context.Context, plugins *Plugins, protoFiles []string, out io.Writer) error {
+	args := g.protocCommand(plugins, protoFiles)
+	start := time.Now()
+	_, err := g.execProtoc(ctx, plugins, args, protoFiles, out)
+	ev := &Event{Type: EventProtoc, Files: protoFiles, Args: args[1:], DurationMs: durationMs(start), Error: errorString(err)}
+	if perr := (*ProtocError)(nil); errors.As(err, &perr) {
+		ev.Diagnostics = perr.Diagnostics
+	}
+	g.emit(ev)
+	return err
+}
+
+// protocModules returns the module cache modules protoc needs to compile the
+// project-relative proto files: the ones their imports resolve into, and the
+// protobuf module if it provides the well-known types.
+func (g *Generator) protocModules(protoFiles []string) ([]*GoModule, error) {
+	if len(g.goModules().Modules()) == 0 {
+		return nil, nil
+	}
+	importGraph := g.newProtoImportGraph()
+	if _, err := importGraph.Closure(protoFiles); err != nil {
+		return nil, err
+	}
+	modules := importGraph.UsedModules()
+	if m := g.goModules().byPath[protobufModulePath]; m != nil && !slices.Contains(modules, m) {
+		modules = append(modules, m)
+	}
+	return modules, nil
+}
 
+// protocCommand returns the protoc command line that generates the given
+// proto files.
+func (g *Generator) protocCommand(plugins *Plugins, protoFiles []string) []string {
+	// Build arguments
+	args := []string{"protoc"}
+	args = append(args, g.buildProtocArgs(plugins)...)
+
+	// Add proto files with vendor prefix
+	for _, f := range protoFiles {
+		args = append(args, filepath.Join(g.VendorDir, g.ModulePath, f))
+	}
 	return args
 }
 
-// runProtoc runs protoc for the given proto files using go-protoc-wasi.
-func (g *Generator) runProtoc(ctx context.Context, protoFiles []string) error {
+// execProtoc runs protoc with the given arguments in a fresh runtime, writing
+// verbose output to out. The vendor and project directories are mounted along
+// with the sibling workspace modules and the module cache directories the
+// imports of the project-relative proto files resolve into (read-only), and
+// any extra directories. Returns the warnings protoc printed to stderr.
+func (g *Generator) execProtoc(ctx context.Context, plugins *Plugins, args, protoFiles []string, out io.Writer, mountDirs ...string) (string, error) {
 	var stdout, stderr bytes.Buffer
 
-	// Create wazero runtime
-	runtime := wazero.NewRuntime(ctx)
+	modules, err := g.protocModules(protoFiles)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve imports: %w", err)
+	}
+
+	// Create wazero runtime sharing compiled modules with previous runs
+	runtime := wazero.NewRuntimeWithConfig(ctx, g.newRuntimeConfig())
 	defer runtime.Close(ctx)
 
 	// Create plugin handler
-	pluginHandler := NewNativePluginHandler(g.Plugins, g.Verbose)
+	pluginHandler := NewNativePluginHandler(plugins, g.Verbose)
+	pluginHandler.Stderr = g.Stderr
+	if g.Events != nil {
+		pluginHandler.OnPlugin = func(program string, d time.Duration, err error) {
+			g.emit(&Event{Type: EventPlugin, Plugin: program, DurationMs: float64(d.Microseconds()) / 1000, Error: errorString(err)})
+		}
+	}
 
 	// Create filesystem config that mounts the vendor directory
 	// This allows protoc to read .proto files and write output files
 	fsConfig := wazero.NewFSConfig().
 		WithDirMount(g.VendorDir, g.VendorDir).
 		WithDirMount(g.ProjectDir, g.ProjectDir)
+	if siblings := g.workspaceSiblings(); len(siblings) != 0 {
+		fsConfig = fsConfig.WithFSMount(fstest.MapFS{}, WorkspaceMountDir)
+		for _, m := range siblings {
+			fsConfig = fsConfig.WithReadOnlyDirMount(m.Dir, workspaceMountPath(m))
+		}
+	}
+	if len(g.goModules().Modules()) != 0 {
+		// Mount the include directory itself so protoc finds it, then each
+		// module below it. Nested module paths resolve to the longest mount.
+		fsConfig = fsConfig.WithFSMount(fstest.MapFS{}, ModuleCacheMountDir)
+		for _, m := range modules {
+			fsConfig = fsConfig.WithReadOnlyDirMount(m.Dir, path.Join(ModuleCacheMountDir, m.Path))
+		}
+	}
+	for _, dir := range mountDirs {
+		fsConfig = fsConfig.WithDirMount(dir, dir)
+	}
 
 	// Create protoc config
 	cfg := &protoc.Config{
@@ -353,55 +784,82 @@ func (g *Generator) runProtoc(ctx context.Context, protoFiles []string) error {
 	// Create protoc instance
 	p, err := protoc.NewProtoc(ctx, runtime, cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create protoc: %w", err)
+		return "", fmt.Errorf("failed to create protoc: %w", err)
 	}
 	defer p.Close(ctx)
 
 	// Initialize protoc (this instantiates WASI)
 	if err := p.Init(ctx); err != nil {
-		return fmt.Errorf("failed to init protoc: %w", err)
+		return "", fmt.Errorf("failed to init protoc: %w", err)
 	}
 
-	// Initialize prost WASM plugin if rust prost is configured
+	// Initialize WASM plugins (prost and any .wasm plugins) in the runtime
 	// This must be done after protoc.Init since that's when WASI gets instantiated
-	if g.Plugins.RustProst != nil {
-		if err := pluginHandler.InitProstWASM(ctx, runtime); err != nil {
-			return fmt.Errorf("failed to init prost WASM: %w", err)
-		}
-		defer pluginHandler.CloseProstWASM(ctx)
-	}
-
-	// Build arguments
-	args := []string{"protoc"}
-	args = append(args, g.buildProtocArgs()...)
-
-	// Add proto files with vendor prefix
-	for _, f := range protoFiles {
-		args = append(args, filepath.Join(g.VendorDir, g.ModulePath, f))
+	if err := pluginHandler.InitWASM(ctx, runtime); err != nil {
+		return "", fmt.Errorf("failed to init WASM plugins: %w", err)
 	}
+	defer pluginHandler.CloseWASM(ctx)
 
 	if g.Verbose {
-		fmt.Fprintf(g.Stdout, "Running: %s\n", strings.Join(args, " "))
+		fmt.Fprintf(out, "Running: %s\n", strings.Join(args, " "))
 	}
 
 	// Run protoc
 	exitCode, err := p.Run(ctx, args)
 	if err != nil {
-		return fmt.Errorf("protoc error: %w", err)
+		return "", fmt.Errorf("protoc error: %w", err)
 	}
 
 	if exitCode != 0 {
-		if stderr.Len() > 0 {
-			return fmt.Errorf("protoc failed with exit code %d: %s", exitCode, stderr.String())
-		}
-		return fmt.Errorf("protoc failed with exit code %d", exitCode)
+		return "", g.newProtocError(exitCode, stderr.String())
 	}
 
 	if g.Verbose && stdout.Len() > 0 {
-		fmt.Fprint(g.Stdout, stdout.String())
+		fmt.Fprint(out, stdout.String())
 	}
 
-	return nil
+	return stderr.String(), nil
+}
+
+// newRuntimeConfig returns the wazero runtime config for protoc runs.
+// The compilation cache is created on first use and reused by later runs so
+// long-lived generators (e.g. watch mode) only compile the WASM modules once.
+// Compiled modules are persisted to the WASM cache directory when available.
+func (g *Generator) newRuntimeConfig() wazero.RuntimeConfig {
+	if g.compilationCache == nil {
+		g.compilationCache = g.openCompilationCache()
+	}
+	return wazero.NewRuntimeConfig().WithCompilationCache(g.compilationCache)
+}
+
+// openCompilationCache opens the on-disk compilation cache, falling back to
+// an in-memory cache if the cache directory is unavailable.
+func (g *Generator) openCompilationCache() wazero.CompilationCache {
+	cacheDir, err := g.Config.GetWasmCacheDir()
+	if err == nil {
+		err = os.MkdirAll(cacheDir, 0o755)
+	}
+	if err == nil {
+		var cache wazero.CompilationCache
+		cache, err = wazero.NewCompilationCacheWithDir(cacheDir)
+		if err == nil {
+			return cache
+		}
+	}
+	if g.Verbose {
+		fmt.Fprintf(g.Stdout, "WASM compilation cache unavailable, using memory: %v\n", err)
+	}
+	return wazero.NewCompilationCache()
+}
+
+// Close releases the compiled WASM modules held by the generator.
+func (g *Generator) Close(ctx context.Context) error {
+	if g.compilationCache == nil {
+		return nil
+	}
+	err := g.compilationCache.Close(ctx)
+	g.compilationCache = nil
+	return err
 }
 
 // getToolVersions returns a string with tool versions for cache invalidation.
@@ -455,7 +913,13 @@ func (g *Generator) getToolVersions() string {
 		}
 	}
 
-	if g.Plugins != nil && g.Plugins.StarpcPython != nil {
+	var wasmPlugins []*Plugin
+	for _, plugins := range g.allPlugins() {
+		wasmPlugins = append(wasmPlugins, plugins.wasmPlugins()...)
+	}
+	versions = append(versions, wasmPluginVersions(wasmPlugins)...)
+
+	if g.hasStarpcPython() {
 		if data, err := os.ReadFile(filepath.Join(g.ProjectDir, "uv.lock")); err == nil {
 			digest := sha256.Sum256(data)
 			versions = append(versions, "uv.lock="+hex.EncodeToString(digest[:]))
@@ -465,12 +929,37 @@ func (g *Generator) getToolVersions() string {
 	return strings.Join(versions, ",")
 }
 
+// allPlugins returns the top-level plugins followed by the profile plugins in
+// configuration order.
+func (g *Generator) allPlugins() []*Plugins {
+	var all []*Plugins
+	if g.Plugins != nil {
+		all = append(all, g.Plugins)
+	}
+	for _, profile := range g.Config.Profiles {
+		if plugins := g.ProfilePlugins[profile.Name]; plugins != nil {
+			all = append(all, plugins)
+		}
+	}
+	return all
+}
+
+// hasStarpcPython returns true if any profile generates StarPC Python stubs.
+func (g *Generator) hasStarpcPython() bool {
+	for _, plugins := range g.allPlugins() {
+		if plugins.StarpcPython != nil {
+			return true
+		}
+	}
+	return false
+}
+
 // formatGeneratedFiles formats the generated Go and TypeScript files.
 func (g *Generator) formatGeneratedFiles(protoFiles []string) error {
 	var goFiles, tsFiles []string
 
 	for _, f := range protoFiles {
-		gf, err := FindGeneratedFilesForProto(f, g.ProjectDir, g.VendorDir, g.ModulePath, g.Plugins.Languages, g.Plugins.RPCLibraries)
+		gf, err := g.pluginsForFile(f).FindGeneratedFiles(f, g.ProjectDir, g.VendorDir, g.ModulePath)
 		if err != nil {
 			continue
 		}
@@ -483,6 +972,13 @@ func (g *Generator) formatGeneratedFiles(protoFiles []string) error {
 		}
 	}
 
+	if len(goFiles) != 0 || len(tsFiles) != 0 {
+		start := time.Now()
+		defer func() {
+			g.emit(&Event{Type: EventFormat, Files: append(goFiles, tsFiles...), DurationMs: durationMs(start)})
+		}()
+	}
+
 	// Format Go files with gofumpt
 	if len(goFiles) > 0 {
 		gofumptPath := filepath.Join(g.ProjectDir, g.Config.ToolsDir, "bin", "gofumpt")
@@ -557,6 +1053,11 @@ func (g *Generator) Clean() error {
 			_ = os.Remove(fullPath)
 		}
 	}
+	if info := g.Cache.DescriptorSet; info != nil {
+		for _, f := range info.GeneratedFiles {
+			_ = os.Remove(filepath.Join(g.ProjectDir, f))
+		}
+	}
 
 	return nil
 }
@@ -0,0 +1,55 @@
+package protogen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestShardProtoFilesGroupsByDirectory(t *testing.T) {
+	shards := shardProtoFiles([]string{"a/x.proto", "b/y.proto", "a/z.proto", "root.proto"})
+	want := [][]string{{"a/x.proto", "a/z.proto"}, {"b/y.proto"}, {"root.proto"}}
+	if !slices.EqualFunc(shards, want, slices.Equal[[]string]) {
+		t.Fatalf("shards = %v, want %v", shards, want)
+	}
+}
+
+func TestGenerateParallelJobsMatchesSerial(t *testing.T) {
+	files := map[string]string{
+		"a/a.proto": "syntax = \"proto3\";\npackage a;\nmessage A { string id = 1; }\n",
+		"b/b.proto": "syntax = \"proto3\";\npackage b;\nimport \"example.com/project/a/a.proto\";\nmessage B { a.A a = 1; }\n",
+		"c/c.proto": "syntax = \"proto3\";\npackage c;\nmessage C { int32 n = 1; }\n",
+	}
+	serial := newCppTestGenerator(t, files)
+	parallel := newCppTestGenerator(t, files)
+	parallel.Config.Jobs = 3
+
+	ctx := context.Background()
+	for _, g := range []*Generator{serial, parallel} {
+		if err := g.Generate(ctx); err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+	}
+
+	for _, dir := range []string{"a", "b", "c"} {
+		for _, ext := range []string{".pb.cc", ".pb.h"} {
+			name := filepath.Join(dir, dir+ext)
+			want, err := os.ReadFile(filepath.Join(serial.ProjectDir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(filepath.Join(parallel.ProjectDir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Fatalf("parallel output for %s differs from serial output", name)
+			}
+		}
+	}
+	if len(parallel.Cache.Packages) != 3 {
+		t.Fatalf("expected 3 cached packages, got %d", len(parallel.Cache.Packages))
+	}
+}
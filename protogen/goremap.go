@@ -0,0 +1,126 @@
+package protogen
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// protobufGoLitePath is the module path of the protobuf-go-lite runtime.
+const protobufGoLitePath = "github.com/aperturerobotics/protobuf-go-lite"
+
+// goImportRemaps maps the protobuf-go well-known type, descriptor and plugin
+// packages to their protobuf-go-lite equivalents.
+var goImportRemaps = map[string]string{
+	"google.golang.org/protobuf/types/known/anypb":           protobufGoLitePath + "/types/known/anypb",
+	"google.golang.org/protobuf/types/known/apipb":           protobufGoLitePath + "/types/known/apipb",
+	"google.golang.org/protobuf/types/known/durationpb":      protobufGoLitePath + "/types/known/durationpb",
+	"google.golang.org/protobuf/types/known/emptypb":         protobufGoLitePath + "/types/known/emptypb",
+	"google.golang.org/protobuf/types/known/fieldmaskpb":     protobufGoLitePath + "/types/known/fieldmaskpb",
+	"google.golang.org/protobuf/types/known/sourcecontextpb": protobufGoLitePath + "/types/known/sourcecontextpb",
+	"google.golang.org/protobuf/types/known/structpb":        protobufGoLitePath + "/types/known/structpb",
+	"google.golang.org/protobuf/types/known/timestamppb":     protobufGoLitePath + "/types/known/timestamppb",
+	"google.golang.org/protobuf/types/known/typepb":          protobufGoLitePath + "/types/known/typepb",
+	"google.golang.org/protobuf/types/known/wrapperspb":      protobufGoLitePath + "/types/known/wrapperspb",
+	"google.golang.org/protobuf/types/descriptorpb":          protobufGoLitePath + "/types/descriptorpb",
+	"google.golang.org/protobuf/types/pluginpb":              protobufGoLitePath + "/types/pluginpb",
+}
+
+// GetGoImportRemaps returns the Go import remaps applied to generated files:
+// the built-in protobuf-go-lite remaps overlaid with the configured ones.
+func (c *Config) GetGoImportRemaps() map[string]string {
+	remaps := maps.Clone(goImportRemaps)
+	maps.Copy(remaps, c.GoImportRemaps)
+	return remaps
+}
+
+// ValidateGoImportRemaps checks that the configured remaps map valid import
+// paths to valid import paths.
+func (c *Config) ValidateGoImportRemaps() error {
+	for from, to := range c.GoImportRemaps {
+		if err := module.CheckImportPath(from); err != nil {
+			return fmt.Errorf("go import remap %q: %w", from, err)
+		}
+		if err := module.CheckImportPath(to); err != nil {
+			return fmt.Errorf("go import remap %q: %w", from, err)
+		}
+	}
+	return nil
+}
+
+// goImportRemapFlags returns the remaps as sorted pseudo protoc flags, so that
+// changing them regenerates the packages with Go output. Nil remaps select
+// the built-in ones like PostProcessor does.
+func goImportRemapFlags(remaps map[string]string) []string {
+	if remaps == nil {
+		remaps = goImportRemaps
+	}
+	flags := make([]string, 0, len(remaps))
+	for from, to := range remaps {
+		flags = append(flags, "--go-import-remap="+from+"="+to)
+	}
+	slices.Sort(flags)
+	return flags
+}
+
+// remapGoImport returns the replacement for an import path. A remap applies to
+// its import path and every package below it, and the longest match wins.
+func remapGoImport(remaps map[string]string, importPath string) (string, bool) {
+	var match string
+	for from := range remaps {
+		if (importPath == from || strings.HasPrefix(importPath, from+"/")) && len(from) > len(match) {
+			match = from
+		}
+	}
+	if match == "" {
+		return "", false
+	}
+	return remaps[match] + strings.TrimPrefix(importPath, match), true
+}
+
+// rewriteGoImports rewrites the import specs of a Go source file using the
+// remaps, leaving the rest of the file untouched. If the last element of a
+// rewritten path changes, the import is named after the old element so the
+// file keeps referring to the package by the same name. Returns nil if no
+// import was rewritten.
+func rewriteGoImports(filename string, src []byte, remaps map[string]string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	last := 0
+	for _, spec := range file.Imports {
+		oldPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		newPath, ok := remapGoImport(remaps, oldPath)
+		if !ok || newPath == oldPath {
+			continue
+		}
+
+		replacement := strconv.Quote(newPath)
+		if oldName := path.Base(oldPath); spec.Name == nil && path.Base(newPath) != oldName && token.IsIdentifier(oldName) {
+			replacement = oldName + " " + replacement
+		}
+		start := fset.Position(spec.Path.Pos()).Offset
+		end := fset.Position(spec.Path.End()).Offset
+		out = append(out, src[last:start]...)
+		out = append(out, replacement...)
+		last = end
+	}
+	if out == nil {
+		return nil, nil
+	}
+	return append(out, src[last:]...), nil
+}
@@ -0,0 +1,90 @@
+package protogen
+
+import "testing"
+
+func TestRemapGoImport(t *testing.T) {
+	remaps := map[string]string{
+		"example.com/protos":         "corp.example/forks/protos",
+		"example.com/protos/special": "corp.example/special",
+	}
+	for importPath, want := range map[string]string{
+		"example.com/protos":            "corp.example/forks/protos",
+		"example.com/protos/a/b":        "corp.example/forks/protos/a/b",
+		"example.com/protos/special/x":  "corp.example/special/x",
+		"example.com/protosx":           "",
+		"google.golang.org/protobuf/xx": "",
+	} {
+		got, ok := remapGoImport(remaps, importPath)
+		if ok != (want != "") || got != want {
+			t.Errorf("remapGoImport(%q) = %q, %v, want %q", importPath, got, ok, want)
+		}
+	}
+}
+
+func TestGetGoImportRemaps(t *testing.T) {
+	cfg := NewConfig()
+	cfg.GoImportRemaps = map[string]string{
+		"google.golang.org/protobuf/types/known/anypb": "corp.example/anypb",
+		"github.com/googleapis/api":                    "corp.example/googleapis/api",
+	}
+	remaps := cfg.GetGoImportRemaps()
+	if got := remaps["google.golang.org/protobuf/types/known/anypb"]; got != "corp.example/anypb" {
+		t.Fatalf("configured remap did not override the built-in one: %q", got)
+	}
+	if got := remaps["google.golang.org/protobuf/types/pluginpb"]; got != protobufGoLitePath+"/types/pluginpb" {
+		t.Fatalf("built-in pluginpb remap missing: %q", got)
+	}
+	if got := goImportRemaps["google.golang.org/protobuf/types/known/anypb"]; got != protobufGoLitePath+"/types/known/anypb" {
+		t.Fatalf("GetGoImportRemaps modified the built-in remaps: %q", got)
+	}
+	if err := cfg.ValidateGoImportRemaps(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	for _, remaps := range []map[string]string{
+		{"": "corp.example/x"},
+		{"example.com/x": ""},
+		{"example.com/x": "corp.example/x y"},
+	} {
+		cfg.GoImportRemaps = remaps
+		if err := cfg.ValidateGoImportRemaps(); err == nil {
+			t.Errorf("expected validation error for %v", remaps)
+		}
+	}
+}
+
+func TestPlanRegeneratesGoPackagesOnRemapChange(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"foo/foo.proto": "syntax = \"proto3\";\npackage foo;\nmessage Foo {}\n",
+	})
+	g.Plugins = &Plugins{Languages: Languages{LanguageGo: {}}, RPCLibraries: RPCLibraries{}}
+	g.GoImportRemaps = g.Config.GetGoImportRemaps()
+
+	// Record the package as generated with the current settings.
+	files := []string{"foo/foo.proto"}
+	packageKey := GetPackageKey(g.ModulePath, files[0])
+	group := g.groupDirsByProfile([]string{"foo"})[0]
+	if err := g.Cache.UpdatePackage(packageKey, files, nil, g.ProjectDir); err != nil {
+		t.Fatal(err)
+	}
+	g.Cache.SetPackageProtocFlags(packageKey, group.protocArgs, g.ModuleDir)
+	g.Cache.SetToolVersions(g.getToolVersions())
+
+	plan, err := g.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Packages) != 0 {
+		t.Fatalf("expected no stale packages, got %+v", plan.Packages)
+	}
+
+	g.Config.GoImportRemaps = map[string]string{"example.com/protos": "corp.example/protos"}
+	g.GoImportRemaps = g.Config.GetGoImportRemaps()
+	plan, err = g.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Packages) != 1 || plan.Packages[0].Reason != StaleReasonFlags {
+		t.Fatalf("expected the remap change to regenerate foo, got %+v", plan.Packages)
+	}
+}
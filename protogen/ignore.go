@@ -0,0 +1,246 @@
+package protogen
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ignoreFileNames are the ignore files read in every directory, lowest
+// precedence first.
+var ignoreFileNames = []string{".gitignore", ".ignore"}
+
+// discoverySkipDirs are the directory names never descended into during
+// discovery.
+var discoverySkipDirs = map[string]struct{}{
+	".git":         {},
+	"vendor":       {},
+	"node_modules": {},
+	".tools":       {},
+}
+
+// ignoreRule is a single pattern from a .gitignore style file.
+type ignoreRule struct {
+	// base is the slash-separated directory containing the ignore file,
+	// relative to the repository root, or to the walk root outside of a
+	// repository. Empty for the root.
+	base string
+	// pattern is the glob matched against paths relative to base.
+	pattern string
+	// negate re-includes paths matched by earlier rules.
+	negate bool
+	// dirOnly only matches directories.
+	dirOnly bool
+}
+
+// parseIgnoreRules parses the contents of a .gitignore style file in the
+// base directory.
+func parseIgnoreRules(data []byte, base string) []ignoreRule {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// Trailing spaces are ignored unless escaped.
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+			line = line[:len(line)-1]
+		}
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		// Patterns with a slash other than a trailing one are relative to the
+		// ignore file, others match at any depth.
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// isIgnored returns true if the slash-separated path relative to the
+// repository root is ignored by the rules. The last matching rule wins.
+func isIgnored(rules []ignoreRule, rel string, isDir bool) bool {
+	var ignored bool
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		name := rel
+		if rule.base != "" {
+			var ok bool
+			if name, ok = strings.CutPrefix(rel, rule.base+"/"); !ok {
+				continue
+			}
+		}
+		if matchGlob(rule.pattern, name) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// loadIgnoreRules reads the ignore files in dir and appends their rules.
+func loadIgnoreRules(rules []ignoreRule, dir, base string) ([]ignoreRule, error) {
+	for _, name := range ignoreFileNames {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Clip so sibling directories do not share appended rules.
+		rules = append(slices.Clip(rules), parseIgnoreRules(data, base)...)
+	}
+	return rules, nil
+}
+
+// findRepoRoot returns the closest directory containing .git at or above dir,
+// or an empty string if dir is not in a git repository.
+func findRepoRoot(dir string) string {
+	for {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// gitExcludeFile returns the info/exclude file of the repository rooted at
+// repoRoot. In worktrees and submodules .git is a file pointing to the git
+// directory, and worktrees share the exclude file of the main repository.
+func gitExcludeFile(repoRoot string) string {
+	gitDir := filepath.Join(repoRoot, ".git")
+	if data, err := os.ReadFile(gitDir); err == nil {
+		target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+		if !ok {
+			return ""
+		}
+		gitDir = strings.TrimSpace(target)
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(repoRoot, gitDir)
+		}
+		if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+			commonDir := strings.TrimSpace(string(common))
+			if !filepath.IsAbs(commonDir) {
+				commonDir = filepath.Join(gitDir, commonDir)
+			}
+			gitDir = commonDir
+		}
+	}
+	return filepath.Join(gitDir, "info", "exclude")
+}
+
+// walkProtoFiles returns the slash-separated paths of the .proto files under
+// root relative to root, sorted. See walkProjectFiles for the skipped files.
+func walkProtoFiles(root string) ([]string, error) {
+	return walkProjectFiles(root, func(entry fs.DirEntry) bool {
+		return strings.HasSuffix(entry.Name(), ".proto")
+	})
+}
+
+// walkProjectFiles returns the slash-separated paths of the files under root
+// selected by match relative to root, sorted. Files and directories ignored by
+// .gitignore, .ignore and .git/info/exclude are skipped, as are the
+// discoverySkipDirs, the skipDirs given relative to root and nested Go modules,
+// which own their proto files and outputs like they own their packages. If
+// root is inside a git repository, the ignore files of the directories between
+// the repository root and root apply as well.
+func walkProjectFiles(root string, match func(entry fs.DirEntry) bool, skipDirs ...string) ([]string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rules are matched against paths relative to the repository root, which
+	// are the walk paths below prefix.
+	var rules []ignoreRule
+	var prefix string
+	if repoRoot := findRepoRoot(root); repoRoot != "" {
+		// Errors are not fatal since .git may point to a missing directory.
+		if data, err := os.ReadFile(gitExcludeFile(repoRoot)); err == nil {
+			rules = parseIgnoreRules(data, "")
+		}
+		rel, err := filepath.Rel(repoRoot, root)
+		if err != nil {
+			return nil, err
+		}
+		if rel != "." {
+			prefix = filepath.ToSlash(rel)
+			dir, base := repoRoot, ""
+			for _, name := range strings.Split(prefix, "/") {
+				if rules, err = loadIgnoreRules(rules, dir, base); err != nil {
+					return nil, err
+				}
+				dir, base = filepath.Join(dir, name), path.Join(base, name)
+			}
+		}
+	}
+
+	var files []string
+	var walk func(dir, base string, rules []ignoreRule) error
+	walk = func(dir, base string, rules []ignoreRule) error {
+		rules, err := loadIgnoreRules(rules, dir, path.Join(prefix, base))
+		if err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			rel := path.Join(base, name)
+			repoRel := path.Join(prefix, rel)
+			if entry.IsDir() {
+				if _, skip := discoverySkipDirs[name]; skip || slices.Contains(skipDirs, rel) || isIgnored(rules, repoRel, true) {
+					continue
+				}
+				subDir := filepath.Join(dir, name)
+				if _, err := os.Stat(filepath.Join(subDir, "go.mod")); err == nil {
+					continue
+				}
+				if err := walk(subDir, rel, rules); err != nil {
+					return err
+				}
+				continue
+			}
+			if !match(entry) || isIgnored(rules, repoRel, false) {
+				continue
+			}
+			files = append(files, rel)
+		}
+		return nil
+	}
+	if err := walk(root, "", rules); err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+	return files, nil
+}
@@ -0,0 +1,33 @@
+package protogen
+
+import "testing"
+
+func TestIsIgnored(t *testing.T) {
+	rules := parseIgnoreRules([]byte("# comment\n*.log\n/root.proto\nout/\n!keep.log\n\\#hash.proto\ndocs/**/gen\n"), "")
+	rules = append(rules, parseIgnoreRules([]byte("local.proto\n!/out/\n"), "sub")...)
+	for _, tc := range []struct {
+		path   string
+		isDir  bool
+		ignore bool
+	}{
+		{"a.log", false, true},
+		{"x/y/a.log", false, true},
+		{"keep.log", false, false},
+		{"root.proto", false, true},
+		{"x/root.proto", false, false},
+		{"out", true, true},
+		{"x/out", true, true},
+		{"out", false, false},
+		{"#hash.proto", false, true},
+		{"docs/a/b/gen", true, true},
+		{"docs/gen", true, true},
+		{"sub/local.proto", false, true},
+		{"local.proto", false, false},
+		{"sub/out", true, false},
+		{"sub/x/out", true, true},
+	} {
+		if got := isIgnored(rules, tc.path, tc.isDir); got != tc.ignore {
+			t.Errorf("isIgnored(%q, %v) = %v, want %v", tc.path, tc.isDir, got, tc.ignore)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package protogen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ParseProtoImports returns the import paths of proto source in file order.
+func ParseProtoImports(src []byte) ([]string, error) {
+	tokens, err := tokenizeProto(string(src))
+	if err != nil {
+		return nil, err
+	}
+	var imports []string
+	stmtStart := true
+	for i := 0; i < len(tokens); i++ {
+		tok := &tokens[i]
+		if tok.kind == protoTokenComment {
+			continue
+		}
+		if !stmtStart || !tok.is("import") {
+			stmtStart = tok.is(";") || tok.is("{") || tok.is("}")
+			continue
+		}
+		// import [public | weak | option] "path" ["path" ...];
+		var path strings.Builder
+		for i++; i < len(tokens) && !tokens[i].is(";"); i++ {
+			if tokens[i].kind != protoTokenString {
+				continue
+			}
+			s, err := unquoteProtoString(tokens[i].text)
+			if err != nil {
+				return nil, err
+			}
+			path.WriteString(s)
+		}
+		imports = append(imports, path.String())
+		stmtStart = true
+	}
+	return imports, nil
+}
+
+// unquoteProtoString decodes a single or double quoted proto string literal.
+func unquoteProtoString(s string) (string, error) {
+	if strings.HasPrefix(s, "'") {
+		s = `"` + strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	return strconv.Unquote(s)
+}
+
+// protoImportGraph resolves the transitive imports of the project's proto
+// files to files on disk, caching the parsed imports and content hashes.
+type protoImportGraph struct {
+	// modulePath is the Go module path that maps imports to projectDir.
+	modulePath string
+	// projectDir is the project directory.
+	projectDir string
+	// siblings are the other workspace modules, searched after includeDirs.
+	siblings []*WorkspaceModule
+	// modules resolves the remaining imports from the Go module cache.
+	modules *moduleCache
+	// usedModules are the module cache modules that imports resolved into, by
+	// module path.
+	usedModules map[string]*GoModule
+	// includeDirs are searched in order for imports of other modules.
+	includeDirs []string
+
+	// imports caches the direct imports of each file by import path.
+	imports map[string][]string
+	// hashes caches the content hash of each file by import path. Files that
+	// could not be found hash to the empty string.
+	hashes map[string]string
+}
+
+// newProtoImportGraph returns an import graph using the generator's protoc
+// include directories.
+func (g *Generator) newProtoImportGraph() *protoImportGraph {
+	includeDirs := []string{g.OutDir}
+	protobufSrcDir := filepath.Join(g.VendorDir, "github.com", "aperturerobotics", "protobuf", "src")
+	if _, err := os.Stat(protobufSrcDir); err == nil {
+		includeDirs = append(includeDirs, protobufSrcDir)
+	} else if dir := g.goModules().Dir(protobufModulePath); dir != "" {
+		includeDirs = append(includeDirs, filepath.Join(dir, "src"))
+	}
+	return &protoImportGraph{
+		modulePath:  g.ModulePath,
+		projectDir:  g.ProjectDir,
+		siblings:    g.workspaceSiblings(),
+		modules:     g.goModules(),
+		usedModules: make(map[string]*GoModule),
+		includeDirs: includeDirs,
+		imports:     make(map[string][]string),
+		hashes:      make(map[string]string),
+	}
+}
+
+// importPath returns the import path of a project-relative proto file.
+func (p *protoImportGraph) importPath(protoFile string) string {
+	return p.modulePath + "/" + filepath.ToSlash(protoFile)
+}
+
+// resolve returns the file on disk for an import path, or an empty string if
+// it cannot be found.
+func (p *protoImportGraph) resolve(importPath string) string {
+	if rel, ok := strings.CutPrefix(importPath, p.modulePath+"/"); ok {
+		path := filepath.Join(p.projectDir, filepath.FromSlash(rel))
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	for _, dir := range p.includeDirs {
+		path := filepath.Join(dir, filepath.FromSlash(importPath))
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	if path := resolveWorkspaceImport(p.siblings, importPath); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	if path := p.modules.resolve(importPath); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			m := p.modules.moduleFor(importPath)
+			p.usedModules[m.Path] = m
+			return path
+		}
+	}
+	return ""
+}
+
+// UsedModules returns the module cache modules that the imports loaded so far
+// resolved into, sorted by path.
+func (p *protoImportGraph) UsedModules() []*GoModule {
+	modules := slices.Collect(maps.Values(p.usedModules))
+	slices.SortFunc(modules, func(a, b *GoModule) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return modules
+}
+
+// load reads, hashes and parses the file for an import path once.
+func (p *protoImportGraph) load(importPath string) error {
+	if _, ok := p.hashes[importPath]; ok {
+		return nil
+	}
+	p.hashes[importPath] = ""
+	path := p.resolve(importPath)
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	p.hashes[importPath] = hex.EncodeToString(sum[:])
+	imports, err := ParseProtoImports(data)
+	if err != nil {
+		return err
+	}
+	p.imports[importPath] = imports
+	return nil
+}
+
+// Hash returns the content hash of the file for an import path, or an empty
+// string if it cannot be found.
+func (p *protoImportGraph) Hash(importPath string) string {
+	if err := p.load(importPath); err != nil {
+		return ""
+	}
+	return p.hashes[importPath]
+}
+
+// Closure returns the transitive imports of the project-relative proto files
+// mapped to their content hashes, excluding the files themselves. Imports
+// that cannot be found map to the empty string.
+func (p *protoImportGraph) Closure(protoFiles []string) (map[string]string, error) {
+	queue := make([]string, len(protoFiles))
+	for i, f := range protoFiles {
+		queue[i] = p.importPath(f)
+	}
+	own := slices.Clone(queue)
+	seen := make(map[string]struct{}, len(queue))
+	for _, f := range queue {
+		seen[f] = struct{}{}
+	}
+
+	closure := make(map[string]string)
+	for len(queue) != 0 {
+		f := queue[0]
+		queue = queue[1:]
+		if err := p.load(f); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(own, f) {
+			closure[f] = p.hashes[f]
+		}
+		for _, imp := range p.imports[f] {
+			if _, ok := seen[imp]; ok {
+				continue
+			}
+			seen[imp] = struct{}{}
+			queue = append(queue, imp)
+		}
+	}
+	return closure, nil
+}
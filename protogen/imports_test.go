@@ -0,0 +1,89 @@
+package protogen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseProtoImports(t *testing.T) {
+	src := `syntax = "proto3";
+// import "commented/out.proto";
+import "a/a.proto";
+import public 'b/b.proto';
+import weak "c/" "c.proto";
+message Import { string import = 1; }
+`
+	imports, err := ParseProtoImports([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a/a.proto", "b/b.proto", "c/c.proto"}; !slices.Equal(imports, want) {
+		t.Fatalf("unexpected imports: %v", imports)
+	}
+}
+
+func TestGenerateRegeneratesImporters(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"a/a.proto":                          "syntax = \"proto3\";\npackage a;\nimport \"example.com/project/b/b.proto\";\nmessage A { b.B b = 1; }\n",
+		"b/b.proto":                          "syntax = \"proto3\";\npackage b;\nimport \"example.com/other/c/c.proto\";\nmessage B { c.C c = 1; }\n",
+		"vendor/example.com/other/c/c.proto": "syntax = \"proto3\";\npackage c;\nmessage C {}\n",
+	})
+	g.Config.Targets = []string{"a/*.proto", "b/*.proto"}
+	ctx := context.Background()
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	info := g.Cache.Packages[GetPackageKey(g.ModulePath, "a/a.proto")]
+	if info == nil {
+		t.Fatal("package a was not cached")
+	}
+	var imports []string
+	for importPath, hash := range info.Imports {
+		if hash == "" {
+			t.Fatalf("import %s was not resolved", importPath)
+		}
+		imports = append(imports, importPath)
+	}
+	slices.Sort(imports)
+	if want := []string{"example.com/other/c/c.proto", "example.com/project/b/b.proto"}; !slices.Equal(imports, want) {
+		t.Fatalf("unexpected import closure: %v", imports)
+	}
+
+	var out strings.Builder
+	g.Stdout = &out
+	g.Verbose = true
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if !strings.Contains(out.String(), "Skipping a (up to date)") {
+		t.Fatalf("unchanged tree was regenerated:\n%s", out.String())
+	}
+
+	// Changing a vendored import regenerates both of its importers.
+	vendored := filepath.Join(g.ProjectDir, "vendor", "example.com", "other", "c", "c.proto")
+	if err := os.WriteFile(vendored, []byte("syntax = \"proto3\";\npackage c;\nmessage C { int32 id = 1; }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	for _, dir := range []string{"a", "b"} {
+		if !strings.Contains(out.String(), "Imports of "+dir+" changed") {
+			t.Fatalf("importer %s was not regenerated:\n%s", dir, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if strings.Contains(out.String(), "changed") {
+		t.Fatalf("import closure was not updated after regeneration:\n%s", out.String())
+	}
+}
@@ -0,0 +1,484 @@
+package protogen
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Proto lint rules.
+const (
+	// LintPackageName requires a lower_snake_case package whose last element
+	// matches the Go package name of the file's directory.
+	LintPackageName = "package-name"
+	// LintGoPackage requires option go_package to match the import path of
+	// the file's package directory.
+	LintGoPackage = "go-package"
+	// LintEnumZeroValue requires the first value of every enum to be zero.
+	LintEnumZeroValue = "enum-zero-value"
+	// LintFieldName requires lower_snake_case field names.
+	LintFieldName = "field-name"
+	// LintServiceComment requires a leading comment on every service.
+	LintServiceComment = "service-comment"
+	// LintRPCComment requires a leading comment on every RPC method.
+	LintRPCComment = "rpc-comment"
+	// LintUnusedImport forbids imports that are not used by the file.
+	LintUnusedImport = "unused-import"
+)
+
+// LintRules lists every proto lint rule.
+var LintRules = []string{
+	LintPackageName,
+	LintGoPackage,
+	LintEnumZeroValue,
+	LintFieldName,
+	LintServiceComment,
+	LintRPCComment,
+	LintUnusedImport,
+}
+
+// lintIgnoreDirective in a leading comment disables the listed rules (or all
+// rules if none are listed) for the element and everything nested in it.
+// Example: "// aptre:lint:ignore field-name"
+const lintIgnoreDirective = "aptre:lint:ignore"
+
+var (
+	lowerSnakeCaseRe = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+	packagePartRe    = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	unusedImportRe   = regexp.MustCompile(`^(.+\.proto):(\d+):(\d+): warning: Import (.+) is unused\.$`)
+)
+
+// LintConfig configures the proto lint rules.
+type LintConfig struct {
+	// Rules are the enabled rules. Empty enables all rules.
+	Rules []string `json:"rules,omitempty" yaml:"rules,omitempty"`
+	// Except are rules to disable.
+	Except []string `json:"except,omitempty" yaml:"except,omitempty"`
+	// Ignore are project-relative file or directory globs excluded from all
+	// rules.
+	Ignore []string `json:"ignore,omitempty" yaml:"ignore,omitempty"`
+	// IgnoreOnly maps rules to project-relative file or directory globs
+	// excluded from that rule.
+	IgnoreOnly map[string][]string `json:"ignoreOnly,omitempty" yaml:"ignoreOnly,omitempty"`
+}
+
+// Validate checks that the config only references known rules and valid
+// globs.
+func (c *LintConfig) Validate() error {
+	rules := slices.Concat(c.Rules, c.Except)
+	for rule := range c.IgnoreOnly {
+		rules = append(rules, rule)
+	}
+	for _, rule := range rules {
+		if !slices.Contains(LintRules, rule) {
+			return fmt.Errorf("lint: unknown rule %q", rule)
+		}
+	}
+	if err := validateGlobs(c.Ignore); err != nil {
+		return fmt.Errorf("lint: %w", err)
+	}
+	for rule, patterns := range c.IgnoreOnly {
+		if err := validateGlobs(patterns); err != nil {
+			return fmt.Errorf("lint: %s: %w", rule, err)
+		}
+	}
+	return nil
+}
+
+// Enabled returns true if the rule applies to the project-relative file.
+func (c *LintConfig) Enabled(rule, file string) bool {
+	if len(c.Rules) != 0 && !slices.Contains(c.Rules, rule) {
+		return false
+	}
+	if slices.Contains(c.Except, rule) {
+		return false
+	}
+	return !matchesFileOrParent(file, c.Ignore) && !matchesFileOrParent(file, c.IgnoreOnly[rule])
+}
+
+// matchesFileOrParent returns true if the project-relative file or any of its
+// parent directories matches one of the globs.
+func matchesFileOrParent(file string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	for name := filepath.ToSlash(file); name != "."; name = path.Dir(name) {
+		if matchesAnyGlob(name, patterns) {
+			return true
+		}
+	}
+	return false
+}
+
+// LintDiagnostic is a proto lint finding.
+type LintDiagnostic struct {
+	// File is the project-relative proto file.
+	File string `json:"file"`
+	// Line is the 1-based line.
+	Line int `json:"line"`
+	// Column is the 1-based column, with tabs advancing to the next multiple
+	// of 8.
+	Column int `json:"column"`
+	// Rule is the lint rule that reported the finding.
+	Rule string `json:"rule"`
+	// Message describes the finding.
+	Message string `json:"message"`
+	// Fixable is set if --fix can correct the finding.
+	Fixable bool `json:"fixable,omitempty"`
+
+	fix *lintFix
+}
+
+// String formats the diagnostic as "file:line:col: message (rule)".
+func (d *LintDiagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", d.File, d.Line, d.Column, d.Message, d.Rule)
+}
+
+// lintFix replaces a span of a proto file. Lines and columns are zero-based
+// protoc source positions. Fixes must not change the wire format or the
+// generated source API.
+type lintFix struct {
+	startLine, startCol int
+	endLine, endCol     int
+	text                string
+}
+
+// LintProto compiles the discovered proto files with the embedded protoc and
+// checks them against the configured lint rules.
+func (g *Generator) LintProto(ctx context.Context) ([]LintDiagnostic, error) {
+	cfg := g.Config.Lint
+	if cfg == nil {
+		cfg = &LintConfig{}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	protoFiles, err := DiscoverProtoFiles(g.ProjectDir, g.Config.Targets, g.Config.Exclude, g.Config.IncludeUntracked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover proto files: %w", err)
+	}
+	if len(protoFiles) == 0 {
+		return nil, nil
+	}
+
+	scratchDir, err := os.MkdirTemp("", "aptre-lint-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scratchDir)
+
+	files, warnings, err := g.buildDescriptorSet(ctx, protoFiles, filepath.Join(scratchDir, "lint.binpb"))
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*protoLinter, len(files))
+	var linters []*protoLinter
+	for _, f := range files {
+		l := &protoLinter{
+			cfg:        cfg,
+			file:       f,
+			rel:        strings.TrimPrefix(f.Name, g.ModulePath+"/"),
+			modulePath: g.ModulePath,
+		}
+		l.lint()
+		byName[f.Name] = l
+		linters = append(linters, l)
+	}
+
+	// protoc reports unused imports as warnings on the vendored file path.
+	scanner := bufio.NewScanner(strings.NewReader(warnings))
+	for scanner.Scan() {
+		m := unusedImportRe.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+		name := filepath.ToSlash(m[1])
+		if rel, err := filepath.Rel(g.VendorDir, m[1]); err == nil && filepath.IsLocal(rel) {
+			name = filepath.ToSlash(rel)
+		}
+		if l := byName[name]; l != nil {
+			l.unusedImport(m[4])
+		}
+	}
+
+	var diags []LintDiagnostic
+	for _, l := range linters {
+		diags = append(diags, l.diags...)
+	}
+	slices.SortStableFunc(diags, func(a, b LintDiagnostic) int {
+		if a.File != b.File {
+			return strings.Compare(a.File, b.File)
+		}
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+	return diags, nil
+}
+
+// FixLintProto applies the fixes of the fixable diagnostics to the proto
+// files in the project. Returns the number of diagnostics fixed.
+func (g *Generator) FixLintProto(diags []LintDiagnostic) (int, error) {
+	byFile := make(map[string][]*lintFix)
+	var files []string
+	for _, d := range diags {
+		if d.fix == nil {
+			continue
+		}
+		if _, ok := byFile[d.File]; !ok {
+			files = append(files, d.File)
+		}
+		byFile[d.File] = append(byFile[d.File], d.fix)
+	}
+
+	var fixed int
+	for _, file := range files {
+		path := filepath.Join(g.ProjectDir, file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fixed, err
+		}
+		fixes := byFile[file]
+		// Apply from the end of the file so earlier positions stay valid.
+		slices.SortFunc(fixes, func(a, b *lintFix) int {
+			if a.startLine != b.startLine {
+				return b.startLine - a.startLine
+			}
+			return b.startCol - a.startCol
+		})
+		lines := strings.SplitAfter(string(data), "\n")
+		for _, fix := range fixes {
+			lines = applyLintFix(lines, fix)
+		}
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0o644); err != nil {
+			return fixed, err
+		}
+		fixed += len(fixes)
+	}
+	return fixed, nil
+}
+
+// applyLintFix replaces the span of the fix. Lines left blank by a deletion
+// are removed.
+func applyLintFix(lines []string, fix *lintFix) []string {
+	if fix.startLine >= len(lines) || fix.endLine >= len(lines) {
+		return lines
+	}
+	start := columnOffset(lines[fix.startLine], fix.startCol)
+	end := columnOffset(lines[fix.endLine], fix.endCol)
+	replaced := lines[fix.startLine][:start] + fix.text + lines[fix.endLine][end:]
+	rest := lines[fix.endLine+1:]
+	lines = append(lines[:fix.startLine], replaced)
+	if fix.text == "" && strings.TrimSpace(replaced) == "" {
+		lines = lines[:fix.startLine]
+		// Collapse the blank lines around the removed line.
+		if len(lines) != 0 && len(rest) != 0 && strings.TrimSpace(lines[len(lines)-1]) == "" && strings.TrimSpace(rest[0]) == "" {
+			rest = rest[1:]
+		}
+	}
+	return append(lines, rest...)
+}
+
+// columnOffset converts a protoc column, where tabs advance to the next
+// multiple of 8, to a byte offset in the line.
+func columnOffset(line string, col int) int {
+	c := 0
+	for i := 0; i < len(line); i++ {
+		if c >= col {
+			return i
+		}
+		if line[i] == '\t' {
+			c += 8 - c%8
+		} else {
+			c++
+		}
+	}
+	return len(line)
+}
+
+// protoLinter checks a single proto file.
+type protoLinter struct {
+	cfg        *LintConfig
+	file       *FileDescriptor
+	rel        string
+	modulePath string
+	diags      []LintDiagnostic
+}
+
+// report records a diagnostic at the element with the descriptor path unless
+// the rule is disabled for it.
+func (l *protoLinter) report(rule string, path []int32, fix *lintFix, format string, args ...any) {
+	if !l.cfg.Enabled(rule, l.rel) || l.ignored(rule, path) {
+		return
+	}
+	line, col := 1, 1
+	for p := path; ; p = p[:len(p)-2] {
+		if loc := l.file.location(p); loc != nil {
+			line, col = loc.startLine+1, loc.startCol+1
+			break
+		}
+		if len(p) < 2 {
+			break
+		}
+	}
+	l.diags = append(l.diags, LintDiagnostic{
+		File:    l.rel,
+		Line:    line,
+		Column:  col,
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+		Fixable: fix != nil,
+		fix:     fix,
+	})
+}
+
+// ignored returns true if the element or one of its parents has a leading
+// comment disabling the rule.
+func (l *protoLinter) ignored(rule string, path []int32) bool {
+	for p := path; len(p) != 0; p = p[:max(len(p)-2, 0)] {
+		for _, line := range strings.Split(l.file.LeadingComments(p), "\n") {
+			_, rest, ok := strings.Cut(line, lintIgnoreDirective)
+			if !ok {
+				continue
+			}
+			rules := strings.Fields(rest)
+			if len(rules) == 0 || slices.Contains(rules, rule) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lint runs the descriptor based rules.
+func (l *protoLinter) lint() {
+	l.lintPackage()
+	for _, m := range l.file.Messages {
+		l.lintMessage(m)
+	}
+	for _, e := range l.file.Enums {
+		l.lintEnum(e)
+	}
+	for _, s := range l.file.Services {
+		if strings.TrimSpace(l.file.LeadingComments(s.path)) == "" {
+			l.report(LintServiceComment, s.path, nil, "service %q should have a comment", s.Name)
+		}
+		for _, m := range s.Methods {
+			if strings.TrimSpace(l.file.LeadingComments(m.path)) == "" {
+				l.report(LintRPCComment, m.path, nil, "rpc %q on service %q should have a comment", m.Name, s.Name)
+			}
+		}
+	}
+}
+
+// lintPackage checks the package name and go_package option.
+func (l *protoLinter) lintPackage() {
+	pkgPath := []int32{2}
+	parts := strings.Split(l.file.Package, ".")
+	dir := path.Dir(filepath.ToSlash(l.rel))
+	switch {
+	case l.file.Package == "":
+		l.report(LintPackageName, nil, nil, "missing package declaration")
+	case slices.ContainsFunc(parts, func(part string) bool { return !packagePartRe.MatchString(part) }):
+		l.report(LintPackageName, pkgPath, nil, "package %q should be lower_snake_case", l.file.Package)
+	case dir != "." && parts[len(parts)-1] != goPackageName(dir):
+		l.report(LintPackageName, pkgPath, nil, "package %q should end in %q to match directory %s", l.file.Package, goPackageName(dir), dir)
+	}
+
+	if l.file.GoPackage == "" {
+		return
+	}
+	want := filepath.ToSlash(GetPackageKey(l.modulePath, l.rel))
+	importPath, name, hasName := strings.Cut(l.file.GoPackage, ";")
+	if importPath == want {
+		return
+	}
+	value := want
+	if hasName {
+		value += ";" + name
+	}
+	// Changing go_package moves the generated Go package, so it is not fixed.
+	l.report(LintGoPackage, []int32{8, 11}, nil, "go_package %q should be %q", l.file.GoPackage, value)
+}
+
+// lintMessage checks the fields and nested types of a message.
+func (l *protoLinter) lintMessage(m *MessageDescriptor) {
+	if !m.MapEntry {
+		for _, f := range m.Fields {
+			if lowerSnakeCaseRe.MatchString(f.Name) {
+				continue
+			}
+			// Renaming a field changes the generated identifiers and the JSON
+			// name, so it is not fixed.
+			l.report(LintFieldName, f.path, nil, "field %q on %q should be lower_snake_case (%s)", f.Name, m.Name, toSnakeCase(f.Name))
+		}
+	}
+	for _, nested := range m.Nested {
+		l.lintMessage(nested)
+	}
+	for _, e := range m.Enums {
+		l.lintEnum(e)
+	}
+}
+
+// lintEnum checks that the first enum value is zero.
+func (l *protoLinter) lintEnum(e *EnumDescriptor) {
+	if len(e.Values) == 0 || e.Values[0].Number == 0 {
+		return
+	}
+	l.report(LintEnumZeroValue, e.Values[0].path, nil, "first value of enum %q should be zero, got %s = %d", e.Name, e.Values[0].Name, e.Values[0].Number)
+}
+
+// unusedImport reports an import that protoc found unused.
+func (l *protoLinter) unusedImport(dep string) {
+	idx := slices.Index(l.file.Dependencies, dep)
+	if idx < 0 {
+		return
+	}
+	path := []int32{3, int32(idx)}
+	var fix *lintFix
+	if loc := l.file.location(path); loc != nil {
+		fix = &lintFix{
+			startLine: loc.startLine,
+			startCol:  loc.startCol,
+			endLine:   loc.endLine,
+			endCol:    loc.endCol,
+		}
+	}
+	l.report(LintUnusedImport, path, fix, "import %q is unused", dep)
+}
+
+// goPackageName returns the Go package name for a directory.
+func goPackageName(dir string) string {
+	name := strings.ToLower(path.Base(dir))
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// toSnakeCase converts a camelCase or PascalCase name to lower_snake_case,
+// keeping acronyms together (e.g. "peerID" to "peer_id").
+func toSnakeCase(name string) string {
+	isUpper := func(i int) bool { return name[i] >= 'A' && name[i] <= 'Z' }
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if isUpper(i) {
+			prevLower := i > 0 && name[i-1] != '_' && !isUpper(i-1)
+			acronymEnd := i > 0 && isUpper(i-1) && i+1 < len(name) && name[i+1] >= 'a' && name[i+1] <= 'z'
+			if prevLower || acronymEnd {
+				sb.WriteByte('_')
+			}
+			c += 'a' - 'A'
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,144 @@
+package protogen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLintProto(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"api/api.proto": `syntax = "proto2";
+package Api;
+
+option go_package = "example.com/other/api;api";
+
+import "example.com/project/common/common.proto";
+
+enum Kind {
+  KIND_A = 1;
+}
+
+message Req {
+	optional string peerID = 1;
+  // aptre:lint:ignore field-name
+  optional string legacyName = 2;
+}
+
+service Svc {
+  rpc Get(Req) returns (Req);
+}
+`,
+		"common/common.proto": "syntax = \"proto3\";\npackage common;\nmessage Empty {}\n",
+	})
+	ctx := context.Background()
+
+	diags, err := g.LintProto(ctx)
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	type key struct {
+		rule         string
+		line, column int
+		fixable      bool
+	}
+	var got []key
+	for _, d := range diags {
+		if d.File != "api/api.proto" {
+			t.Errorf("unexpected diagnostic in %s: %s", d.File, d.String())
+		}
+		got = append(got, key{d.Rule, d.Line, d.Column, d.Fixable})
+	}
+	want := []key{
+		{LintPackageName, 2, 1, false},
+		{LintGoPackage, 4, 1, false},
+		{LintUnusedImport, 6, 1, true},
+		{LintEnumZeroValue, 9, 3, false},
+		{LintFieldName, 13, 9, false},
+		{LintServiceComment, 18, 1, false},
+		{LintRPCComment, 19, 3, false},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got diagnostics %v, want %v", got, want)
+	}
+
+	fixed, err := g.FixLintProto(diags)
+	if err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	if fixed != 1 {
+		t.Fatalf("expected 1 fix, got %d", fixed)
+	}
+	data, err := os.ReadFile(filepath.Join(g.ProjectDir, "api", "api.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the unused import is removed; renames would change the API.
+	wantProto := `syntax = "proto2";
+package Api;
+
+option go_package = "example.com/other/api;api";
+
+enum Kind {
+  KIND_A = 1;
+}
+
+message Req {
+	optional string peerID = 1;
+  // aptre:lint:ignore field-name
+  optional string legacyName = 2;
+}
+
+service Svc {
+  rpc Get(Req) returns (Req);
+}
+`
+	if string(data) != wantProto {
+		t.Fatalf("unexpected fixed file:\n%s", data)
+	}
+
+	// The remaining findings are not fixable; ignore them via config.
+	g.Config.Lint = &LintConfig{
+		Except:     []string{LintEnumZeroValue, LintGoPackage, LintFieldName},
+		Ignore:     []string{"common"},
+		IgnoreOnly: map[string][]string{LintPackageName: {"api/*.proto"}, LintServiceComment: {"api"}, LintRPCComment: {"**"}},
+	}
+	diags, err = g.LintProto(ctx)
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	if len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics after fix: %v", diags)
+	}
+}
+
+func TestLintConfigValidate(t *testing.T) {
+	for name, cfg := range map[string]*LintConfig{
+		"unknown rule":        {Rules: []string{"nope"}},
+		"unknown except":      {Except: []string{"nope"}},
+		"unknown ignore rule": {IgnoreOnly: map[string][]string{"nope": {"a"}}},
+		"bad glob":            {Ignore: []string{"["}},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+	if err := (&LintConfig{Rules: LintRules}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestToSnakeCase(t *testing.T) {
+	for in, want := range map[string]string{
+		"peerID":     "peer_id",
+		"HTTPServer": "http_server",
+		"already_ok": "already_ok",
+		"fooBarBaz":  "foo_bar_baz",
+	} {
+		if got := toSnakeCase(in); got != want {
+			t.Errorf("toSnakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package protogen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ModuleCacheMountDir is the directory in the protoc filesystem where the
+// module cache directories of the dependencies are mounted read-only, each at
+// its module path. It is passed to protoc as an include path, so the flags do
+// not depend on the host's GOMODCACHE.
+const ModuleCacheMountDir = "/.aptre/gomodcache"
+
+// protobufModulePath is the module providing the well-known types in src.
+const protobufModulePath = "github.com/aperturerobotics/protobuf"
+
+// GoModule is a dependency module with its directory in the module cache.
+type GoModule struct {
+	// Path is the module path.
+	Path string `json:"Path"`
+	// Dir is the directory holding the module's files.
+	Dir string `json:"Dir"`
+}
+
+// goListModule is a module printed by go list -m -json.
+type goListModule struct {
+	GoModule
+	Main    bool      `json:"Main"`
+	Replace *GoModule `json:"Replace"`
+}
+
+// ListGoModules returns the dependencies of the module in moduleDir that are
+// present in the module cache, using go list -m -json. Main modules and
+// modules that are not downloaded are skipped.
+func ListGoModules(moduleDir string) ([]*GoModule, error) {
+	cmd := exec.Command("go", "list", "-m", "-json", "-e", "all")
+	cmd.Dir = moduleDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list -m: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var modules []*GoModule
+	dec := json.NewDecoder(&stdout)
+	for {
+		var m goListModule
+		if err := dec.Decode(&m); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse go list output: %w", err)
+		}
+		dir := m.Dir
+		if dir == "" && m.Replace != nil {
+			dir = m.Replace.Dir
+		}
+		if m.Main || dir == "" {
+			continue
+		}
+		modules = append(modules, &GoModule{Path: m.Path, Dir: dir})
+	}
+	return modules, nil
+}
+
+// moduleCache resolves proto imports from the module cache. The module list
+// is loaded on first use.
+type moduleCache struct {
+	moduleDir string
+
+	once    sync.Once
+	modules []*GoModule
+	byPath  map[string]*GoModule
+}
+
+// newModuleCache returns the module cache resolver for a generator, or nil if
+// the project vendors its dependencies.
+func newModuleCache(moduleDir, vendorDir string) *moduleCache {
+	if _, err := os.Stat(filepath.Join(vendorDir, "modules.txt")); err == nil {
+		return nil
+	}
+	return &moduleCache{moduleDir: moduleDir}
+}
+
+// goModules returns the module cache resolver with the modules loaded, or nil
+// if the project vendors its dependencies. Failing to list the modules is
+// reported to g.Stderr.
+func (g *Generator) goModules() *moduleCache {
+	return g.moduleCache.load(g.Stderr)
+}
+
+// load lists the modules once and returns the cache. Failures are reported as
+// a warning to stderr and leave the cache empty so generation falls back to
+// the vendor directory.
+func (c *moduleCache) load(stderr io.Writer) *moduleCache {
+	if c == nil {
+		return nil
+	}
+	c.once.Do(func() {
+		modules, err := ListGoModules(c.moduleDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "warning: failed to resolve imports from the Go module cache: %v\n", err)
+			return
+		}
+		c.modules = modules
+		c.byPath = make(map[string]*GoModule, len(modules))
+		for _, m := range modules {
+			c.byPath[m.Path] = m
+		}
+	})
+	return c
+}
+
+// Modules returns the modules in the cache.
+func (c *moduleCache) Modules() []*GoModule {
+	if c == nil {
+		return nil
+	}
+	return c.modules
+}
+
+// Dir returns the directory of a module, or an empty string.
+func (c *moduleCache) Dir(modulePath string) string {
+	if c == nil || c.byPath[modulePath] == nil {
+		return ""
+	}
+	return c.byPath[modulePath].Dir
+}
+
+// moduleFor returns the module containing an import path, or nil. The longest
+// matching module path wins like it does for Go packages.
+func (c *moduleCache) moduleFor(importPath string) *GoModule {
+	if c == nil {
+		return nil
+	}
+	for modulePath := path.Dir(importPath); modulePath != "." && modulePath != "/"; modulePath = path.Dir(modulePath) {
+		if m, ok := c.byPath[modulePath]; ok {
+			return m
+		}
+	}
+	return nil
+}
+
+// resolve returns the file in the module cache for an import path, or an
+// empty string.
+func (c *moduleCache) resolve(importPath string) string {
+	m := c.moduleFor(importPath)
+	if m == nil {
+		return ""
+	}
+	rel := strings.TrimPrefix(importPath, m.Path+"/")
+	return filepath.Join(m.Dir, filepath.FromSlash(rel))
+}
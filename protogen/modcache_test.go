@@ -0,0 +1,106 @@
+package protogen
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGenerateResolvesModuleCacheImports(t *testing.T) {
+	root, _ := writeProtoTree(t, map[string]string{
+		"project/go.mod":        "module example.com/project\n\ngo 1.24\n\nrequire (\n\texample.com/dep v0.0.0\n\texample.com/unused v0.0.0\n)\n\nreplace example.com/dep => ../dep\n\nreplace example.com/unused => ../unused\n",
+		"project/api/api.proto": "syntax = \"proto3\";\npackage api;\nimport \"example.com/dep/types/types.proto\";\nmessage Api { types.T t = 1; }\n",
+		"dep/go.mod":            "module example.com/dep\n\ngo 1.24\n",
+		"dep/types/types.proto": "syntax = \"proto3\";\npackage types;\nmessage T {}\n",
+		"unused/go.mod":         "module example.com/unused\n\ngo 1.24\n",
+	})
+	dir := filepath.Join(root, "project")
+	t.Setenv("GOWORK", "off")
+	t.Setenv("GOFLAGS", "-mod=mod")
+
+	modules, err := ListGoModules(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameModules := func(a, b *GoModule) bool { return *a == *b }
+	dep := &GoModule{Path: "example.com/dep", Dir: filepath.Join(root, "dep")}
+	unused := &GoModule{Path: "example.com/unused", Dir: filepath.Join(root, "unused")}
+	if !slices.EqualFunc(modules, []*GoModule{dep, unused}, sameModules) {
+		t.Fatalf("unexpected modules: %v", modules)
+	}
+
+	cfg := NewConfig()
+	cfg.ProjectDir = dir
+	cfg.Targets = []string{"*.proto"}
+	cfg.WasmCacheDir = t.TempDir()
+	vendorDir := filepath.Join(dir, "vendor")
+	g := &Generator{
+		Config:      cfg,
+		Plugins:     &Plugins{Languages: Languages{LanguageCpp: {}}, RPCLibraries: RPCLibraries{}},
+		Cache:       NewCache(),
+		ProjectDir:  dir,
+		ModuleDir:   dir,
+		ModulePath:  "example.com/project",
+		VendorDir:   vendorDir,
+		OutDir:      vendorDir,
+		Stdout:      io.Discard,
+		Stderr:      io.Discard,
+		moduleCache: newModuleCache(dir, vendorDir),
+	}
+	if !slices.Contains(g.protocIncludeArgs(), ModuleCacheMountDir) {
+		t.Fatalf("module cache is not an include path: %v", g.protocIncludeArgs())
+	}
+	// Only the modules the imports resolve into are mounted.
+	mounted, err := g.protocModules([]string{"api/api.proto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.EqualFunc(mounted, []*GoModule{dep}, sameModules) {
+		t.Fatalf("unexpected mounted modules: %v", mounted)
+	}
+	if err := g.Generate(context.Background()); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "api", "api.pb.cc")); err != nil {
+		t.Fatalf("missing generated file: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "dep", "types", "types.pb.cc")); !os.IsNotExist(err) {
+		t.Fatal("generated into the module cache")
+	}
+	importKey := "example.com/dep/types/types.proto"
+	if info := g.Cache.Packages[GetPackageKey(g.ModulePath, "api/api.proto")]; info == nil || info.Imports[importKey] == "" {
+		t.Fatalf("module cache import was not hashed: %+v", info)
+	}
+
+	// Vendored projects do not use the module cache.
+	if err := os.WriteFile(filepath.Join(vendorDir, "modules.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if newModuleCache(dir, vendorDir) != nil {
+		t.Fatal("module cache used for a vendored project")
+	}
+}
+
+func TestModuleCacheWarnsToGeneratorStderr(t *testing.T) {
+	dir, _ := writeProtoTree(t, map[string]string{
+		"go.mod": "module example.com/project\n\nnot a directive\n",
+	})
+	t.Setenv("GOWORK", "off")
+	t.Setenv("GOFLAGS", "-mod=mod")
+
+	var stderr bytes.Buffer
+	g := &Generator{moduleCache: newModuleCache(dir, filepath.Join(dir, "vendor"))}
+	// Stderr is set after construction like the CLI does.
+	g.Stderr = &stderr
+	if modules := g.goModules().Modules(); len(modules) != 0 {
+		t.Fatalf("unexpected modules: %v", modules)
+	}
+	if !strings.Contains(stderr.String(), "warning: failed to resolve imports from the Go module cache") {
+		t.Fatalf("missing warning: %q", stderr.String())
+	}
+}
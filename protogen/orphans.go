@@ -0,0 +1,155 @@
+package protogen
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// generatedHeaderMarkers identify a generated file in its header.
+var generatedHeaderMarkers = [][]byte{
+	[]byte("@generated"),
+	[]byte("Code generated"),
+	[]byte("Generated by the protocol buffer compiler"),
+}
+
+// generatedHeaderSize is the number of leading bytes searched for a marker.
+const generatedHeaderSize = 1024
+
+// removeOrphanedOutputs removes the generated files of cached packages whose
+// proto files no longer exist and drops them from the cache. Packages in
+// current are kept. Returns the removed project-relative files, sorted.
+func (g *Generator) removeOrphanedOutputs(current map[string]struct{}) ([]string, error) {
+	var removed []string
+	for key, info := range g.Cache.Packages {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		if slices.ContainsFunc(info.ProtoFiles, func(f string) bool {
+			_, err := os.Stat(filepath.Join(g.ProjectDir, f))
+			return err == nil
+		}) {
+			continue
+		}
+		for _, f := range info.GeneratedFiles {
+			err := os.Remove(filepath.Join(g.ProjectDir, f))
+			if err == nil {
+				removed = append(removed, f)
+			} else if !os.IsNotExist(err) {
+				return removed, err
+			}
+		}
+		delete(g.Cache.Packages, key)
+	}
+	slices.Sort(removed)
+	return removed, nil
+}
+
+// CleanOrphans removes the generated files recorded in the cache whose source
+// proto files no longer exist and saves the cache. Returns the removed
+// project-relative files.
+func (g *Generator) CleanOrphans() ([]string, error) {
+	removed, err := g.removeOrphanedOutputs(nil)
+	if err != nil {
+		return removed, err
+	}
+	cacheFile, err := g.Config.GetCacheFilePath()
+	if err != nil {
+		return removed, err
+	}
+	return removed, g.Cache.Save(cacheFile)
+}
+
+// FindUnclaimedGeneratedFiles returns the project-relative files under the
+// project directory that look generated (by file name and header) but are not
+// recorded in the cache, sorted. The directories skipped by proto discovery,
+// the tools directory and nested Go modules are skipped, so the outputs of
+// other modules are never reported.
+func (g *Generator) FindUnclaimedGeneratedFiles() ([]string, error) {
+	claimed := make(map[string]struct{})
+	infos := slices.Collect(maps.Values(g.Cache.Packages))
+	if g.Cache.DescriptorSet != nil {
+		infos = append(infos, g.Cache.DescriptorSet)
+	}
+	for _, info := range infos {
+		for _, f := range info.GeneratedFiles {
+			claimed[filepath.Clean(f)] = struct{}{}
+		}
+	}
+
+	var skipDirs []string
+	if toolsRel := filepath.Clean(g.Config.ToolsDir); filepath.IsLocal(toolsRel) {
+		skipDirs = append(skipDirs, filepath.ToSlash(toolsRel))
+	}
+	candidates, err := walkProjectFiles(g.ProjectDir, func(entry fs.DirEntry) bool {
+		return entry.Type().IsRegular() && hasGeneratedFileSuffix(entry.Name())
+	}, skipDirs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var unclaimed []string
+	for _, f := range candidates {
+		rel := filepath.FromSlash(f)
+		if _, ok := claimed[rel]; ok {
+			continue
+		}
+		generated, err := hasGeneratedHeader(filepath.Join(g.ProjectDir, rel))
+		if err != nil {
+			return nil, err
+		}
+		if generated {
+			unclaimed = append(unclaimed, rel)
+		}
+	}
+	return unclaimed, nil
+}
+
+// RemoveProjectFiles removes the project-relative files.
+func (g *Generator) RemoveProjectFiles(files []string) error {
+	for _, f := range files {
+		if err := os.Remove(filepath.Join(g.ProjectDir, f)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasGeneratedFileSuffix returns true if the file name matches an output of
+// a built-in generator. C# outputs are not matched since their suffix is
+// shared with hand-written sources.
+func hasGeneratedFileSuffix(name string) bool {
+	for _, kind := range generatedFileKinds {
+		if kind.lang != LanguageCSharp && strings.HasSuffix(name, kind.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasGeneratedHeader returns true if the file starts with a generated code
+// marker.
+func hasGeneratedHeader(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, generatedHeaderSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	for _, marker := range generatedHeaderMarkers {
+		if bytes.Contains(buf[:n], marker) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
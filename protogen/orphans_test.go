@@ -0,0 +1,96 @@
+package protogen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGenerateRemovesOrphanedOutputs(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"a/a.proto": "syntax = \"proto3\";\npackage a;\nmessage A {}\n",
+		"b/b.proto": "syntax = \"proto3\";\npackage b;\nmessage B {}\n",
+		"c/c.proto": "syntax = \"proto3\";\npackage c;\nmessage C {}\n",
+	})
+	ctx := context.Background()
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	// Generate removes the outputs of a deleted proto file.
+	if err := os.Remove(filepath.Join(g.ProjectDir, "b", "b.proto")); err != nil {
+		t.Fatal(err)
+	}
+	commitAll(t, g.ProjectDir)
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	for _, f := range []string{"b.pb.cc", "b.pb.h"} {
+		if _, err := os.Stat(filepath.Join(g.ProjectDir, "b", f)); !os.IsNotExist(err) {
+			t.Fatalf("orphaned b/%s was not removed", f)
+		}
+	}
+	if _, ok := g.Cache.Packages[GetPackageKey(g.ModulePath, "b/b.proto")]; ok {
+		t.Fatal("orphaned package was not removed from the cache")
+	}
+
+	// Excluded packages whose proto files still exist keep their outputs.
+	g.Config.Exclude = []string{"a/*"}
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(g.ProjectDir, "a", "a.pb.cc")); err != nil {
+		t.Fatal("outputs of an excluded package were removed")
+	}
+
+	// CleanOrphans removes the outputs without generating.
+	if err := os.Remove(filepath.Join(g.ProjectDir, "c", "c.proto")); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := g.CleanOrphans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(removed, []string{"c/c.pb.cc", "c/c.pb.h"}) {
+		t.Fatalf("unexpected removed files: %v", removed)
+	}
+}
+
+func TestFindUnclaimedGeneratedFiles(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"a/a.proto":                    "syntax = \"proto3\";\npackage a;\nmessage A {}\n",
+		"old/old.pb.go":                "// Code generated by protoc-gen-go-lite. DO NOT EDIT.\npackage old\n",
+		"old/old.pb.ts":                "// @generated by protoc-gen-es-lite\nexport {}\n",
+		"old/handwritten.pb.go":        "package old\n",
+		"old/notes.go":                 "// Code generated by hand. DO NOT EDIT.\npackage old\n",
+		"node_modules/x/x.pb.ts":       "// @generated by protoc-gen-es-lite\n",
+		"vendor/example.com/y/y.pb.go": "// Code generated by protoc-gen-go-lite. DO NOT EDIT.\npackage y\n",
+		".gitignore":                   "/ignored/\n",
+		"ignored/i.pb.go":              "// Code generated by protoc-gen-go-lite. DO NOT EDIT.\npackage i\n",
+		"nested/go.mod":                "module example.com/nested\n",
+		"nested/api/api.pb.go":         "// Code generated by protoc-gen-go-lite. DO NOT EDIT.\npackage api\n",
+	})
+	if err := g.Generate(context.Background()); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	unclaimed, err := g.FindUnclaimedGeneratedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(unclaimed, []string{"old/old.pb.go", "old/old.pb.ts"}) {
+		t.Fatalf("unexpected unclaimed files: %v", unclaimed)
+	}
+	if err := g.RemoveProjectFiles(unclaimed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(g.ProjectDir, "old", "old.pb.go")); !os.IsNotExist(err) {
+		t.Fatal("unclaimed file was not removed")
+	}
+	// The outputs of nested modules belong to their own manifests.
+	if _, err := os.Stat(filepath.Join(g.ProjectDir, "nested", "api", "api.pb.go")); err != nil {
+		t.Fatalf("nested module output was removed: %v", err)
+	}
+}
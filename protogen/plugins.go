@@ -5,11 +5,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	prost "github.com/aperturerobotics/go-protoc-gen-prost"
 	"github.com/tetratelabs/wazero"
@@ -24,6 +27,7 @@ const (
 	PluginTypeTypeScript
 	PluginTypeCpp
 	PluginTypeRust
+	PluginTypeCustom
 )
 
 // Plugin represents a protoc plugin configuration.
@@ -40,6 +44,13 @@ type Plugin struct {
 	OutFlag string
 	// Options are the plugin options.
 	Options map[string]string
+	// OutDir overrides the output directory when set.
+	OutDir string
+	// Match restricts the plugin to package directories matching these globs.
+	Match []string
+	// Outputs are the project-relative generated file globs for each proto
+	// file, with {dir} and {name} placeholders.
+	Outputs []string
 }
 
 // Plugins holds the configured plugins for a project.
@@ -65,6 +76,8 @@ type Plugins struct {
 	// RustProst is the protoc-gen-prost plugin for Rust protobuf types.
 	// This uses an embedded WASM module, no external binary required.
 	RustProst *Plugin
+	// Custom contains the plugins declared in the configuration.
+	Custom []*Plugin
 }
 
 func discoverNodePlugin(projectDir, binaryName string) string {
@@ -142,8 +155,7 @@ func DiscoverPlugins(cfg *Config) (*Plugins, error) {
 
 	if hasGo && langs.Has(LanguageGo) {
 		// Go plugins from tools bin
-		goLitePath := filepath.Join(toolsBin, "protoc-gen-go-lite")
-		if _, err := os.Stat(goLitePath); err == nil {
+		if goLitePath := findToolsPlugin(toolsBin, "protoc-gen-go-lite"); goLitePath != "" {
 			plugins.GoLite = &Plugin{
 				Name:       "go-lite",
 				BinaryName: "protoc-gen-go-lite",
@@ -157,8 +169,7 @@ func DiscoverPlugins(cfg *Config) (*Plugins, error) {
 		}
 
 		if rpcs.Has(RPCLibraryStarpc) {
-			goStarpcPath := filepath.Join(toolsBin, "protoc-gen-go-starpc")
-			if _, err := os.Stat(goStarpcPath); err == nil {
+			if goStarpcPath := findToolsPlugin(toolsBin, "protoc-gen-go-starpc"); goStarpcPath != "" {
 				plugins.GoStarpc = &Plugin{
 					Name:       "go-starpc",
 					BinaryName: "protoc-gen-go-starpc",
@@ -192,8 +203,7 @@ func DiscoverPlugins(cfg *Config) (*Plugins, error) {
 	}
 
 	if hasGo && langs.Has(LanguageCpp) && rpcs.Has(RPCLibraryStarpc) {
-		cppStarpcPath := filepath.Join(toolsBin, "protoc-gen-starpc-cpp")
-		if _, err := os.Stat(cppStarpcPath); err == nil {
+		if cppStarpcPath := findToolsPlugin(toolsBin, "protoc-gen-starpc-cpp"); cppStarpcPath != "" {
 			plugins.CppStarpc = &Plugin{
 				Name:       "starpc-cpp",
 				BinaryName: "protoc-gen-starpc-cpp",
@@ -207,8 +217,7 @@ func DiscoverPlugins(cfg *Config) (*Plugins, error) {
 
 	if hasGo && langs.Has(LanguageRust) {
 		if rpcs.Has(RPCLibraryStarpc) {
-			rustStarpcPath := filepath.Join(toolsBin, "protoc-gen-starpc-rust")
-			if _, err := os.Stat(rustStarpcPath); err == nil {
+			if rustStarpcPath := findToolsPlugin(toolsBin, "protoc-gen-starpc-rust"); rustStarpcPath != "" {
 				plugins.RustStarpc = &Plugin{
 					Name:       "starpc-rust",
 					BinaryName: "protoc-gen-starpc-rust",
@@ -240,8 +249,11 @@ func DiscoverPlugins(cfg *Config) (*Plugins, error) {
 	}
 
 	if hasTS && langs.Has(LanguageTypeScript) {
-		// TypeScript plugins from node_modules
+		// TypeScript plugins from node_modules, or WASI builds in the tools bin
 		esLitePath := discoverNodePlugin(projectDir, "protoc-gen-es-lite")
+		if esLitePath == "" {
+			esLitePath = findToolsPlugin(toolsBin, "protoc-gen-es-lite")
+		}
 		if esLitePath != "" {
 			plugins.ESLite = &Plugin{
 				Name:       "es-lite",
@@ -258,6 +270,9 @@ func DiscoverPlugins(cfg *Config) (*Plugins, error) {
 
 		if rpcs.Has(RPCLibraryStarpc) {
 			esStarpcPath := discoverNodePlugin(projectDir, "protoc-gen-es-starpc")
+			if esStarpcPath == "" {
+				esStarpcPath = findToolsPlugin(toolsBin, "protoc-gen-es-starpc")
+			}
 			if esStarpcPath != "" {
 				plugins.ESStarpc = &Plugin{
 					Name:       "es-starpc",
@@ -274,6 +289,11 @@ func DiscoverPlugins(cfg *Config) (*Plugins, error) {
 		}
 	}
 
+	plugins.Custom, err = discoverCustomPlugins(cfg, projectDir, toolsBin, langs)
+	if err != nil {
+		return nil, err
+	}
+
 	return plugins, nil
 }
 
@@ -352,6 +372,16 @@ func (p *Plugins) GetProtocArgs(outDir, csharpOutDir string) []string {
 		args = append(args, fmt.Sprintf("--%s=%s", p.RustStarpc.OutFlag, outDir))
 	}
 
+	// Custom plugins in configuration order
+	for _, custom := range p.Custom {
+		customOutDir := outDir
+		if custom.OutDir != "" {
+			customOutDir = custom.OutDir
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", custom.OutFlag, customOutDir))
+		args = append(args, sortedPluginOpts(custom)...)
+	}
+
 	return args
 }
 
@@ -368,13 +398,25 @@ func (p *Plugins) HasTSPlugins() bool {
 // NativePluginHandler implements go-protoc-wasi's PluginHandler interface.
 // It spawns native plugin processes and handles IPC.
 // For protoc-gen-prost, it uses the embedded WASM module instead of a native binary.
+// Plugins resolved to .wasm files run as WASI modules in the protoc runtime.
 type NativePluginHandler struct {
 	// Plugins is the configured plugins.
 	Plugins *Plugins
 	// Verbose enables verbose output.
 	Verbose bool
+	// Stderr receives the stderr of WASM plugins in verbose mode. Nil
+	// discards it.
+	Stderr io.Writer
+	// OnPlugin is called after each plugin invocation if set.
+	OnPlugin func(program string, d time.Duration, err error)
 	// prostWASM is the prost WASM plugin instance (lazily initialized).
 	prostWASM *prost.ProtocGenProst
+	// runtime is the wazero runtime WASM plugins are instantiated in.
+	runtime wazero.Runtime
+	// mtx guards wasmModules.
+	mtx sync.Mutex
+	// wasmModules caches compiled WASM plugin modules by path.
+	wasmModules map[string]wazero.CompiledModule
 }
 
 // NewNativePluginHandler creates a new NativePluginHandler.
@@ -400,6 +442,32 @@ func (h *NativePluginHandler) InitProstWASM(ctx context.Context, runtime wazero.
 	return nil
 }
 
+// InitWASM prepares the handler to run WASM plugins in the given runtime and
+// initializes the prost WASM plugin if rust prost is configured.
+// The runtime must already have WASI instantiated (e.g., by protoc).
+func (h *NativePluginHandler) InitWASM(ctx context.Context, runtime wazero.Runtime) error {
+	h.runtime = runtime
+	if h.Plugins != nil && h.Plugins.RustProst != nil {
+		return h.InitProstWASM(ctx, runtime)
+	}
+	return nil
+}
+
+// CloseWASM closes the prost WASM plugin and the compiled WASM plugins.
+func (h *NativePluginHandler) CloseWASM(ctx context.Context) error {
+	err := h.CloseProstWASM(ctx)
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	for path, compiled := range h.wasmModules {
+		if cerr := compiled.Close(ctx); cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(h.wasmModules, path)
+	}
+	h.runtime = nil
+	return err
+}
+
 // CloseProstWASM closes the prost WASM plugin if initialized.
 func (h *NativePluginHandler) CloseProstWASM(ctx context.Context) error {
 	if h.prostWASM != nil {
@@ -415,6 +483,17 @@ func (h *NativePluginHandler) CloseProstWASM(ctx context.Context) error {
 // and returns the CodeGeneratorResponse from stdout.
 // For protoc-gen-prost, it uses the embedded WASM module if initialized.
 func (h *NativePluginHandler) Communicate(ctx context.Context, program string, searchPath bool, input []byte) ([]byte, error) {
+	if h.OnPlugin == nil {
+		return h.communicate(ctx, program, searchPath, input)
+	}
+	start := time.Now()
+	out, err := h.communicate(ctx, program, searchPath, input)
+	h.OnPlugin(program, time.Since(start), err)
+	return out, err
+}
+
+// communicate runs a plugin and returns its CodeGeneratorResponse.
+func (h *NativePluginHandler) communicate(ctx context.Context, program string, searchPath bool, input []byte) ([]byte, error) {
 	// Use WASM prost plugin if available
 	if program == "protoc-gen-prost" && h.prostWASM != nil {
 		return h.prostWASM.Execute(ctx, input)
@@ -425,6 +504,9 @@ func (h *NativePluginHandler) Communicate(ctx context.Context, program string, s
 	if pluginPath == "" {
 		return nil, fmt.Errorf("plugin not found: %s", program)
 	}
+	if strings.HasSuffix(pluginPath, wasmPluginExt) {
+		return h.runWASMPlugin(ctx, program, pluginPath, input)
+	}
 
 	cmd := exec.CommandContext(ctx, pluginPath)
 	cmd.Stdin = bytes.NewReader(input)
@@ -481,6 +563,11 @@ func (h *NativePluginHandler) findPluginPath(program string, searchPath bool) st
 				return h.Plugins.RustProst.Path
 			}
 		}
+		for _, custom := range h.Plugins.Custom {
+			if custom.BinaryName == program {
+				return custom.Path
+			}
+		}
 	}
 
 	// Fall back to PATH search if allowed
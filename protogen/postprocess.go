@@ -15,17 +15,6 @@ const (
 	CppBuildTag = "//go:build deps_only && cgo"
 )
 
-// goImportRemaps maps standard protobuf Go imports to protobuf-go-lite equivalents.
-var goImportRemaps = map[string]string{
-	"google.golang.org/protobuf/types/known/emptypb":     "github.com/aperturerobotics/protobuf-go-lite/types/known/emptypb",
-	"google.golang.org/protobuf/types/known/anypb":       "github.com/aperturerobotics/protobuf-go-lite/types/known/anypb",
-	"google.golang.org/protobuf/types/known/durationpb":  "github.com/aperturerobotics/protobuf-go-lite/types/known/durationpb",
-	"google.golang.org/protobuf/types/known/timestamppb": "github.com/aperturerobotics/protobuf-go-lite/types/known/timestamppb",
-	"google.golang.org/protobuf/types/known/wrapperspb":  "github.com/aperturerobotics/protobuf-go-lite/types/known/wrapperspb",
-	"google.golang.org/protobuf/types/known/structpb":    "github.com/aperturerobotics/protobuf-go-lite/types/known/structpb",
-	"google.golang.org/protobuf/types/known/fieldmaskpb": "github.com/aperturerobotics/protobuf-go-lite/types/known/fieldmaskpb",
-}
-
 // PostProcessor handles post-processing of generated files.
 type PostProcessor struct {
 	// ProjectDir is the project directory.
@@ -35,6 +24,9 @@ type PostProcessor struct {
 	// TsImportBoundaries are module-relative prefixes that trigger @go/ rewrites
 	// when generated TS protobuf imports cross between them.
 	TsImportBoundaries []string
+	// GoImportRemaps maps Go import paths to their replacements in generated
+	// Go files. If nil, the built-in protobuf-go-lite remaps are used.
+	GoImportRemaps map[string]string
 	// VendorDir is the vendor directory path.
 	VendorDir string
 	// Verbose enables verbose output.
@@ -197,29 +189,23 @@ func (p *PostProcessor) ProcessCppFile(filePath string) error {
 }
 
 // ProcessGoFile processes a Go file.
-// Rewrites standard protobuf imports to protobuf-go-lite equivalents.
+// Rewrites the remapped import specs, such as the standard protobuf imports,
+// to their protobuf-go-lite equivalents.
 func (p *PostProcessor) ProcessGoFile(filePath string) error {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return err
 	}
 
-	content := string(data)
-	modified := false
-
-	// Replace each import path
-	for oldImport, newImport := range goImportRemaps {
-		if strings.Contains(content, oldImport) {
-			content = strings.ReplaceAll(content, oldImport, newImport)
-			modified = true
-		}
+	remaps := p.GoImportRemaps
+	if remaps == nil {
+		remaps = goImportRemaps
 	}
-
-	if modified {
-		return os.WriteFile(filePath, []byte(content), 0o644) //nolint:gosec
+	rewritten, err := rewriteGoImports(filePath, data, remaps)
+	if err != nil || rewritten == nil {
+		return err
 	}
-
-	return nil
+	return os.WriteFile(filePath, rewritten, 0o644) //nolint:gosec
 }
 
 // ProcessPythonFile rewrites canonical Go module imports to the module-relative
@@ -299,6 +285,9 @@ func (p *PostProcessor) pythonImportPrefixes() []string {
 // For example, a file generated from "github.com/aperturerobotics/bifrost/daemon/api/api.proto"
 // might import from "../../../controllerbus/bus/api/api.pb.js" which needs to be rewritten
 // to "@go/github.com/aperturerobotics/controllerbus/bus/api/api.pb.js".
+//
+// The module specifiers of all import and export declarations and dynamic
+// imports are rewritten, see tsModuleSpecifiers.
 func (p *PostProcessor) ProcessTsFile(filePath string) error {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
@@ -319,95 +308,72 @@ func (p *PostProcessor) ProcessTsFile(filePath string) error {
 	// Get the directory of the proto file (e.g., "github.com/aperturerobotics/bifrost/daemon/api")
 	protoDir := filepath.Dir(sourceProtoPath)
 
-	// Pattern to match any relative imports starting with ../
-	importPattern := regexp.MustCompile(`from\s+"(\.\.\/[^"]+)"`)
-
 	modified := false
-	var result bytes.Buffer
-	scanner := bufio.NewScanner(bytes.NewReader(data))
+	var result strings.Builder
+	last := 0
+	for _, spec := range tsModuleSpecifiers(tokenizeTs(content)) {
+		goImportPath, ok := p.rewriteTsImport(fileDir, protoDir, spec.text)
+		if !ok {
+			continue
+		}
+		result.WriteString(content[last:spec.start])
+		result.WriteString(goImportPath)
+		last = spec.end
+		modified = true
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		lineModified := false
-		matches := importPattern.FindStringSubmatch(line)
+	if modified {
+		result.WriteString(content[last:])
+		return os.WriteFile(filePath, []byte(result.String()), 0o644)
+	}
 
-		if len(matches) > 1 {
-			importPath := matches[1]
-
-			// Resolve the import path relative to the proto directory to get the full Go import path.
-			// For example:
-			//   protoDir = "github.com/aperturerobotics/bifrost/daemon/api"
-			//   importPath = "../../../controllerbus/bus/api/api.pb.js"
-			//   result = "github.com/aperturerobotics/controllerbus/bus/api/api.pb.js"
-			resolvedPath := resolveRelativeImport(protoDir, importPath)
-
-			// Check if this resolves to outside our module (i.e., it's a vendor dependency)
-			if !strings.HasPrefix(resolvedPath, p.ModulePath) {
-				// This is an external import - verify it exists in vendor and rewrite to @go/ format
-				vendorFilePath := filepath.Join(p.VendorDir, resolvedPath)
-				// Check for .ts file (the .js extension in import maps to .ts source)
-				tsPath := strings.TrimSuffix(vendorFilePath, ".js") + ".ts"
-
-				if fileExists(tsPath) || fileExists(vendorFilePath) {
-					goImportPath := "@go/" + resolvedPath
-					newLine := strings.Replace(line, importPath, goImportPath, 1)
-					if newLine != line {
-						line = newLine
-						lineModified = true
-						modified = true
-					}
-				}
-			} else {
-				// This is an internal import within the same module.
-				// Check if it resolves to the vendor directory (self-referencing via full path).
-				absImportPath := filepath.Clean(filepath.Join(fileDir, importPath))
-				relToProject, err := filepath.Rel(p.ProjectDir, absImportPath)
-				if err == nil && strings.HasPrefix(relToProject, "vendor/") {
-					vendorPath := strings.TrimPrefix(relToProject, "vendor/")
-					goImportPath := "@go/" + filepath.ToSlash(vendorPath)
-					newLine := strings.Replace(line, importPath, goImportPath, 1)
-					if newLine != line {
-						line = newLine
-						lineModified = true
-						modified = true
-					}
-				}
+	return nil
+}
 
-				if !lineModified {
-					sourceBoundary, hasSourceBoundary := p.lookupTsImportBoundary(strings.TrimPrefix(strings.TrimPrefix(protoDir, p.ModulePath), "/"))
-					targetBoundary, hasTargetBoundary := p.lookupTsImportBoundary(strings.TrimPrefix(strings.TrimPrefix(strings.TrimSuffix(resolvedPath, ".js"), p.ModulePath), "/"))
-					if hasSourceBoundary && hasTargetBoundary && sourceBoundary != targetBoundary {
-						goImportPath := "@go/" + resolvedPath
-						newLine := strings.Replace(line, importPath, goImportPath, 1)
-						if newLine != line {
-							line = newLine
-							lineModified = true
-							modified = true
-						}
-					}
-				}
-				// Otherwise, leave internal relative imports as-is.
-			}
-		}
+// rewriteTsImport returns the @go/ path for a relative import in a file in
+// fileDir generated from a proto file in protoDir. Returns false if the import
+// should stay as-is.
+func (p *PostProcessor) rewriteTsImport(fileDir, protoDir, importPath string) (string, bool) {
+	if !strings.HasPrefix(importPath, "./") && !strings.HasPrefix(importPath, "../") {
+		return "", false
+	}
 
-		result.WriteString(line)
-		result.WriteString("\n")
+	// Resolve the import path relative to the proto directory to get the full Go import path.
+	// For example:
+	//   protoDir = "github.com/aperturerobotics/bifrost/daemon/api"
+	//   importPath = "../../../controllerbus/bus/api/api.pb.js"
+	//   result = "github.com/aperturerobotics/controllerbus/bus/api/api.pb.js"
+	resolvedPath := resolveRelativeImport(protoDir, importPath)
+
+	// Check if this resolves to outside our module (i.e., it's a vendor dependency)
+	if !strings.HasPrefix(resolvedPath, p.ModulePath) {
+		// This is an external import - verify it exists in vendor and rewrite to @go/ format
+		vendorFilePath := filepath.Join(p.VendorDir, resolvedPath)
+		// Check for .ts file (the .js extension in import maps to .ts source)
+		tsPath := strings.TrimSuffix(vendorFilePath, ".js") + ".ts"
+		if fileExists(tsPath) || fileExists(vendorFilePath) {
+			return "@go/" + resolvedPath, true
+		}
+		return "", false
 	}
 
-	if err := scanner.Err(); err != nil {
-		return err
+	// This is an internal import within the same module.
+	// Check if it resolves to the vendor directory (self-referencing via full path).
+	absImportPath := filepath.Clean(filepath.Join(fileDir, importPath))
+	relToProject, err := filepath.Rel(p.ProjectDir, absImportPath)
+	if err == nil && strings.HasPrefix(relToProject, "vendor/") {
+		vendorPath := strings.TrimPrefix(relToProject, "vendor/")
+		return "@go/" + filepath.ToSlash(vendorPath), true
 	}
 
-	if modified {
-		// Remove trailing newline added by loop
-		output := result.Bytes()
-		if len(output) > 0 && output[len(output)-1] == '\n' {
-			output = output[:len(output)-1]
-		}
-		return os.WriteFile(filePath, output, 0o644)
+	sourceBoundary, hasSourceBoundary := p.lookupTsImportBoundary(strings.TrimPrefix(strings.TrimPrefix(protoDir, p.ModulePath), "/"))
+	targetBoundary, hasTargetBoundary := p.lookupTsImportBoundary(strings.TrimPrefix(strings.TrimPrefix(strings.TrimSuffix(resolvedPath, ".js"), p.ModulePath), "/"))
+	if hasSourceBoundary && hasTargetBoundary && sourceBoundary != targetBoundary {
+		return "@go/" + resolvedPath, true
 	}
 
-	return nil
+	// Otherwise, leave internal relative imports as-is.
+	return "", false
 }
 
 // lookupTsImportBoundary returns the longest matching configured boundary.
@@ -458,7 +424,7 @@ func resolveRelativeImport(baseDir, importPath string) string {
 	parts := strings.Split(baseDir, "/")
 
 	// Process each ../ in the import path
-	remaining := importPath
+	remaining := strings.TrimPrefix(importPath, "./")
 	for strings.HasPrefix(remaining, "../") {
 		remaining = strings.TrimPrefix(remaining, "../")
 		if len(parts) > 0 {
@@ -172,3 +172,181 @@ from undeclared.example import example_pb2
 		t.Fatalf("unexpected dependency import rewrite:\n%s", got)
 	}
 }
+
+func TestProcessGoFileRewritesOnlyImportSpecs(t *testing.T) {
+	projectDir := t.TempDir()
+	filePath := filepath.Join(projectDir, "example.pb.go")
+	content := `package example
+
+import (
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	"example.com/protos/common"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Mentions google.golang.org/protobuf/types/known/anypb in a comment.
+const source = "google.golang.org/protobuf/types/known/timestamppb"
+
+var (
+	_ = anypb.Any{}
+	_ = descriptorpb.FileDescriptorProto{}
+	_ = common.Ref{}
+	_ = timestamppb.Timestamp{}
+)
+`
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write go file: %v", err)
+	}
+
+	cfg := NewConfig()
+	cfg.GoImportRemaps = map[string]string{"example.com/protos": "corp.example/forks/protos-fork"}
+	pp := NewPostProcessor(projectDir, "", "example.com/project", nil, false)
+	pp.GoImportRemaps = cfg.GetGoImportRemaps()
+	if err := pp.ProcessGoFile(filePath); err != nil {
+		t.Fatalf("process go file: %v", err)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read go file: %v", err)
+	}
+	want := strings.NewReplacer(
+		`anypb "google.golang.org/protobuf/types/known/anypb"`,
+		`anypb "github.com/aperturerobotics/protobuf-go-lite/types/known/anypb"`,
+		`descriptorpb "google.golang.org/protobuf/types/descriptorpb"`,
+		`descriptorpb "github.com/aperturerobotics/protobuf-go-lite/types/descriptorpb"`,
+		`"example.com/protos/common"`,
+		`"corp.example/forks/protos-fork/common"`,
+		"\t\"google.golang.org/protobuf/types/known/timestamppb\"",
+		"\t\"github.com/aperturerobotics/protobuf-go-lite/types/known/timestamppb\"",
+	).Replace(content)
+	if got := string(data); got != want {
+		t.Fatalf("unexpected rewrite:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestProcessGoFileNamesRenamedImports(t *testing.T) {
+	projectDir := t.TempDir()
+	filePath := filepath.Join(projectDir, "example.pb.go")
+	content := "package example\n\nimport \"example.com/protos/common\"\n\nvar _ = common.Ref{}\n"
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write go file: %v", err)
+	}
+
+	pp := NewPostProcessor(projectDir, "", "example.com/project", nil, false)
+	pp.GoImportRemaps = map[string]string{"example.com/protos/common": "corp.example/common-fork"}
+	if err := pp.ProcessGoFile(filePath); err != nil {
+		t.Fatalf("process go file: %v", err)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read go file: %v", err)
+	}
+	want := "package example\n\nimport common \"corp.example/common-fork\"\n\nvar _ = common.Ref{}\n"
+	if got := string(data); got != want {
+		t.Fatalf("unexpected rewrite:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestProcessTsFileRewritesAllImportForms(t *testing.T) {
+	projectDir := t.TempDir()
+	vendorDir := filepath.Join(projectDir, "vendor")
+	filePath := filepath.Join(projectDir, "bldr", "plugin", "plugin.pb.ts")
+	vendoredPath := filepath.Join(vendorDir, "github.com", "aperturerobotics", "controllerbus", "bus", "bus.pb.ts")
+	for _, dir := range []string{filepath.Dir(filePath), filepath.Dir(vendoredPath)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	if err := os.WriteFile(vendoredPath, nil, 0o644); err != nil {
+		t.Fatalf("write vendored ts file: %v", err)
+	}
+
+	content := `// @generated from file github.com/s4wave/spacewave/bldr/plugin/plugin.proto
+import type { Message } from "@aptre/protobuf-es-lite"
+import {
+  VolumeInfo,
+  VolumeRef,
+} from "../../db/volume/volume.pb.js"
+import type { Bus } from '../../../../aperturerobotics/controllerbus/bus/bus.pb.js'
+import { Manifest } from "./manifest/manifest.pb.js"
+import { Config } from "./config.pb.js"
+export { BlockRef } from "../../db/block/block.pb.js"
+export * from "../../db/bucket/bucket.pb.js"
+export type { Peer } from "../../net/peer/peer.pb.js"
+
+// import { Ignored } from "../../db/ignored.pb.js"
+export const loadSession = () => import("../../net/session/session.pb.js")
+`
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write ts file: %v", err)
+	}
+
+	pp := NewPostProcessor(
+		projectDir,
+		vendorDir,
+		"github.com/s4wave/spacewave",
+		[]string{"bldr", "bldr/plugin/manifest", "db", "net"},
+		false,
+	)
+	if err := pp.ProcessTsFile(filePath); err != nil {
+		t.Fatalf("process ts file: %v", err)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read ts file: %v", err)
+	}
+	want := `// @generated from file github.com/s4wave/spacewave/bldr/plugin/plugin.proto
+import type { Message } from "@aptre/protobuf-es-lite"
+import {
+  VolumeInfo,
+  VolumeRef,
+} from "@go/github.com/s4wave/spacewave/db/volume/volume.pb.js"
+import type { Bus } from '@go/github.com/aperturerobotics/controllerbus/bus/bus.pb.js'
+import { Manifest } from "@go/github.com/s4wave/spacewave/bldr/plugin/manifest/manifest.pb.js"
+import { Config } from "./config.pb.js"
+export { BlockRef } from "@go/github.com/s4wave/spacewave/db/block/block.pb.js"
+export * from "@go/github.com/s4wave/spacewave/db/bucket/bucket.pb.js"
+export type { Peer } from "@go/github.com/s4wave/spacewave/net/peer/peer.pb.js"
+
+// import { Ignored } from "../../db/ignored.pb.js"
+export const loadSession = () => import("@go/github.com/s4wave/spacewave/net/session/session.pb.js")
+`
+	if got := string(data); got != want {
+		t.Fatalf("unexpected rewrite:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestProcessTsFileRewritesSelfVendorImports(t *testing.T) {
+	projectDir := t.TempDir()
+	filePath := filepath.Join(projectDir, "api", "api.pb.ts")
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		t.Fatalf("mkdir ts dir: %v", err)
+	}
+
+	content := `// @generated from file github.com/example/mod/api/api.proto
+import {
+  Other,
+} from "../vendor/github.com/example/mod/other/other.pb.js"
+`
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write ts file: %v", err)
+	}
+
+	pp := NewPostProcessor(projectDir, filepath.Join(projectDir, "vendor"), "github.com/example/mod", nil, false)
+	if err := pp.ProcessTsFile(filePath); err != nil {
+		t.Fatalf("process ts file: %v", err)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read ts file: %v", err)
+	}
+	want := strings.Replace(content, "../vendor/", "@go/", 1)
+	if got := string(data); got != want {
+		t.Fatalf("unexpected rewrite:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,210 @@
+package protogen
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Profile is a named set of generation settings applied to the proto package
+// directories matching its globs. Unset fields inherit the top-level config.
+type Profile struct {
+	// Name identifies the profile in the cache and in verbose output.
+	Name string `json:"name" yaml:"name"`
+	// Match is the list of globs matched against project-relative package
+	// directories (e.g. "api/**"). A "**" element matches any number of
+	// directories, including none.
+	Match []string `json:"match" yaml:"match"`
+	// Languages overrides the protobuf output languages.
+	Languages []string `json:"languages,omitempty" yaml:"languages,omitempty"`
+	// RPCLibraries overrides the RPC stub generators.
+	RPCLibraries []string `json:"rpc,omitempty" yaml:"rpc,omitempty"`
+	// GoLiteFeatures overrides the go-lite features.
+	GoLiteFeatures string `json:"features,omitempty" yaml:"features,omitempty"`
+}
+
+// Matches returns true if the project-relative package directory matches any
+// of the profile's globs.
+func (p *Profile) Matches(dir string) bool {
+	return matchesAnyGlob(dir, p.Match)
+}
+
+// ValidateProfiles checks that every profile has a unique name and at least
+// one valid glob.
+func (c *Config) ValidateProfiles() error {
+	seen := make(map[string]struct{}, len(c.Profiles))
+	for i, p := range c.Profiles {
+		if p == nil || p.Name == "" {
+			return fmt.Errorf("profile %d: name is required", i)
+		}
+		if _, ok := seen[p.Name]; ok {
+			return fmt.Errorf("profile %q: duplicate name", p.Name)
+		}
+		seen[p.Name] = struct{}{}
+		if len(p.Match) == 0 {
+			return fmt.Errorf("profile %q: match is required", p.Name)
+		}
+		if err := validateGlobs(p.Match); err != nil {
+			return fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// MatchProfile returns the first profile matching the project-relative
+// package directory, or nil if the top-level settings apply.
+func (c *Config) MatchProfile(dir string) *Profile {
+	for _, p := range c.Profiles {
+		if p.Matches(dir) {
+			return p
+		}
+	}
+	return nil
+}
+
+// ForProfile returns a copy of the config with the profile's overrides
+// applied. Returns the config itself if profile is nil.
+func (c *Config) ForProfile(profile *Profile) *Config {
+	if profile == nil {
+		return c
+	}
+	cfg := *c
+	cfg.Profiles = nil
+	if len(profile.Languages) != 0 {
+		cfg.Languages = profile.Languages
+	}
+	if len(profile.RPCLibraries) != 0 {
+		cfg.RPCLibraries = profile.RPCLibraries
+	}
+	if profile.GoLiteFeatures != "" {
+		cfg.GoLiteFeatures = profile.GoLiteFeatures
+	}
+	return &cfg
+}
+
+// matchesAnyGlob returns true if the project-relative directory matches any
+// of the globs.
+func matchesAnyGlob(dir string, patterns []string) bool {
+	dir = filepath.ToSlash(filepath.Clean(dir))
+	for _, pattern := range patterns {
+		if matchGlob(path.Clean(pattern), dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateGlobs checks that every pattern is a valid glob.
+func validateGlobs(patterns []string) error {
+	for _, pattern := range patterns {
+		for _, expanded := range expandBraces(pattern) {
+			if _, err := path.Match(expanded, ""); err != nil {
+				return fmt.Errorf("invalid match pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// matchGlob matches a slash-separated name against a glob pattern where a
+// "**" element matches zero or more path elements, "{a,b}" matches either
+// alternative and all other elements use path.Match semantics.
+func matchGlob(pattern, name string) bool {
+	var nameParts []string
+	if name != "." {
+		nameParts = strings.Split(name, "/")
+	}
+	for _, expanded := range expandBraces(pattern) {
+		if expanded == "." || expanded == "" {
+			if name == "." {
+				return true
+			}
+			continue
+		}
+		if matchGlobParts(strings.Split(expanded, "/"), nameParts) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandBraces expands the "{a,b}" alternatives in a glob pattern, including
+// nested ones. Braces without a top-level comma are kept literally.
+func expandBraces(pattern string) []string {
+	depth, start := 0, -1
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth != 0 {
+				continue
+			}
+			alternatives := splitBraceAlternatives(pattern[start+1 : i])
+			if len(alternatives) < 2 {
+				continue
+			}
+			var expanded []string
+			for _, alt := range alternatives {
+				expanded = append(expanded, expandBraces(pattern[:start]+alt+pattern[i+1:])...)
+			}
+			return expanded
+		}
+	}
+	return []string{pattern}
+}
+
+// splitBraceAlternatives splits the contents of a brace set at its top-level
+// commas.
+func splitBraceAlternatives(s string) []string {
+	var parts []string
+	depth, last := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(parts, s[last:])
+}
+
+// matchGlobParts matches path elements against pattern elements.
+func matchGlobParts(pattern, name []string) bool {
+	for len(pattern) != 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlobParts(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,149 @@
+package protogen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileMatches(t *testing.T) {
+	profile := &Profile{Name: "api", Match: []string{"./api/**", "*/internal"}}
+	for dir, want := range map[string]bool{
+		"api":            true,
+		"api/v1":         true,
+		"api/v1/types":   true,
+		"apis":           false,
+		"foo/internal":   true,
+		"foo/bar/intern": false,
+		".":              false,
+	} {
+		if got := profile.Matches(dir); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", dir, got, want)
+		}
+	}
+
+	root := &Profile{Name: "all", Match: []string{"**"}}
+	for _, dir := range []string{".", "a", "a/b"} {
+		if !root.Matches(dir) {
+			t.Errorf("** did not match %q", dir)
+		}
+	}
+}
+
+func TestConfigForProfileOverridesSettings(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Languages = []string{"go", "ts"}
+	profile := &Profile{
+		Name:           "internal",
+		Match:          []string{"internal/**"},
+		Languages:      []string{"go"},
+		GoLiteFeatures: "marshal+unmarshal+size",
+	}
+	cfg.Profiles = []*Profile{profile}
+
+	if cfg.MatchProfile("internal/db") != profile {
+		t.Fatal("expected internal/db to match the internal profile")
+	}
+	if cfg.MatchProfile("api") != nil {
+		t.Fatal("expected api to use the top-level settings")
+	}
+
+	derived := cfg.ForProfile(profile)
+	if derived.GoLiteFeatures != "marshal+unmarshal+size" || len(derived.Languages) != 1 {
+		t.Fatalf("profile overrides not applied: %+v", derived)
+	}
+	if len(derived.RPCLibraries) != 0 {
+		t.Fatalf("unset profile rpc should inherit the top-level value, got %v", derived.RPCLibraries)
+	}
+	if cfg.GoLiteFeatures != DefaultGoLiteFeatures {
+		t.Fatal("ForProfile modified the top-level config")
+	}
+}
+
+func TestValidateProfiles(t *testing.T) {
+	for name, profiles := range map[string][]*Profile{
+		"missing name":  {{Match: []string{"a"}}},
+		"missing match": {{Name: "a"}},
+		"duplicate":     {{Name: "a", Match: []string{"a"}}, {Name: "a", Match: []string{"b"}}},
+		"bad pattern":   {{Name: "a", Match: []string{"["}}},
+	} {
+		cfg := NewConfig()
+		cfg.Profiles = profiles
+		if err := cfg.ValidateProfiles(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestGenerateAppliesProfilesPerPackage(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"a/a.proto": "syntax = \"proto3\";\npackage a;\nmessage A { string id = 1; }\n",
+		"b/b.proto": "syntax = \"proto3\";\npackage b;\nmessage B { int32 n = 1; }\n",
+	})
+	profile := &Profile{Name: "py", Match: []string{"b/**"}, Languages: []string{"cpp", "python"}}
+	g.Config.Profiles = []*Profile{profile}
+	g.ProfilePlugins = map[string]*Plugins{
+		"py": {Languages: Languages{LanguageCpp: {}, LanguagePython: {}}, RPCLibraries: RPCLibraries{}},
+	}
+
+	ctx := context.Background()
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	for path, want := range map[string]bool{
+		"a/a.pb.cc":   true,
+		"a/a_pb2.py":  false,
+		"b/b.pb.cc":   true,
+		"b/b_pb2.py":  true,
+		"b/b_pb2.pyi": true,
+	} {
+		_, err := os.Stat(filepath.Join(g.ProjectDir, path))
+		if got := err == nil; got != want {
+			t.Fatalf("%s exists = %v, want %v", path, got, want)
+		}
+	}
+
+	aKey := GetPackageKey(g.ModulePath, "a/a.proto")
+	bKey := GetPackageKey(g.ModulePath, "b/b.proto")
+	aHash := g.Cache.Packages[aKey].ProtocFlagsHash
+	bHash := g.Cache.Packages[bKey].ProtocFlagsHash
+	if aHash == "" || bHash == "" || aHash == bHash {
+		t.Fatalf("expected distinct per-profile flags hashes, got %q and %q", aHash, bHash)
+	}
+
+	// Changing the profile only invalidates the packages it matches.
+	g.ProfilePlugins["py"] = &Plugins{Languages: Languages{LanguagePython: {}}, RPCLibraries: RPCLibraries{}}
+	toolVersions := g.getToolVersions()
+	groups := g.groupDirsByProfile([]string{"a", "b"})
+	for _, group := range groups {
+		dir := group.dirs[0]
+		files := []string{filepath.Join(dir, dir+".proto")}
+		stale, err := g.Cache.NeedsRegeneration(GetPackageKey(g.ModulePath, files[0]), files, g.ProjectDir, group.flagsHash, toolVersions, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := dir == "b"; stale != want {
+			t.Fatalf("package %s stale = %v, want %v", dir, stale, want)
+		}
+	}
+}
+
+func TestMatchGlobBraces(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"{a,b}/*.proto", "a/x.proto", true},
+		{"{a,b}/*.proto", "b/x.proto", true},
+		{"{a,b}/*.proto", "c/x.proto", false},
+		{"{a,b/c}/**/x.proto", "b/c/d/x.proto", true},
+		{"x.{proto,{pb,bin}}", "x.bin", true},
+		{"{single}.proto", "{single}.proto", true},
+		{`\{a,b}.proto`, "{a,b}.proto", true},
+	} {
+		if got := matchGlob(tc.pattern, tc.name); got != tc.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,841 @@
+package protogen
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// protoFmtIndent is the indentation of one nesting level in formatted proto
+// files.
+const protoFmtIndent = "  "
+
+// FormatProto formats the discovered proto files with FormatProtoSource.
+// Before anything is written, the original and formatted files are compiled
+// with the embedded protoc and must produce the same descriptors. With check
+// set the project is not modified. Returns the files that are (or were) not
+// formatted, with the diff to the formatted source.
+func (g *Generator) FormatProto(ctx context.Context, check bool) ([]StaleFile, error) {
+	protoFiles, err := DiscoverProtoFiles(g.ProjectDir, g.Config.Targets, g.Config.Exclude, g.Config.IncludeUntracked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover proto files: %w", err)
+	}
+
+	var changed []StaleFile
+	formatted := make(map[string][]byte)
+	for _, f := range protoFiles {
+		src, err := os.ReadFile(filepath.Join(g.ProjectDir, f))
+		if err != nil {
+			return nil, err
+		}
+		out, err := FormatProtoSource(src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		if bytes.Equal(src, out) {
+			continue
+		}
+		p := filepath.ToSlash(f)
+		changed = append(changed, StaleFile{Path: f, Diff: UnifiedDiff("a/"+p, "b/"+p, src, out)})
+		formatted[f] = out
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	if err := g.verifyProtoFormat(ctx, protoFiles, formatted); err != nil {
+		return nil, err
+	}
+	if check {
+		return changed, nil
+	}
+	for f, out := range formatted {
+		path := filepath.Join(g.ProjectDir, f)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+			return nil, err
+		}
+	}
+	return changed, nil
+}
+
+// verifyProtoFormat compiles the project's proto files and a scratch copy
+// with the formatted sources and checks that every file produces the same
+// descriptor.
+func (g *Generator) verifyProtoFormat(ctx context.Context, protoFiles []string, formatted map[string][]byte) error {
+	scratchDir, err := os.MkdirTemp("", "aptre-format-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratchDir)
+
+	scratch, err := g.newScratchGenerator(scratchDir)
+	if err != nil {
+		return fmt.Errorf("failed to prepare scratch directory: %w", err)
+	}
+	for f, out := range formatted {
+		if err := os.WriteFile(filepath.Join(scratch.ProjectDir, f), out, 0o644); err != nil {
+			return err
+		}
+	}
+
+	data, _, err := g.compileDescriptorSet(ctx, protoFiles, filepath.Join(scratchDir, "original.binpb"), false)
+	if err != nil {
+		return err
+	}
+	want, err := canonicalFileDescriptors(data)
+	if err != nil {
+		return err
+	}
+	data, _, err = scratch.compileDescriptorSet(ctx, protoFiles, filepath.Join(scratchDir, "formatted.binpb"), false)
+	if err != nil {
+		return fmt.Errorf("formatted proto files do not compile: %w", err)
+	}
+	got, err := canonicalFileDescriptors(data)
+	if err != nil {
+		return err
+	}
+	for f := range formatted {
+		name := filepath.ToSlash(filepath.Join(g.ModulePath, f))
+		if got[name] != want[name] {
+			return fmt.Errorf("%s: formatting would change the compiled descriptor", f)
+		}
+	}
+	return nil
+}
+
+// protoTokenKind is the kind of a proto source token.
+type protoTokenKind int
+
+const (
+	protoTokenIdent protoTokenKind = iota
+	protoTokenNumber
+	protoTokenString
+	protoTokenSymbol
+	protoTokenComment
+	// protoTokenAggregate is an option value in braces. Its tokens are in
+	// protoToken.group, excluding the braces.
+	protoTokenAggregate
+)
+
+// protoToken is a token of a proto source file.
+type protoToken struct {
+	kind protoTokenKind
+	text string
+	// line is the 1-based source line of the token.
+	line int
+	// newlines is the number of line breaks between the previous token and
+	// this one.
+	newlines int
+	// group contains the tokens of an aggregate value.
+	group []protoToken
+}
+
+// is returns true if the token is the given symbol or identifier.
+func (t *protoToken) is(text string) bool {
+	return (t.kind == protoTokenSymbol || t.kind == protoTokenIdent) && t.text == text
+}
+
+// isLineComment returns true if the token is a // comment.
+func (t *protoToken) isLineComment() bool {
+	return t.kind == protoTokenComment && strings.HasPrefix(t.text, "//")
+}
+
+// tokenizeProto splits proto source into tokens using the lexical rules of
+// the protoc tokenizer. Dotted names without whitespace are kept as one
+// identifier token, including a leading dot for fully qualified names.
+func tokenizeProto(src string) ([]protoToken, error) {
+	var tokens []protoToken
+	line := 1
+	newlines := 0
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			newlines++
+			line++
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\v' || c == '\f':
+			i++
+			continue
+		}
+
+		start, startLine := i, line
+		kind := protoTokenSymbol
+		switch {
+		case strings.HasPrefix(src[i:], "//"):
+			kind = protoTokenComment
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			kind = protoTokenComment
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated block comment", line)
+			}
+			i += end + 4
+			line += strings.Count(src[start:i], "\n")
+		case c == '"' || c == '\'':
+			kind = protoTokenString
+			i++
+			for i < len(src) && src[i] != c {
+				if src[i] == '\n' {
+					return nil, fmt.Errorf("line %d: unterminated string", line)
+				}
+				if src[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			i++
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			kind = protoTokenNumber
+			hex := strings.HasPrefix(src[i:], "0x") || strings.HasPrefix(src[i:], "0X")
+			for i < len(src) {
+				d := src[i]
+				if isIdentChar(d) || d == '.' {
+					i++
+					continue
+				}
+				if (d == '+' || d == '-') && !hex && (src[i-1] == 'e' || src[i-1] == 'E') {
+					i++
+					continue
+				}
+				break
+			}
+		case isIdentStart(c) || (c == '.' && i+1 < len(src) && isIdentStart(src[i+1]) && !continuesName(src, i)):
+			kind = protoTokenIdent
+			i++
+			for i < len(src) && (isIdentChar(src[i]) || (src[i] == '.' && i+1 < len(src) && isIdentStart(src[i+1]))) {
+				i++
+			}
+		default:
+			i++
+		}
+		text := src[start:i]
+		if kind == protoTokenComment {
+			text = strings.TrimRight(text, " \t\r")
+		}
+		tokens = append(tokens, protoToken{kind: kind, text: text, line: startLine, newlines: newlines})
+		newlines = 0
+	}
+	return tokens, nil
+}
+
+// continuesName returns true if the dot at src[i] directly follows an
+// identifier or closing parenthesis, making it a separator rather than the
+// start of a fully qualified name.
+func continuesName(src string, i int) bool {
+	return i != 0 && (isIdentChar(src[i-1]) || src[i-1] == ')')
+}
+
+func isDigit(c byte) bool      { return c >= '0' && c <= '9' }
+func isIdentStart(c byte) bool { return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isIdentChar(c byte) bool  { return isIdentStart(c) || isDigit(c) }
+
+// protoComment is a comment attached to a statement.
+type protoComment struct {
+	text string
+	// blankBefore is set if a blank line separates the comment from what
+	// precedes it.
+	blankBefore bool
+}
+
+// protoStmt is a statement of a proto file: a declaration ending in a
+// semicolon or a block declaration with nested statements.
+type protoStmt struct {
+	// comments are the comments on the lines before the statement.
+	comments []protoComment
+	// blankBefore is set if a blank line precedes the statement or its
+	// comments.
+	blankBefore bool
+	// detached is set if a blank line separates the comments from the
+	// statement.
+	detached bool
+	// tokens are the statement tokens, excluding the terminator.
+	tokens []protoToken
+	// block is set for declarations with a body in braces.
+	block bool
+	// body are the statements inside the braces.
+	body []*protoStmt
+	// closeComments are the comments before the closing brace.
+	closeComments []protoComment
+	// trailing is the comment after the terminator or opening brace.
+	trailing string
+	// closeTrailing is the comment after the closing brace.
+	closeTrailing string
+}
+
+// keyword returns the first token of the statement.
+func (s *protoStmt) keyword() string {
+	if len(s.tokens) == 0 {
+		return ""
+	}
+	return s.tokens[0].text
+}
+
+// protoParser builds statements from tokens.
+type protoParser struct {
+	tokens []protoToken
+	pos    int
+}
+
+// peek returns the next token, or nil at the end of the file.
+func (p *protoParser) peek() *protoToken {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+// next consumes and returns the next token, or nil at the end of the file.
+func (p *protoParser) next() *protoToken {
+	tok := p.peek()
+	if tok != nil {
+		p.pos++
+	}
+	return tok
+}
+
+// trailingComment consumes a comment on the same line as the previous token.
+func (p *protoParser) trailingComment() string {
+	if tok := p.peek(); tok != nil && tok.kind == protoTokenComment && tok.newlines == 0 {
+		p.pos++
+		return tok.text
+	}
+	return ""
+}
+
+// parseBody parses statements until the closing brace of a block, or the end
+// of the file if inBlock is false. Returns the statements and the comments
+// after the last statement.
+func (p *protoParser) parseBody(inBlock bool) ([]*protoStmt, []protoComment, error) {
+	var stmts []*protoStmt
+	var pending []protoComment
+	for {
+		tok := p.peek()
+		if tok == nil {
+			if inBlock {
+				return nil, nil, errors.New("unexpected end of file, expected }")
+			}
+			return stmts, pending, nil
+		}
+		blankBefore := tok.newlines >= 2
+		switch {
+		case tok.kind == protoTokenComment:
+			p.pos++
+			pending = append(pending, protoComment{text: tok.text, blankBefore: blankBefore})
+			continue
+		case tok.is("}"):
+			if !inBlock {
+				return nil, nil, fmt.Errorf("line %d: unexpected }", tok.line)
+			}
+			return stmts, pending, nil
+		case tok.is(";"):
+			// Empty statement.
+			p.pos++
+			continue
+		}
+
+		stmt := &protoStmt{comments: pending, blankBefore: blankBefore}
+		if len(pending) != 0 {
+			stmt.blankBefore = pending[0].blankBefore
+			stmt.detached = blankBefore
+		}
+		pending = nil
+		if err := p.parseStmt(stmt); err != nil {
+			return nil, nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+}
+
+// parseStmt parses the tokens of a statement up to its terminator.
+func (p *protoParser) parseStmt(stmt *protoStmt) error {
+	depth := 0
+	for {
+		tok := p.next()
+		if tok == nil {
+			return fmt.Errorf("unexpected end of file in %q statement", stmt.keyword())
+		}
+		switch {
+		case tok.kind == protoTokenComment:
+		case tok.is("(") || tok.is("[") || tok.is("<"):
+			depth++
+		case tok.is(")") || tok.is("]") || tok.is(">"):
+			depth--
+		case tok.is("{") && len(stmt.tokens) != 0 && stmt.tokens[len(stmt.tokens)-1].is("="):
+			agg, err := p.parseAggregate()
+			if err != nil {
+				return err
+			}
+			stmt.tokens = append(stmt.tokens, agg)
+			continue
+		case tok.is("{") && len(stmt.tokens) == 0:
+			return fmt.Errorf("line %d: unexpected {", tok.line)
+		case tok.is("{") && depth == 0:
+			stmt.block = true
+			stmt.trailing = p.trailingComment()
+			body, closeComments, err := p.parseBody(true)
+			if err != nil {
+				return err
+			}
+			p.next()
+			stmt.body, stmt.closeComments = body, closeComments
+			stmt.closeTrailing = p.trailingComment()
+			return nil
+		case tok.is(";") && depth == 0:
+			stmt.trailing = p.trailingComment()
+			return nil
+		case tok.is("}"):
+			return fmt.Errorf("line %d: unexpected } in %q statement", tok.line, stmt.keyword())
+		}
+		stmt.tokens = append(stmt.tokens, *tok)
+	}
+}
+
+// parseAggregate consumes an aggregate option value after its opening brace.
+func (p *protoParser) parseAggregate() (protoToken, error) {
+	agg := protoToken{kind: protoTokenAggregate}
+	depth := 0
+	for {
+		tok := p.next()
+		if tok == nil {
+			return agg, errors.New("unexpected end of file in option value")
+		}
+		switch {
+		case tok.is("{"):
+			depth++
+		case tok.is("}"):
+			if depth == 0 {
+				return agg, nil
+			}
+			depth--
+		}
+		agg.group = append(agg.group, *tok)
+	}
+}
+
+// FormatProtoSource formats proto source in the canonical aptre style:
+// two-space indentation, one statement per line, normalized spacing, the
+// syntax, package, imports and file options first with imports and options
+// sorted, and comments kept with the statements they precede or follow.
+func FormatProtoSource(src []byte) ([]byte, error) {
+	tokens, err := tokenizeProto(string(src))
+	if err != nil {
+		return nil, err
+	}
+	p := &protoParser{tokens: tokens}
+	stmts, tail, err := p.parseBody(false)
+	if err != nil {
+		return nil, err
+	}
+	stmts = sortProtoHeader(stmts)
+
+	var pr protoPrinter
+	pr.stmts(stmts, "")
+	pr.comments(tail, "", len(stmts) != 0 && len(tail) != 0 && tail[0].blankBefore)
+	out := strings.TrimLeft(pr.sb.String(), "\n")
+	if out == "" {
+		return nil, nil
+	}
+	return []byte(out), nil
+}
+
+// protoHeaderRank orders the top-level statements of a formatted file.
+func protoHeaderRank(s *protoStmt) int {
+	switch s.keyword() {
+	case "syntax", "edition":
+		return 0
+	case "package":
+		return 1
+	case "import":
+		return 2
+	case "option":
+		return 3
+	default:
+		return 4
+	}
+}
+
+// sortProtoHeader moves the syntax, package, import and option statements to
+// the top of the file, sorting imports by path and options by name.
+func sortProtoHeader(stmts []*protoStmt) []*protoStmt {
+	sorted := slices.Clone(stmts)
+	slices.SortStableFunc(sorted, func(a, b *protoStmt) int {
+		ra, rb := protoHeaderRank(a), protoHeaderRank(b)
+		if ra != rb || ra < 2 || ra > 3 {
+			return cmp.Compare(ra, rb)
+		}
+		return cmp.Compare(protoHeaderSortKey(a), protoHeaderSortKey(b))
+	})
+	for i, s := range sorted {
+		rank := protoHeaderRank(s)
+		switch {
+		case i == 0:
+		case rank != protoHeaderRank(sorted[i-1]):
+			s.blankBefore = true
+		case rank < 4:
+			s.blankBefore = false
+		}
+	}
+	return sorted
+}
+
+// protoHeaderSortKey returns the import path or option name of a statement.
+func protoHeaderSortKey(s *protoStmt) string {
+	var parts []string
+	for _, tok := range s.tokens[1:] {
+		switch {
+		case tok.kind == protoTokenComment:
+			continue
+		case s.keyword() == "import" && tok.kind == protoTokenString:
+			return tok.text
+		case tok.is("="):
+			return strings.Join(parts, "")
+		}
+		parts = append(parts, tok.text)
+	}
+	return strings.Join(parts, "")
+}
+
+// protoPrinter writes formatted statements.
+type protoPrinter struct {
+	sb strings.Builder
+}
+
+// line writes a line, trimming trailing whitespace.
+func (p *protoPrinter) line(text string) {
+	p.sb.WriteString(strings.TrimRight(text, " \t"))
+	p.sb.WriteByte('\n')
+}
+
+// comments writes comment lines. A blank line is kept before comments that
+// were separated from the preceding comment, and before the first comment if
+// leadingBlank is set.
+func (p *protoPrinter) comments(comments []protoComment, indent string, leadingBlank bool) {
+	for i, c := range comments {
+		if (i == 0 && leadingBlank) || (i != 0 && c.blankBefore) {
+			p.line("")
+		}
+		// Continuation lines of block comments are kept as written.
+		for j, line := range strings.Split(c.text, "\n") {
+			if j == 0 {
+				line = indent + line
+			}
+			p.line(strings.TrimRight(line, "\r"))
+		}
+	}
+}
+
+// stmts writes statements at the given indentation.
+func (p *protoPrinter) stmts(stmts []*protoStmt, indent string) {
+	for i, s := range stmts {
+		blank := i != 0 && s.blankBefore
+		if len(s.comments) != 0 {
+			p.comments(s.comments, indent, blank)
+			if s.detached {
+				p.line("")
+			}
+		} else if blank {
+			p.line("")
+		}
+
+		text := indent + formatProtoTokens(s.tokens, indent)
+		if last := s.tokens[len(s.tokens)-1]; last.isLineComment() {
+			// Keep the terminator out of the comment.
+			text += "\n" + indent + protoFmtIndent + protoFmtIndent
+		}
+		if !s.block {
+			p.line(appendComment(text+";", s.trailing))
+			continue
+		}
+		if len(s.body) == 0 && len(s.closeComments) == 0 && s.trailing == "" {
+			p.line(appendComment(text+" {}", s.closeTrailing))
+			continue
+		}
+		p.line(appendComment(text+" {", s.trailing))
+		p.stmts(s.body, indent+protoFmtIndent)
+		closeBlank := len(s.closeComments) != 0 && len(s.body) != 0 && s.closeComments[0].blankBefore
+		p.comments(s.closeComments, indent+protoFmtIndent, closeBlank)
+		p.line(appendComment(indent+"}", s.closeTrailing))
+	}
+}
+
+// appendComment appends a trailing comment to a line.
+func appendComment(text, comment string) string {
+	if comment == "" {
+		return text
+	}
+	return text + " " + comment
+}
+
+// formatProtoTokens joins statement tokens with canonical spacing.
+func formatProtoTokens(tokens []protoToken, indent string) string {
+	var sb strings.Builder
+	var prev, prevPrev *protoToken
+	for i := range tokens {
+		tok := &tokens[i]
+		if prev != nil {
+			switch {
+			case prev.isLineComment():
+				sb.WriteString("\n" + indent + protoFmtIndent + protoFmtIndent)
+			case protoTokenSpace(prevPrev, prev, tok):
+				sb.WriteByte(' ')
+			}
+		}
+		switch tok.kind {
+		case protoTokenAggregate:
+			sb.WriteString(formatProtoAggregate(tok.group, indent, inProtoBrackets(tokens[:i])))
+		default:
+			sb.WriteString(tok.text)
+		}
+		prevPrev, prev = prev, tok
+	}
+	return sb.String()
+}
+
+// inProtoBrackets returns true if the tokens leave a [ unclosed.
+func inProtoBrackets(tokens []protoToken) bool {
+	depth := 0
+	for i := range tokens {
+		switch {
+		case tokens[i].is("["):
+			depth++
+		case tokens[i].is("]"):
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// protoTokenSpace returns true if a space separates prev and tok.
+func protoTokenSpace(prevPrev, prev, tok *protoToken) bool {
+	switch {
+	case tok.kind == protoTokenComment || prev.kind == protoTokenComment:
+		return true
+	case tok.is(";") || tok.is(",") || tok.is(")") || tok.is("]") || tok.is(">") || tok.is("<") || tok.is(":"):
+		return false
+	case tok.is(".") || prev.is(".") || prev.is("(") || prev.is("[") || prev.is("<") || prev.is("-") || prev.is("/") || tok.is("/"):
+		return false
+	case tok.is("(") && prev.kind == protoTokenIdent && prevPrev != nil && prevPrev.is("rpc"):
+		return false
+	}
+	return true
+}
+
+// formatProtoAggregate formats an aggregate option value in braces. Values
+// inside field options are kept on one line unless they contain comments.
+func formatProtoAggregate(tokens []protoToken, indent string, inline bool) string {
+	if len(tokens) == 0 {
+		return "{}"
+	}
+	fields := splitProtoAggregate(tokens)
+	if inline && !slices.ContainsFunc(tokens, func(t protoToken) bool { return t.kind == protoTokenComment }) {
+		parts := make([]string, len(fields))
+		for i, field := range fields {
+			parts[i] = formatProtoAggregateField(field, indent, true)
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("{")
+	inner := indent + protoFmtIndent
+	for _, field := range fields {
+		sb.WriteString("\n" + inner + formatProtoAggregateField(field, inner, false))
+	}
+	sb.WriteString("\n" + indent + "}")
+	return sb.String()
+}
+
+// splitProtoAggregate splits aggregate tokens into fields, dropping the
+// optional , and ; separators. Comments are returned as their own fields.
+func splitProtoAggregate(tokens []protoToken) [][]protoToken {
+	var fields [][]protoToken
+	var cur []protoToken
+	depth := 0
+	flush := func() {
+		if len(cur) != 0 {
+			fields = append(fields, cur)
+			cur = nil
+		}
+	}
+	for _, tok := range tokens {
+		if depth == 0 {
+			switch {
+			case tok.kind == protoTokenComment:
+				flush()
+				fields = append(fields, []protoToken{tok})
+				continue
+			case tok.is(",") || tok.is(";"):
+				flush()
+				continue
+			case len(cur) != 0 && protoAggregateFieldDone(cur) &&
+				(tok.kind != protoTokenString || cur[len(cur)-1].kind != protoTokenString):
+				flush()
+			}
+		}
+		switch {
+		case tok.is("{") || tok.is("[") || tok.is("<"):
+			depth++
+		case tok.is("}") || tok.is("]") || tok.is(">"):
+			depth--
+		}
+		cur = append(cur, tok)
+	}
+	flush()
+	return fields
+}
+
+// protoAggregateName returns the field name of an aggregate field and the
+// index of the token after it. Extension and Any type names in brackets are
+// joined without spaces.
+func protoAggregateName(field []protoToken) (string, int) {
+	if !field[0].is("[") {
+		return field[0].text, 1
+	}
+	var sb strings.Builder
+	for i, tok := range field {
+		sb.WriteString(tok.text)
+		if tok.is("]") {
+			return sb.String(), i + 1
+		}
+	}
+	return sb.String(), len(field)
+}
+
+// protoAggregateFieldDone returns true if the tokens form a complete
+// aggregate field: a name followed by a value.
+func protoAggregateFieldDone(field []protoToken) bool {
+	_, i := protoAggregateName(field)
+	if i < len(field) && field[i].is(":") {
+		i++
+	}
+	if i < len(field) && field[i].is("-") {
+		i++
+	}
+	if i >= len(field) {
+		return false
+	}
+	last := field[len(field)-1]
+	return last.kind != protoTokenSymbol || last.is("}") || last.is("]") || last.is(">")
+}
+
+// formatProtoAggregateField formats a single aggregate field.
+func formatProtoAggregateField(field []protoToken, indent string, inline bool) string {
+	if field[0].kind == protoTokenComment {
+		return field[0].text
+	}
+	name, i := protoAggregateName(field)
+	value := field[i:]
+	if len(value) != 0 && value[0].is(":") {
+		name += ":"
+		value = value[1:]
+	}
+	if len(value) == 0 {
+		return name
+	}
+	return name + " " + formatProtoAggregateValue(value, indent, inline)
+}
+
+// formatProtoAggregateValue formats a scalar, message or list value.
+// Messages in angle brackets are written with braces.
+func formatProtoAggregateValue(value []protoToken, indent string, inline bool) string {
+	switch {
+	case value[0].is("{") || value[0].is("<"):
+		return formatProtoAggregate(value[1:len(value)-1], indent, inline)
+	case value[0].is("["):
+		return formatProtoAggregateList(value[1:len(value)-1], indent, inline)
+	}
+	var sb strings.Builder
+	for i, tok := range value {
+		if i != 0 && !value[i-1].is("-") {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(tok.text)
+	}
+	return sb.String()
+}
+
+// formatProtoAggregateList formats the elements of a list value. Lists of
+// messages are written one element per line unless inline is set.
+func formatProtoAggregateList(tokens []protoToken, indent string, inline bool) string {
+	var elems [][]protoToken
+	var cur []protoToken
+	depth := 0
+	multiline := false
+	for _, tok := range tokens {
+		if depth == 0 {
+			switch {
+			case tok.kind == protoTokenComment:
+				multiline = true
+				if len(cur) != 0 {
+					elems = append(elems, cur)
+					cur = nil
+				}
+				elems = append(elems, []protoToken{tok})
+				continue
+			case tok.is(","):
+				if len(cur) != 0 {
+					elems = append(elems, cur)
+					cur = nil
+				}
+				continue
+			case tok.is("{") || tok.is("<"):
+				multiline = multiline || !inline
+			}
+		}
+		switch {
+		case tok.is("{") || tok.is("[") || tok.is("<"):
+			depth++
+		case tok.is("}") || tok.is("]") || tok.is(">"):
+			depth--
+		}
+		cur = append(cur, tok)
+	}
+	if len(cur) != 0 {
+		elems = append(elems, cur)
+	}
+	if len(elems) == 0 {
+		return "[]"
+	}
+
+	if !multiline {
+		parts := make([]string, len(elems))
+		for i, elem := range elems {
+			parts[i] = formatProtoAggregateValue(elem, indent, inline)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[")
+	inner := indent + protoFmtIndent
+	for i, elem := range elems {
+		sb.WriteString("\n" + inner)
+		if elem[0].kind == protoTokenComment {
+			sb.WriteString(elem[0].text)
+			continue
+		}
+		sb.WriteString(formatProtoAggregateValue(elem, inner, false))
+		if slices.ContainsFunc(elems[i+1:], func(e []protoToken) bool { return e[0].kind != protoTokenComment }) {
+			sb.WriteString(",")
+		}
+	}
+	sb.WriteString("\n" + indent + "]")
+	return sb.String()
+}
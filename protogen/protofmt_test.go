@@ -0,0 +1,175 @@
+package protogen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatProtoSource(t *testing.T) {
+	src := `// Copyright header.
+
+// Package doc.
+syntax="proto3";
+import "z/z.proto";
+option java_package="x";
+package   demo.v1;
+import public "a/a.proto";
+option go_package = "example.com/project/demo";
+
+
+
+// Thing is a thing.
+message Thing{
+	// id is the identifier.
+	string id=1;  // trailing
+  map<string,int32>counts = 2 [deprecated=true,(opt.rules).string={min_len:1,max_len:10}];
+  repeated .demo.v1.Other others=3;
+  reserved 4 to 6,10;
+  oneof kind {
+    int32 num = 7 [default = -1];
+  }
+
+  enum State { STATE_UNSPECIFIED=0; }
+  message Empty {}
+
+  // dangling
+}
+
+// Detached comment.
+
+option (file_opt) = { name: "x" list: [1,2] nested < a: 1 > };
+
+service Svc{
+  rpc Get ( Thing ) returns ( stream Thing ) ;
+  rpc Put(Thing) returns (Thing) { option idempotency_level = IDEMPOTENT; };
+}
+// tail
+`
+	want := `// Copyright header.
+
+// Package doc.
+syntax = "proto3";
+
+package demo.v1;
+
+import public "a/a.proto";
+import "z/z.proto";
+
+// Detached comment.
+
+option (file_opt) = {
+  name: "x"
+  list: [1, 2]
+  nested {
+    a: 1
+  }
+};
+option go_package = "example.com/project/demo";
+option java_package = "x";
+
+// Thing is a thing.
+message Thing {
+  // id is the identifier.
+  string id = 1; // trailing
+  map<string, int32> counts = 2 [deprecated = true, (opt.rules).string = {min_len: 1, max_len: 10}];
+  repeated .demo.v1.Other others = 3;
+  reserved 4 to 6, 10;
+  oneof kind {
+    int32 num = 7 [default = -1];
+  }
+
+  enum State {
+    STATE_UNSPECIFIED = 0;
+  }
+  message Empty {}
+
+  // dangling
+}
+
+service Svc {
+  rpc Get(Thing) returns (stream Thing);
+  rpc Put(Thing) returns (Thing) {
+    option idempotency_level = IDEMPOTENT;
+  }
+}
+// tail
+`
+	out, err := FormatProtoSource([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != want {
+		t.Fatalf("unexpected output:\n%s", UnifiedDiff("want", "got", []byte(want), out))
+	}
+
+	again, err := FormatProtoSource(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(out) {
+		t.Fatalf("formatting is not idempotent:\n%s", UnifiedDiff("first", "second", out, again))
+	}
+
+	for _, bad := range []string{"message A {", "message A { string a = 1; }}", `option x = "a`, "/* open"} {
+		if _, err := FormatProtoSource([]byte(bad)); err == nil {
+			t.Errorf("expected error formatting %q", bad)
+		}
+	}
+}
+
+func TestFormatProto(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"a/a.proto": "syntax = \"proto3\";\npackage a;\nimport \"example.com/project/b/b.proto\";\nimport \"example.com/project/c/c.proto\";\nmessage A { b.B b = 1; c.C c = 2; }\n",
+		"b/b.proto": "syntax = \"proto3\";\n\npackage b;\n\nmessage B {}\n",
+		"c/c.proto": "syntax = \"proto3\";\n\npackage c;\n\nmessage C {}\n",
+	})
+	ctx := context.Background()
+
+	changed, err := g.FormatProto(ctx, true)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(changed) != 1 || changed[0].Path != "a/a.proto" || !strings.Contains(changed[0].Diff, "+message A {") {
+		t.Fatalf("unexpected check result: %v", changed)
+	}
+	data, err := os.ReadFile(filepath.Join(g.ProjectDir, "a", "a.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "syntax = \"proto3\";\npackage a;") {
+		t.Fatalf("check mode modified the file:\n%s", data)
+	}
+
+	if _, err := g.FormatProto(ctx, false); err != nil {
+		t.Fatalf("format: %v", err)
+	}
+	data, err = os.ReadFile(filepath.Join(g.ProjectDir, "a", "a.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `syntax = "proto3";
+
+package a;
+
+import "example.com/project/b/b.proto";
+import "example.com/project/c/c.proto";
+
+message A {
+  b.B b = 1;
+  c.C c = 2;
+}
+`
+	if string(data) != want {
+		t.Fatalf("unexpected formatted file:\n%s", data)
+	}
+	changed, err = g.FormatProto(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 0 {
+		t.Fatalf("expected formatted tree, got %v", changed)
+	}
+}
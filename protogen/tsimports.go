@@ -0,0 +1,302 @@
+package protogen
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// tsTokenKind is the kind of a TypeScript token.
+type tsTokenKind int
+
+const (
+	// tsIdent is an identifier or keyword.
+	tsIdent tsTokenKind = iota
+	// tsString is a single or double quoted string literal.
+	tsString
+	// tsPunct is a single punctuation character.
+	tsPunct
+	// tsOther is a number, regular expression or template literal.
+	tsOther
+)
+
+// tsToken is a token in a TypeScript source.
+type tsToken struct {
+	kind tsTokenKind
+	// text is the identifier, the punctuation character, or the contents of a
+	// string literal without the quotes.
+	text string
+	// start and end are the byte offsets of text in the source.
+	start, end int
+}
+
+// is checks the kind and text of the token.
+func (t tsToken) is(kind tsTokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+// tsRegexPrecedingKeywords are the keywords after which a "/" starts a
+// regular expression literal rather than a division.
+var tsRegexPrecedingKeywords = map[string]struct{}{
+	"await": {}, "case": {}, "delete": {}, "do": {}, "else": {}, "in": {},
+	"instanceof": {}, "new": {}, "of": {}, "return": {}, "throw": {},
+	"typeof": {}, "void": {}, "yield": {},
+}
+
+// tokenizeTs splits a TypeScript source into tokens, skipping whitespace and
+// comments. It only distinguishes what is needed to find module specifiers:
+// string literals, identifiers and punctuation. Template literals are skipped
+// except for the expressions they embed.
+func tokenizeTs(src string) []tsToken {
+	var tokens []tsToken
+	// templates holds the brace depth of each template expression being
+	// tokenized, so the closing "}" resumes the enclosing template.
+	var templates []int
+	depth := 0
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			if n := strings.IndexByte(src[i:], '\n'); n >= 0 {
+				i += n + 1
+			} else {
+				i = len(src)
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			if n := strings.Index(src[i+2:], "*/"); n >= 0 {
+				i += n + 4
+			} else {
+				i = len(src)
+			}
+		case c == '"' || c == '\'':
+			end := scanTsString(src, i)
+			tokens = append(tokens, tsToken{kind: tsString, text: src[i+1 : end-1], start: i + 1, end: end - 1})
+			i = end
+		case c == '`':
+			end, expr := scanTsTemplate(src, i+1)
+			tokens = append(tokens, tsToken{kind: tsOther, start: i, end: end})
+			if expr {
+				depth++
+				templates = append(templates, depth)
+			}
+			i = end
+		case c == '}' && len(templates) != 0 && templates[len(templates)-1] == depth:
+			// End of a template expression: resume the template.
+			templates = templates[:len(templates)-1]
+			depth--
+			end, expr := scanTsTemplate(src, i+1)
+			tokens = append(tokens, tsToken{kind: tsOther, start: i, end: end})
+			if expr {
+				depth++
+				templates = append(templates, depth)
+			}
+			i = end
+		case isTsIdentStart(src[i:]):
+			end := i
+			for end < len(src) && isTsIdentPart(src[end:]) {
+				_, size := utf8.DecodeRuneInString(src[end:])
+				end += size
+			}
+			tokens = append(tokens, tsToken{kind: tsIdent, text: src[i:end], start: i, end: end})
+			i = end
+		case c >= '0' && c <= '9':
+			end := i + 1
+			for end < len(src) && (isTsIdentPart(src[end:]) || src[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, tsToken{kind: tsOther, start: i, end: end})
+			i = end
+		case c == '/' && tsRegexAllowed(tokens):
+			end := scanTsRegex(src, i)
+			tokens = append(tokens, tsToken{kind: tsOther, start: i, end: end})
+			i = end
+		default:
+			switch c {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+			tokens = append(tokens, tsToken{kind: tsPunct, text: src[i : i+1], start: i, end: i + 1})
+			i++
+		}
+	}
+	return tokens
+}
+
+// scanTsString returns the offset after the string literal starting at i. An
+// unterminated literal ends at the end of the line.
+func scanTsString(src string, i int) int {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1
+		case '\n':
+			return j
+		}
+	}
+	return len(src)
+}
+
+// scanTsTemplate scans template literal text starting at i, up to and
+// including the closing "`" or the "${" starting an expression. Returns the
+// offset after it and whether an expression was started.
+func scanTsTemplate(src string, i int) (int, bool) {
+	for j := i; j < len(src); j++ {
+		switch {
+		case src[j] == '\\':
+			j++
+		case src[j] == '`':
+			return j + 1, false
+		case strings.HasPrefix(src[j:], "${"):
+			return j + 2, true
+		}
+	}
+	return len(src), false
+}
+
+// scanTsRegex returns the offset after the regular expression literal and
+// flags starting at i.
+func scanTsRegex(src string, i int) int {
+	inClass := false
+	j := i + 1
+	for ; j < len(src) && src[j] != '\n'; j++ {
+		switch c := src[j]; {
+		case c == '\\':
+			j++
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			j++
+			for j < len(src) && isTsIdentPart(src[j:]) {
+				j++
+			}
+			return j
+		}
+	}
+	return j
+}
+
+// tsRegexAllowed checks if a "/" following the tokens starts a regular
+// expression literal rather than a division.
+func tsRegexAllowed(tokens []tsToken) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	prev := tokens[len(tokens)-1]
+	switch prev.kind {
+	case tsPunct:
+		return prev.text != ")" && prev.text != "]" && prev.text != "}"
+	case tsIdent:
+		_, ok := tsRegexPrecedingKeywords[prev.text]
+		return ok
+	}
+	return false
+}
+
+// isTsIdentStart checks if s starts with a character that can start an
+// identifier.
+func isTsIdentStart(s string) bool {
+	c := s[0]
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= utf8.RuneSelf
+}
+
+// isTsIdentPart checks if s starts with a character that can continue an
+// identifier.
+func isTsIdentPart(s string) bool {
+	return isTsIdentStart(s) || (s[0] >= '0' && s[0] <= '9')
+}
+
+// tsTokenAt returns the token at i, or an empty punctuation token past the
+// end.
+func tsTokenAt(tokens []tsToken, i int) tsToken {
+	if i < len(tokens) {
+		return tokens[i]
+	}
+	return tsToken{kind: tsPunct}
+}
+
+// tsModuleSpecifiers returns the string tokens naming the modules of the
+// import and export declarations and dynamic imports in the tokens:
+//
+//	import "mod"
+//	import x, { y as z } from "mod"
+//	import type { T } from "mod"
+//	import * as ns from "mod"
+//	import x = require("mod")
+//	export { x } from "mod"
+//	export type { T } from "mod"
+//	export * from "mod"
+//	export * as ns from "mod"
+//	import("mod")
+func tsModuleSpecifiers(tokens []tsToken) []tsToken {
+	var specs []tsToken
+	for i, tok := range tokens {
+		if tok.kind != tsIdent || (tok.text != "import" && tok.text != "export") {
+			continue
+		}
+		// Skip member accesses such as obj.import.
+		if i > 0 && tokens[i-1].is(tsPunct, ".") {
+			continue
+		}
+		next := tsTokenAt(tokens, i+1)
+		switch {
+		case tok.text == "import" && next.is(tsPunct, "("):
+			arg, after := tsTokenAt(tokens, i+2), tsTokenAt(tokens, i+3)
+			if arg.kind == tsString && (after.is(tsPunct, ")") || after.is(tsPunct, ",")) {
+				specs = append(specs, arg)
+			}
+		case tok.text == "import" && next.kind == tsString:
+			specs = append(specs, next)
+		case tok.text == "import" && (next.kind == tsIdent || next.is(tsPunct, "{") || next.is(tsPunct, "*")),
+			tok.text == "export" && (next.is(tsIdent, "type") || next.is(tsPunct, "{") || next.is(tsPunct, "*")):
+			if spec, ok := tsFromClause(tokens, i+1); ok {
+				specs = append(specs, spec)
+			}
+		}
+	}
+	return specs
+}
+
+// tsFromClause returns the module specifier ending the import or export
+// clause starting at tokens[i]: the string after "from", or the argument of
+// require in a TypeScript import-equals declaration. Returns false if the
+// clause does not name a module.
+func tsFromClause(tokens []tsToken, i int) (tsToken, bool) {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok.is(tsPunct, "{"):
+			depth++
+		case tok.is(tsPunct, "}"):
+			depth--
+			if depth < 0 || (depth == 0 && !tsTokenAt(tokens, i+1).is(tsIdent, "from")) {
+				return tsToken{}, false
+			}
+		case depth > 0:
+		case tok.is(tsIdent, "from"):
+			if spec := tsTokenAt(tokens, i+1); spec.kind == tsString {
+				return spec, true
+			}
+		case tok.is(tsPunct, "="):
+			if tsTokenAt(tokens, i+1).is(tsIdent, "require") && tsTokenAt(tokens, i+2).is(tsPunct, "(") {
+				if spec := tsTokenAt(tokens, i+3); spec.kind == tsString {
+					return spec, true
+				}
+			}
+			return tsToken{}, false
+		case tok.kind == tsIdent, tok.is(tsPunct, "*"), tok.is(tsPunct, ","):
+		default:
+			return tsToken{}, false
+		}
+	}
+	return tsToken{}, false
+}
@@ -0,0 +1,55 @@
+package protogen
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTsModuleSpecifiers(t *testing.T) {
+	src := `// import { Fake } from "./comment.js"
+/* export * from "./block-comment.js" */
+import "./side-effect.js"
+import Default, {
+  A,
+  B as C,
+} from "./multi-line.js"
+import type { T } from './type-only.js'
+import * as ns from "./namespace.js"
+import x = require("./require.js")
+export { D } from "./export-named.js"
+export type { E } from "./export-type.js"
+export * from "./export-star.js"
+export * as other from "./export-star-as.js"
+export { F }
+export const from = "./not-a-module.js"
+export type G = { import: "./type-member.js" }
+const lazy = () => import("./dynamic.js")
+const meta = import.meta.url
+const tpl = ` + "`import x from \"./template.js\" ${import(\"./template-expr.js\")} ${`nested ${{ a: 1 }.a}`}`" + `
+const re = /from "\.\/regex.js"/g
+obj.import("./member.js")
+`
+	var got []string
+	for _, spec := range tsModuleSpecifiers(tokenizeTs(src)) {
+		if src[spec.start:spec.end] != spec.text {
+			t.Fatalf("specifier %q has offsets of %q", spec.text, src[spec.start:spec.end])
+		}
+		got = append(got, spec.text)
+	}
+	want := []string{
+		"./side-effect.js",
+		"./multi-line.js",
+		"./type-only.js",
+		"./namespace.js",
+		"./require.js",
+		"./export-named.js",
+		"./export-type.js",
+		"./export-star.js",
+		"./export-star-as.js",
+		"./dynamic.js",
+		"./template-expr.js",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected specifiers:\n%q\nwant:\n%q", got, want)
+	}
+}
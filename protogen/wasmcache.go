@@ -0,0 +1,136 @@
+package protogen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	prost "github.com/aperturerobotics/go-protoc-gen-prost"
+	protoc "github.com/aperturerobotics/go-protoc-wasi"
+)
+
+const (
+	// protocWASIModule is the module embedding the protoc WASM binary.
+	protocWASIModule = "github.com/aperturerobotics/go-protoc-wasi"
+	// protocGenProstModule is the module embedding the prost WASM binary.
+	protocGenProstModule = "github.com/aperturerobotics/go-protoc-gen-prost"
+	// wasmCacheKeyPrefix prefixes every versioned cache subdirectory.
+	wasmCacheKeyPrefix = "protoc-"
+)
+
+// GetWasmCacheRoot returns the root of the persistent WASM compilation cache.
+// Relative WasmCacheDir values are resolved against the project directory.
+// Defaults to "aptre/wazero" under the user cache directory.
+func (c *Config) GetWasmCacheRoot() (string, error) {
+	if c.WasmCacheDir != "" {
+		if filepath.IsAbs(c.WasmCacheDir) {
+			return c.WasmCacheDir, nil
+		}
+		projectDir, err := c.GetProjectDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(projectDir, c.WasmCacheDir), nil
+	}
+
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(userCacheDir, "aptre", "wazero"), nil
+}
+
+// GetWasmCacheDir returns the compilation cache directory for the embedded
+// protoc and prost versions.
+func (c *Config) GetWasmCacheDir() (string, error) {
+	root, err := c.GetWasmCacheRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, wasmCacheKey()), nil
+}
+
+// PurgeWasmCache removes every versioned compilation cache under the cache
+// root, then removes the root itself if nothing else is left in it.
+func (c *Config) PurgeWasmCache() error {
+	root, err := c.GetWasmCacheRoot()
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), wasmCacheKeyPrefix) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(root, entry.Name())); err != nil {
+			return err
+		}
+	}
+	// Only succeeds when the root is empty.
+	_ = os.Remove(root)
+	return nil
+}
+
+// wasmCacheKey returns the cache subdirectory name for the embedded protoc and
+// prost WASM modules, derived from their module versions. Falls back to a
+// digest of the embedded binaries when the versions are not recorded.
+func wasmCacheKey() string {
+	protocVersion := embeddedModuleVersion(protocWASIModule)
+	prostVersion := embeddedModuleVersion(protocGenProstModule)
+	if protocVersion == "" || prostVersion == "" {
+		h := sha256.New()
+		h.Write(protoc.ProtocWASM)
+		h.Write(prost.ProtocGenProstWASM)
+		digest := hex.EncodeToString(h.Sum(nil))[:16]
+		if protocVersion == "" {
+			protocVersion = digest
+		}
+		if prostVersion == "" {
+			prostVersion = digest
+		}
+	}
+	return sanitizeCacheKey(wasmCacheKeyPrefix + protocVersion + "_prost-" + prostVersion)
+}
+
+// embeddedModuleVersion returns the version of a dependency from the build
+// info, or an empty string if it is unknown.
+func embeddedModuleVersion(modulePath string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != modulePath {
+			continue
+		}
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		if dep.Version == "" || dep.Version == "(devel)" {
+			return ""
+		}
+		return dep.Version
+	}
+	return ""
+}
+
+// sanitizeCacheKey replaces characters that are unsafe in directory names.
+func sanitizeCacheKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
@@ -0,0 +1,88 @@
+package protogen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestConfigGetWasmCacheDirOverride(t *testing.T) {
+	projectDir := t.TempDir()
+	cfg := NewConfig()
+	cfg.ProjectDir = projectDir
+	cfg.WasmCacheDir = ".cache/wazero"
+
+	dir, err := cfg.GetWasmCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(projectDir, ".cache", "wazero")
+	if filepath.Dir(dir) != root {
+		t.Fatalf("cache dir %q is not under %q", dir, root)
+	}
+	if !strings.HasPrefix(filepath.Base(dir), wasmCacheKeyPrefix) {
+		t.Fatalf("cache dir %q is not keyed by the embedded versions", dir)
+	}
+}
+
+func TestPurgeWasmCacheKeepsUnrelatedEntries(t *testing.T) {
+	root := t.TempDir()
+	cfg := NewConfig()
+	cfg.WasmCacheDir = root
+
+	keyed, err := cfg.GetWasmCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	older := filepath.Join(root, wasmCacheKeyPrefix+"v0.0.1_prost-v0.0.1")
+	unrelated := filepath.Join(root, "other")
+	for _, dir := range []string{keyed, older, unrelated} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cfg.PurgeWasmCache(); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{keyed, older} {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Fatalf("%s was not purged", dir)
+		}
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Fatalf("unrelated entry removed: %v", err)
+	}
+}
+
+func TestGeneratePersistsWasmCompilationCache(t *testing.T) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		t.Skip("wazero only persists compiled modules with the compiler engine")
+	}
+	g := newCppTestGenerator(t, map[string]string{
+		"foo/foo.proto": "syntax = \"proto3\";\npackage foo;\nmessage Foo {}\n",
+	})
+	g.Config.WasmCacheDir = filepath.Join(t.TempDir(), "wasm")
+	ctx := context.Background()
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if err := g.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := g.Config.GetWasmCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) == 0 {
+		t.Fatal("compilation cache directory is empty after generate")
+	}
+}
@@ -0,0 +1,163 @@
+package protogen
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// wasmPluginExt is the file extension of WASI protoc plugins.
+const wasmPluginExt = ".wasm"
+
+// IsWASM returns true if the plugin runs from a WASI module instead of a
+// native executable.
+func (p *Plugin) IsWASM() bool {
+	return strings.HasSuffix(p.Path, wasmPluginExt)
+}
+
+// findWASMPluginPath resolves a .wasm plugin from a project-relative path or
+// from a vendored module path (e.g. "github.com/org/plugin/plugin.wasm").
+func findWASMPluginPath(projectDir, vendorDir, binary string) (string, error) {
+	if filepath.IsAbs(binary) {
+		if _, err := os.Stat(binary); err != nil {
+			return "", err
+		}
+		return binary, nil
+	}
+	candidates := []string{
+		filepath.Join(projectDir, binary),
+		filepath.Join(vendorDir, binary),
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found, checked %s", binary, strings.Join(candidates, ", "))
+}
+
+// findToolsPlugin returns the native plugin in the tools bin directory,
+// falling back to a WASI build of the plugin next to it. Returns an empty
+// string if neither exists.
+func findToolsPlugin(toolsBin, binaryName string) string {
+	for _, name := range []string{binaryName, binaryName + wasmPluginExt} {
+		path := filepath.Join(toolsBin, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// wasmPlugins returns every configured plugin that runs from a WASI module.
+func (p *Plugins) wasmPlugins() []*Plugin {
+	var plugins []*Plugin
+	for _, plugin := range []*Plugin{
+		p.GoLite, p.GoStarpc, p.ESLite, p.ESStarpc,
+		p.CppStarpc, p.RustStarpc, p.StarpcPython,
+	} {
+		if plugin != nil && plugin.IsWASM() {
+			plugins = append(plugins, plugin)
+		}
+	}
+	for _, plugin := range p.Custom {
+		if plugin.IsWASM() {
+			plugins = append(plugins, plugin)
+		}
+	}
+	return plugins
+}
+
+// compileWASMPlugin reads and compiles a WASI plugin module.
+func compileWASMPlugin(ctx context.Context, runtime wazero.Runtime, path string) (wazero.CompiledModule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := runtime.CompileModule(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile %s: %w", path, err)
+	}
+	return compiled, nil
+}
+
+// runWASMPlugin runs a WASI command module as a protoc plugin: the
+// CodeGeneratorRequest is piped to stdin and the CodeGeneratorResponse is read
+// from stdout. Each call uses a fresh anonymous instance in the runtime that
+// protoc already instantiated WASI into.
+func (h *NativePluginHandler) runWASMPlugin(ctx context.Context, program, path string, input []byte) ([]byte, error) {
+	if h.runtime == nil {
+		return nil, fmt.Errorf("plugin %s: WASM runtime not initialized", program)
+	}
+
+	h.mtx.Lock()
+	compiled, ok := h.wasmModules[path]
+	if !ok {
+		var err error
+		compiled, err = compileWASMPlugin(ctx, h.runtime, path)
+		if err != nil {
+			h.mtx.Unlock()
+			return nil, fmt.Errorf("plugin %s: %w", program, err)
+		}
+		if h.wasmModules == nil {
+			h.wasmModules = make(map[string]wazero.CompiledModule)
+		}
+		h.wasmModules[path] = compiled
+	}
+	h.mtx.Unlock()
+
+	var stdout, stderr bytes.Buffer
+	modCfg := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(program).
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(&stdout).
+		WithStderr(&stderr)
+	mod, err := h.runtime.InstantiateModule(ctx, compiled, modCfg)
+	if mod != nil {
+		defer mod.Close(ctx)
+	}
+	if err != nil {
+		var exitErr *sys.ExitError
+		if errors.As(err, &exitErr) {
+			err = fmt.Errorf("exit code %d", exitErr.ExitCode())
+		}
+		if stderr.Len() > 0 {
+			return nil, fmt.Errorf("plugin %s failed: %v: %s", program, err, stderr.String())
+		}
+		return nil, fmt.Errorf("plugin %s failed: %v", program, err)
+	}
+	if h.Verbose && h.Stderr != nil && stderr.Len() > 0 {
+		fmt.Fprint(h.Stderr, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// wasmPluginVersions returns digests of the WASI plugin modules for cache
+// invalidation, so replacing a .wasm file regenerates its outputs.
+func wasmPluginVersions(plugins []*Plugin) []string {
+	var versions []string
+	seen := make(map[string]struct{})
+	for _, plugin := range plugins {
+		if _, ok := seen[plugin.Path]; ok {
+			continue
+		}
+		seen[plugin.Path] = struct{}{}
+		data, err := os.ReadFile(plugin.Path)
+		if err != nil {
+			continue
+		}
+		digest := sha256.Sum256(data)
+		versions = append(versions, plugin.BinaryName+wasmPluginExt+"="+hex.EncodeToString(digest[:]))
+	}
+	return versions
+}
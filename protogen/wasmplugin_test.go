@@ -0,0 +1,140 @@
+package protogen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// buildWASMPlugin compiles a WASI protoc plugin that replies with a
+// CodeGeneratorResponse containing a single file. Skips the test if the Go
+// toolchain cannot target wasip1.
+func buildWASMPlugin(t *testing.T, dir, fileName, content string) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping WASM plugin build in short mode")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	var file []byte
+	file = appendProtoBytes(file, 1, []byte(fileName))
+	file = appendProtoBytes(file, 15, []byte(content))
+	resp := appendProtoBytes(nil, 15, file)
+
+	srcDir := filepath.Join(dir, "src")
+	if err := os.MkdirAll(srcDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	src := fmt.Sprintf(`package main
+
+import (
+	"io"
+	"os"
+)
+
+func main() {
+	if _, err := io.ReadAll(os.Stdin); err != nil {
+		os.Exit(1)
+	}
+	os.Stderr.WriteString("request read\n")
+	os.Stdout.Write([]byte(%q))
+}
+`, resp)
+	if err := os.WriteFile(filepath.Join(srcDir, "main.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "go.mod"), []byte("module example.com/plugin\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "protoc-gen-notes.wasm")
+	cmd := exec.Command(goBin, "build", "-o", out, ".")
+	cmd.Dir = srcDir
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm", "GOFLAGS=", "GOWORK=off")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("cannot build wasip1 plugin: %v\n%s", err, output)
+	}
+	return out
+}
+
+func TestFindWASMPluginPath(t *testing.T) {
+	projectDir := t.TempDir()
+	vendorDir := filepath.Join(projectDir, "vendor")
+	local := filepath.Join(projectDir, "plugins", "local.wasm")
+	vendored := filepath.Join(vendorDir, "github.com", "org", "plugin", "plugin.wasm")
+	for _, path := range []string{local, vendored} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("\x00asm"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for binary, want := range map[string]string{
+		"plugins/local.wasm":                            local,
+		"./plugins/local.wasm":                          local,
+		"github.com/org/plugin/plugin.wasm":             vendored,
+		filepath.Join(projectDir, "plugins/local.wasm"): local,
+	} {
+		got, err := findWASMPluginPath(projectDir, vendorDir, binary)
+		if err != nil {
+			t.Fatalf("%s: %v", binary, err)
+		}
+		if got != want {
+			t.Fatalf("%s resolved to %q, want %q", binary, got, want)
+		}
+	}
+	if _, err := findWASMPluginPath(projectDir, vendorDir, "missing.wasm"); err == nil {
+		t.Fatal("expected error for missing plugin")
+	}
+}
+
+func TestGenerateRunsWASMPlugin(t *testing.T) {
+	g := newCppTestGenerator(t, map[string]string{
+		"a/a.proto": "syntax = \"proto3\";\npackage a;\nmessage A { string id = 1; }\n",
+	})
+	wasmPath := buildWASMPlugin(t, t.TempDir(), "example.com/project/a/a.notes.txt", "notes from wasm\n")
+	g.Plugins.Custom = []*Plugin{{
+		Name:       "notes",
+		BinaryName: "protoc-gen-notes",
+		Path:       wasmPath,
+		Type:       PluginTypeCustom,
+		OutFlag:    "notes_out",
+		Options:    map[string]string{},
+		Outputs:    []string{"{dir}/{name}.notes.txt"},
+	}}
+	var stderr bytes.Buffer
+	g.Verbose = true
+	g.Stderr = &stderr
+
+	if err := g.Generate(context.Background()); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "request read") {
+		t.Fatalf("plugin stderr not written to the generator's stderr: %q", stderr.String())
+	}
+	data, err := os.ReadFile(filepath.Join(g.ProjectDir, "a", "a.notes.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "notes from wasm\n" {
+		t.Fatalf("unexpected plugin output %q", data)
+	}
+
+	versions := strings.Split(g.Cache.ToolVersions, ",")
+	if !slices.ContainsFunc(versions, func(v string) bool {
+		return strings.HasPrefix(v, "protoc-gen-notes.wasm=")
+	}) {
+		t.Fatalf("tool versions %q missing WASM plugin digest", g.Cache.ToolVersions)
+	}
+}
@@ -0,0 +1,149 @@
+package protogen
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// DefaultWatchInterval is the default polling interval for Watch.
+const DefaultWatchInterval = 500 * time.Millisecond
+
+// watchStamp identifies the observed state of a watched file.
+type watchStamp struct {
+	// exists is false when the file was missing.
+	exists bool
+	// modTime is the file modification time.
+	modTime time.Time
+	// size is the file size in bytes.
+	size int64
+}
+
+// watchSnapshot maps absolute file paths to their observed state.
+type watchSnapshot map[string]watchStamp
+
+// Watch runs Generate and then polls the proto sources and tool version inputs,
+// running Generate again whenever one of them changes. The cache limits each
+// run to the packages affected by the change, and the compiled WASM modules
+// stay warm between runs. Generation errors are reported to Stderr without
+// stopping the watch. Watch returns nil when ctx is canceled.
+func (g *Generator) Watch(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	prev, err := g.snapshotWatchFiles()
+	if err != nil {
+		return err
+	}
+	g.runWatchGenerate(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		next, err := g.snapshotWatchFiles()
+		if err != nil {
+			fmt.Fprintf(g.Stderr, "watch: %v\n", err)
+			continue
+		}
+		changed := changedWatchFiles(prev, next)
+		prev = next
+		if len(changed) == 0 {
+			continue
+		}
+
+		if g.Verbose {
+			for _, f := range changed {
+				fmt.Fprintf(g.Stdout, "Changed: %s\n", f)
+			}
+		}
+		g.runWatchGenerate(ctx)
+	}
+}
+
+// runWatchGenerate runs one generation pass and reports the outcome.
+func (g *Generator) runWatchGenerate(ctx context.Context) {
+	if err := g.Generate(ctx); err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Fprintf(g.Stderr, "generate: %v\n", err)
+		return
+	}
+	fmt.Fprintf(g.Stdout, "Generation complete, watching for changes...\n")
+}
+
+// watchFiles returns the absolute paths of all files that affect generation:
+// the discovered proto files plus the inputs read by getToolVersions.
+func (g *Generator) watchFiles() ([]string, error) {
+	protoFiles, err := DiscoverProtoFiles(g.ProjectDir, g.Config.Targets, g.Config.Exclude, g.Config.IncludeUntracked)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(protoFiles)+3)
+	for _, f := range protoFiles {
+		files = append(files, filepath.Join(g.ProjectDir, f))
+	}
+	files = append(
+		files,
+		filepath.Join(g.ProjectDir, "package.json"),
+		filepath.Join(g.ProjectDir, g.Config.ToolsDir, "go.mod"),
+		filepath.Join(g.ProjectDir, "uv.lock"),
+	)
+	return files, nil
+}
+
+// snapshotWatchFiles stats every watched file.
+func (g *Generator) snapshotWatchFiles() (watchSnapshot, error) {
+	files, err := g.watchFiles()
+	if err != nil {
+		return nil, err
+	}
+	return snapshotFiles(files)
+}
+
+// snapshotFiles stats the given files, recording missing files as absent.
+func snapshotFiles(files []string) (watchSnapshot, error) {
+	snap := make(watchSnapshot, len(files))
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			if os.IsNotExist(err) {
+				snap[f] = watchStamp{}
+				continue
+			}
+			return nil, err
+		}
+		snap[f] = watchStamp{exists: true, modTime: info.ModTime(), size: info.Size()}
+	}
+	return snap, nil
+}
+
+// changedWatchFiles returns the sorted paths that were added, removed or
+// modified between two snapshots.
+func changedWatchFiles(prev, next watchSnapshot) []string {
+	var changed []string
+	for f, stamp := range next {
+		old, ok := prev[f]
+		if !ok || old.exists != stamp.exists || old.size != stamp.size || !old.modTime.Equal(stamp.modTime) {
+			changed = append(changed, f)
+		}
+	}
+	for f, stamp := range prev {
+		if _, ok := next[f]; !ok && stamp.exists {
+			changed = append(changed, f)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
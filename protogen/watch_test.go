@@ -0,0 +1,88 @@
+package protogen
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestChangedWatchFilesDetectsEdits(t *testing.T) {
+	dir, _ := writeProtoTree(t, map[string]string{
+		"a/a.proto": "syntax = \"proto3\";\npackage a;\n",
+		"b/b.proto": "syntax = \"proto3\";\npackage b;\n",
+	})
+	a := filepath.Join(dir, "a", "a.proto")
+	b := filepath.Join(dir, "b", "b.proto")
+	lock := filepath.Join(dir, "uv.lock")
+
+	prev, err := snapshotFiles([]string{a, b, lock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed := changedWatchFiles(prev, prev); len(changed) != 0 {
+		t.Fatalf("unchanged snapshot reported changes: %v", changed)
+	}
+
+	if err := os.WriteFile(a, []byte("syntax = \"proto3\";\npackage a;\nmessage A {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lock, []byte("version = 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	next, err := snapshotFiles([]string{a, b, lock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := changedWatchFiles(prev, next)
+	if want := []string{a, lock}; !slices.Equal(changed, want) {
+		t.Fatalf("changed = %v, want %v", changed, want)
+	}
+}
+
+func TestChangedWatchFilesDetectsAddedAndRemovedProtos(t *testing.T) {
+	dir, _ := writeProtoTree(t, map[string]string{
+		"a.proto": "syntax = \"proto3\";\n",
+		"b.proto": "syntax = \"proto3\";\n",
+	})
+	a := filepath.Join(dir, "a.proto")
+	b := filepath.Join(dir, "b.proto")
+	c := filepath.Join(dir, "c.proto")
+
+	prev, err := snapshotFiles([]string{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Discovery no longer returns b and now returns c.
+	next, err := snapshotFiles([]string{a, c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next[c] = watchStamp{exists: true, modTime: time.Now(), size: 1}
+	changed := changedWatchFiles(prev, next)
+	if want := []string{b, c}; !slices.Equal(changed, want) {
+		t.Fatalf("changed = %v, want %v", changed, want)
+	}
+}
+
+func TestWatchFilesIncludesToolVersionInputs(t *testing.T) {
+	dir := t.TempDir()
+	cfg := NewConfig()
+	cfg.ProjectDir = dir
+	g := &Generator{Config: cfg, ProjectDir: dir}
+
+	files, err := g.watchFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		filepath.Join(dir, "package.json"),
+		filepath.Join(dir, ".tools", "go.mod"),
+		filepath.Join(dir, "uv.lock"),
+	} {
+		if !slices.Contains(files, want) {
+			t.Fatalf("watch files %v missing %s", files, want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package protogen
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// WorkspaceMountDir is the directory in the protoc filesystem where the
+// sibling workspace modules are mounted read-only, each at its module path.
+// It is passed to protoc as an include path after the vendor directory, so
+// vendored copies take precedence and modules nested below the project's
+// module path do not collide with the project's vendor symlink.
+const WorkspaceMountDir = "/.aptre/workspace"
+
+// Workspace is a Go workspace defined by a go.work file.
+type Workspace struct {
+	// Dir is the directory containing go.work.
+	Dir string
+	// Modules are the workspace members in go.work order.
+	Modules []*WorkspaceModule
+}
+
+// WorkspaceModule is a module listed in a go.work use directive.
+type WorkspaceModule struct {
+	// Dir is the absolute module root directory.
+	Dir string
+	// Path is the Go module path.
+	Path string
+}
+
+// FindWorkspaceFile returns the go.work file used for dir, following the go
+// command: GOWORK selects the file or disables workspaces with "off",
+// otherwise the nearest go.work in dir or its ancestors is used. Returns an
+// empty string if there is none.
+func FindWorkspaceFile(dir string) (string, error) {
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+		return "", nil
+	case "", "auto":
+	default:
+		return filepath.Abs(gowork)
+	}
+	for {
+		path := filepath.Join(dir, "go.work")
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadWorkspace parses a go.work file and reads the module path of every
+// member.
+func LoadWorkspace(goWorkPath string) (*Workspace, error) {
+	data, err := os.ReadFile(goWorkPath)
+	if err != nil {
+		return nil, err
+	}
+	workFile, err := modfile.ParseWork(goWorkPath, data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &Workspace{Dir: filepath.Dir(goWorkPath)}
+	for _, use := range workFile.Use {
+		dir := filepath.FromSlash(use.Path)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(ws.Dir, dir)
+		}
+		modulePath, err := GetGoModule(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workspace module %s: %w", use.Path, err)
+		}
+		ws.Modules = append(ws.Modules, &WorkspaceModule{Dir: dir, Path: modulePath})
+	}
+	return ws, nil
+}
+
+// Module returns the member with the given root directory, or nil.
+func (w *Workspace) Module(dir string) *WorkspaceModule {
+	for _, m := range w.Modules {
+		if m.Dir == dir {
+			return m
+		}
+	}
+	return nil
+}
+
+// Siblings returns the members other than the module in dir.
+func (w *Workspace) Siblings(dir string) []*WorkspaceModule {
+	var siblings []*WorkspaceModule
+	for _, m := range w.Modules {
+		if m.Dir != dir {
+			siblings = append(siblings, m)
+		}
+	}
+	return siblings
+}
+
+// GetWorkspace returns the Go workspace the project's module belongs to.
+// Returns nil if there is no go.work or the module is not a member.
+func (c *Config) GetWorkspace() (*Workspace, error) {
+	moduleDir, err := c.GetModuleDir()
+	if err != nil {
+		return nil, err
+	}
+	goWorkPath, err := FindWorkspaceFile(moduleDir)
+	if err != nil || goWorkPath == "" {
+		return nil, err
+	}
+	ws, err := LoadWorkspace(goWorkPath)
+	if err != nil {
+		return nil, err
+	}
+	if ws.Module(moduleDir) == nil {
+		return nil, nil
+	}
+	return ws, nil
+}
+
+// resolveWorkspaceImport returns the file in a sibling module for an import
+// path, or an empty string if no sibling module contains it. The longest
+// matching module path wins.
+func resolveWorkspaceImport(siblings []*WorkspaceModule, importPath string) string {
+	var match *WorkspaceModule
+	for _, m := range siblings {
+		if strings.HasPrefix(importPath, m.Path+"/") && (match == nil || len(m.Path) > len(match.Path)) {
+			match = m
+		}
+	}
+	if match == nil {
+		return ""
+	}
+	return filepath.Join(match.Dir, filepath.FromSlash(strings.TrimPrefix(importPath, match.Path+"/")))
+}
+
+// workspaceSiblings returns the other members of the generator's workspace.
+func (g *Generator) workspaceSiblings() []*WorkspaceModule {
+	if g.Workspace == nil {
+		return nil
+	}
+	return g.Workspace.Siblings(g.ModuleDir)
+}
+
+// workspaceMountPath returns the directory in the protoc filesystem where a
+// sibling workspace module is mounted.
+func workspaceMountPath(m *WorkspaceModule) string {
+	return path.Join(WorkspaceMountDir, m.Path)
+}
@@ -0,0 +1,153 @@
+package protogen
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadWorkspace(t *testing.T) {
+	dir, _ := writeProtoTree(t, map[string]string{
+		"go.work":       "go 1.24\n\nuse (\n\t./a\n\t./libs/b\n)\n",
+		"a/go.mod":      "module example.com/a\n",
+		"libs/b/go.mod": "module example.com/b\n",
+		"c/go.mod":      "module example.com/c\n",
+	})
+	t.Setenv("GOWORK", "")
+
+	cfg := NewConfig()
+	cfg.ProjectDir = filepath.Join(dir, "a")
+	ws, err := cfg.GetWorkspace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ws == nil || ws.Dir != dir || len(ws.Modules) != 2 {
+		t.Fatalf("unexpected workspace: %+v", ws)
+	}
+	if m := ws.Modules[1]; m.Dir != filepath.Join(dir, "libs", "b") || m.Path != "example.com/b" {
+		t.Fatalf("unexpected module: %+v", m)
+	}
+	if siblings := ws.Siblings(cfg.ProjectDir); len(siblings) != 1 || siblings[0].Path != "example.com/b" {
+		t.Fatalf("unexpected siblings: %+v", siblings)
+	}
+
+	// Modules that are not members are not part of the workspace.
+	cfg.ProjectDir = filepath.Join(dir, "c")
+	if ws, err := cfg.GetWorkspace(); err != nil || ws != nil {
+		t.Fatalf("expected no workspace, got %+v, %v", ws, err)
+	}
+
+	t.Setenv("GOWORK", "off")
+	cfg.ProjectDir = filepath.Join(dir, "a")
+	if ws, err := cfg.GetWorkspace(); err != nil || ws != nil {
+		t.Fatalf("expected GOWORK=off to disable the workspace, got %+v, %v", ws, err)
+	}
+}
+
+func TestGenerateResolvesWorkspaceImports(t *testing.T) {
+	dir, _ := writeProtoTree(t, map[string]string{
+		"go.work":       "go 1.24\n\nuse (\n\t./a\n\t./b\n)\n",
+		"a/go.mod":      "module example.com/a\n",
+		"a/api/a.proto": "syntax = \"proto3\";\npackage a;\nimport \"example.com/b/api/b.proto\";\nmessage A { b.B b = 1; }\n",
+		"b/go.mod":      "module example.com/b\n",
+		"b/api/b.proto": "syntax = \"proto3\";\npackage b;\nmessage B {}\n",
+	})
+	t.Setenv("GOWORK", "")
+
+	cfg := NewConfig()
+	cfg.ProjectDir = filepath.Join(dir, "a")
+	cfg.Targets = []string{"*.proto"}
+	cfg.WasmCacheDir = t.TempDir()
+	ws, err := cfg.GetWorkspace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vendorDir := filepath.Join(dir, "a", "vendor")
+	g := &Generator{
+		Config:     cfg,
+		Plugins:    &Plugins{Languages: Languages{LanguageCpp: {}}, RPCLibraries: RPCLibraries{}},
+		Cache:      NewCache(),
+		ProjectDir: cfg.ProjectDir,
+		ModuleDir:  cfg.ProjectDir,
+		ModulePath: "example.com/a",
+		Workspace:  ws,
+		VendorDir:  vendorDir,
+		OutDir:     vendorDir,
+		Stdout:     io.Discard,
+		Stderr:     io.Discard,
+	}
+	ctx := context.Background()
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a", "api", "a.pb.cc")); err != nil {
+		t.Fatalf("missing generated file: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b", "api", "b.pb.cc")); !os.IsNotExist(err) {
+		t.Fatal("generated the imported sibling module")
+	}
+	if _, err := os.Lstat(filepath.Join(vendorDir, "example.com", "b")); !os.IsNotExist(err) {
+		t.Fatal("sibling module was linked into the vendor directory")
+	}
+
+	// Changing the sibling's proto file regenerates the importer.
+	if err := os.WriteFile(filepath.Join(dir, "b", "api", "b.proto"), []byte("syntax = \"proto3\";\npackage b;\nmessage B { string id = 1; }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := g.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Packages) != 1 || plan.Packages[0].Reason != StaleReasonImports {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+}
+
+func TestGenerateResolvesParentWorkspaceImports(t *testing.T) {
+	dir, _ := writeProtoTree(t, map[string]string{
+		"go.work":         "go 1.24\n\nuse (\n\t.\n\t./sub\n)\n",
+		"go.mod":          "module example.com/repo\n",
+		"common/c.proto":  "syntax = \"proto3\";\npackage common;\nmessage C {}\n",
+		"sub/go.mod":      "module example.com/repo/sub\n",
+		"sub/api/s.proto": "syntax = \"proto3\";\npackage sub;\nimport \"example.com/repo/common/c.proto\";\nmessage S { common.C c = 1; }\n",
+		"sub/api/t.proto": "syntax = \"proto3\";\npackage sub;\nimport \"example.com/repo/sub/api/s.proto\";\nmessage T { S s = 1; }\n",
+	})
+	t.Setenv("GOWORK", "")
+
+	subDir := filepath.Join(dir, "sub")
+	cfg := NewConfig()
+	cfg.ProjectDir = subDir
+	cfg.Targets = []string{"*.proto"}
+	cfg.WasmCacheDir = t.TempDir()
+	ws, err := cfg.GetWorkspace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vendorDir := filepath.Join(subDir, "vendor")
+	g := &Generator{
+		Config:     cfg,
+		Plugins:    &Plugins{Languages: Languages{LanguageCpp: {}}, RPCLibraries: RPCLibraries{}},
+		Cache:      NewCache(),
+		ProjectDir: subDir,
+		ModuleDir:  subDir,
+		ModulePath: "example.com/repo/sub",
+		Workspace:  ws,
+		VendorDir:  vendorDir,
+		OutDir:     vendorDir,
+		Stdout:     io.Discard,
+		Stderr:     io.Discard,
+	}
+	if err := g.Generate(context.Background()); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	for _, f := range []string{"s.pb.cc", "t.pb.cc"} {
+		if _, err := os.Stat(filepath.Join(subDir, "api", f)); err != nil {
+			t.Fatalf("missing generated file: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "common", "c.pb.cc")); !os.IsNotExist(err) {
+		t.Fatal("generated the imported parent module")
+	}
+}